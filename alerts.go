@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/notify"
+	"github.com/neixir/gator/internal/output"
+)
+
+// How an alert is delivered
+const (
+	alertWebhook = "webhook"
+	alertCommand = "command"
+	alertEmail   = "email"
+)
+
+var alertKinds = []string{alertWebhook, alertCommand, alertEmail}
+
+// Status of a delivery
+const (
+	deliverySent   = "sent"
+	deliveryFailed = "failed"
+)
+
+// How many deliveries alert history shows unless --limit says otherwise
+const defaultAlertHistory = 20
+
+// alertRule is a rule ready to match the new posts of a feed.
+type alertRule struct {
+	database.AlertRule
+	postCondition
+}
+
+func compileAlertRule(rule database.AlertRule) (alertRule, error) {
+	c, err := newPostCondition(rule.FeedID, rule.Field, rule.Match, rule.Pattern)
+	if err != nil {
+		return alertRule{}, fmt.Errorf("alert rule %s: %v", rule.ID, err)
+	}
+
+	return alertRule{AlertRule: rule, postCondition: c}, nil
+}
+
+// describeAlertRule says what a rule does, like `title contains "go": webhook https://...`.
+func describeAlertRule(rule database.AlertRule, feeds map[uuid.UUID]string) string {
+	c := postCondition{feedID: rule.FeedID, field: rule.Field, match: rule.Match, pattern: rule.Pattern}
+	return fmt.Sprintf("%s: %s %s", c.describe(feeds), rule.Kind, rule.Target)
+}
+
+// notifierFor returns what delivers the alerts of rule.
+func notifierFor(cfg *config.Config, rule database.AlertRule) (notify.Notifier, error) {
+	switch rule.Kind {
+	case alertWebhook:
+		return notify.Webhook{URL: rule.Target}, nil
+	case alertCommand:
+		return notify.Command{Command: rule.Target}, nil
+	case alertEmail:
+		if cfg == nil || cfg.SMTP == nil {
+			return nil, errors.New("there is no smtp server in the config file")
+		}
+		return notify.Email{Server: *cfg.SMTP, To: rule.Target}, nil
+	}
+
+	return nil, fmt.Errorf("unknown kind of alert %q", rule.Kind)
+}
+
+// loadAlertRules returns the alert rules for the new posts of feed.
+// A rule that doesn't compile is skipped, it shouldn't stop the scraper.
+// So are the rules of deactivated users, and command rules whose owner isn't
+// an admin anymore: what counts is who they are now, not when they added it.
+func loadAlertRules(db scraperDB, feed database.Feed) ([]alertRule, error) {
+	ctx := context.Background()
+	rules, err := db.GetAlertRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("getting alert rules. %v", err)
+	}
+
+	owners := map[uuid.UUID]database.User{}
+	var compiled []alertRule
+	for _, rule := range rules {
+		owner, ok := owners[rule.UserID]
+		if !ok {
+			owner, err = db.GetUserById(ctx, rule.UserID)
+			if err != nil {
+				fmt.Printf("  (skipping alert rule %s: getting its owner. %v)\n", rule.ID, err)
+				continue
+			}
+			owners[rule.UserID] = owner
+		}
+		if owner.DeactivatedAt.Valid {
+			continue
+		}
+		if rule.Kind == alertCommand && !owner.IsAdmin {
+			fmt.Printf("  (skipping alert rule %s: %s is not an admin anymore)\n", rule.ID, owner.Name)
+			continue
+		}
+
+		r, err := compileAlertRule(rule)
+		if err != nil {
+			fmt.Printf("  (skipping %v)\n", err)
+			continue
+		}
+		compiled = append(compiled, r)
+	}
+
+	return compiled, nil
+}
+
+// sendAlerts delivers the alerts of the rules post matches, and keeps a
+// record of each. Failures are recorded or printed, they don't stop the
+// scraper.
+func sendAlerts(db scraperDB, cfg *config.Config, feed database.Feed, rules []alertRule, post database.Post) {
+	ctx := context.Background()
+	for _, rule := range rules {
+		if !rule.matches(post) {
+			continue
+		}
+
+		msg := notify.Message{
+			Rule:        rule.ID.String(),
+			Feed:        feed.Name,
+			Title:       post.Title,
+			URL:         post.Url,
+			Author:      post.Author.String,
+			PublishedAt: post.PublishedAt.Time,
+			Description: post.DescriptionText.String,
+		}
+		attempts := 0
+		notifier, err := notifierFor(cfg, rule.AlertRule)
+		if err == nil {
+			attempts, err = notifier.Notify(ctx, msg)
+		}
+
+		status := deliverySent
+		var errText sql.NullString
+		if err != nil {
+			status = deliveryFailed
+			errText = sql.NullString{String: err.Error(), Valid: true}
+			fmt.Printf("  (alert %s by %s failed: %v)\n", rule.ID, rule.Kind, err)
+		} else {
+			fmt.Printf("  (alert %s sent by %s)\n", rule.ID, rule.Kind)
+		}
+
+		_, err = db.CreateAlertDelivery(ctx, database.CreateAlertDeliveryParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			RuleID:    rule.ID,
+			PostTitle: post.Title,
+			PostUrl:   post.Url,
+			Status:    status,
+			Attempts:  int32(attempts),
+			Error:     errText,
+		})
+		if err != nil {
+			fmt.Printf("  (saving delivery of alert %s failed: %v)\n", rule.ID, err)
+		}
+	}
+}
+
+// alert add <condition> [--feed <url>] --webhook <url> | --command <cmd> | --email <address>
+// Command alerts run as whoever runs agg, so only admins can add them.
+func handlerAlertAdd(s *state, cmd command, user database.User) error {
+	condition, err := postConditionFromFlags(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	var kind, target string
+	var given []string
+	for _, k := range alertKinds {
+		if value, _ := cmd.flag(k).(string); value != "" {
+			kind, target = k, value
+			given = append(given, "--"+k)
+		}
+	}
+	if len(given) != 1 {
+		return fmt.Errorf("give one of --webhook <url>, --command <cmd> or --email <address> (got %d)", len(given))
+	}
+
+	switch kind {
+	case alertWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q is not an http or https URL", target)
+		}
+	case alertCommand:
+		if !user.IsAdmin {
+			return errors.New("only admins can add command alerts, they run as whoever runs agg")
+		}
+	case alertEmail:
+		if s.cfg.SMTP == nil {
+			return fmt.Errorf("email alerts need an smtp section in the config file")
+		}
+		if err := notify.ValidAddress(target); err != nil {
+			return err
+		}
+	}
+
+	rule, err := s.db.CreateAlertRule(context.Background(), database.CreateAlertRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    condition.feedID,
+		Field:     condition.field,
+		Match:     condition.match,
+		Pattern:   condition.pattern,
+		Kind:      kind,
+		Target:    target,
+	})
+	if err != nil {
+		return fmt.Errorf("creating alert rule. %v", err)
+	}
+
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added alert %s: %s\n", rule.ID, describeAlertRule(rule, feeds))
+	return nil
+}
+
+func handlerAlertList(s *state, cmd command, user database.User) error {
+	rules, err := s.db.GetAlertRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("getting alert rules. %v", err)
+	}
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+
+	list := output.NewList("id", "field", "match", "pattern", "feed", "kind", "target", "created_at")
+	for _, rule := range rules {
+		feed := ""
+		if rule.FeedID.Valid {
+			feed = feeds[rule.FeedID.UUID]
+		}
+		list.Add(rule.ID, rule.Field, rule.Match, rule.Pattern, feed, rule.Kind, rule.Target, rule.CreatedAt)
+	}
+
+	return printList(cmd, list, func() {
+		fmt.Printf("%d alert rules.\n", len(rules))
+		for _, rule := range rules {
+			fmt.Printf("* %s %s\n", rule.ID, describeAlertRule(rule, feeds))
+		}
+	})
+}
+
+// alert remove <id>
+// Its delivery history goes with it.
+func handlerAlertRemove(s *state, cmd command, user database.User) error {
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("%q is not an alert id. `gator alert list` lists them", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteAlertRule(context.Background(), database.DeleteAlertRuleParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("removing alert rule. %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you have no alert rule %s", id)
+	}
+
+	fmt.Printf("Removed alert %s.\n", id)
+	return nil
+}
+
+// alert history [--limit N]
+// The latest deliveries of the user's alerts, newest first.
+func handlerAlertHistory(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	limit, _ := cmd.flag("limit").(int)
+	if limit <= 0 {
+		return errors.New("--limit has to be at least 1")
+	}
+
+	deliveries, err := s.db.GetAlertDeliveriesForUser(ctx, database.GetAlertDeliveriesForUserParams{UserID: user.ID, Limit: int32(limit)})
+	if err != nil {
+		return fmt.Errorf("getting alert history. %v", err)
+	}
+	rules, err := s.db.GetAlertRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("getting alert rules. %v", err)
+	}
+	kind := func(ruleID uuid.UUID) string {
+		i := slices.IndexFunc(rules, func(r database.AlertRule) bool { return r.ID == ruleID })
+		if i < 0 {
+			return ""
+		}
+		return rules[i].Kind
+	}
+
+	list := output.NewList("id", "created_at", "rule_id", "kind", "status", "attempts", "title", "url", "error")
+	for _, d := range deliveries {
+		list.Add(d.ID, d.CreatedAt, d.RuleID, kind(d.RuleID), d.Status, d.Attempts, d.PostTitle, d.PostUrl, d.Error)
+	}
+
+	return printList(cmd, list, func() {
+		fmt.Printf("%d deliveries.\n", len(deliveries))
+		for _, d := range deliveries {
+			line := fmt.Sprintf("* %s %s %s: %s", d.CreatedAt.Format(time.DateTime), kind(d.RuleID), d.Status, d.PostTitle)
+			if d.Status == deliveryFailed {
+				line += fmt.Sprintf(" (%d attempt(s): %s)", d.Attempts, strings.TrimSpace(d.Error.String))
+			}
+			fmt.Println(line)
+		}
+	})
+}
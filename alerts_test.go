@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/feedtest"
+	"github.com/neixir/gator/internal/notify"
+	"github.com/neixir/gator/internal/smtptest"
+)
+
+func TestAlerts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command alert uses sh")
+	}
+	forEachBackend(t, testAlerts)
+}
+
+func testAlerts(t *testing.T, s *state) {
+	feeds := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml": {Fixture: "rss2.xml"},
+		"/later.xml": {Body: []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Later</title><link>https://example.com/</link>
+<item><title>Go beats the odds</title><link>https://example.com/odds</link><description>yes</description></item>
+</channel></rss>`)},
+	})
+	url := feeds.FeedURL("/rss2.xml")
+
+	var mu sync.Mutex
+	var hooked []notify.Message
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		hooked = append(hooked, msg)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	mail := smtptest.NewServer(t)
+	out := filepath.Join(t.TempDir(), "out")
+
+	register(t, s, "alice")
+	mustRun(t, s, "addfeed", "Boot.dev", url)
+
+	if _, err := runCommand(t, s, "alert", "add", "--title-contains", "go"); err == nil || !strings.Contains(err.Error(), "give one of") {
+		t.Errorf("alert add without a delivery: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "alert", "add", "--title-contains", "go", "--webhook", "ftp://example.com"); err == nil || !strings.Contains(err.Error(), "not an http") {
+		t.Errorf("alert add with an ftp webhook: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "alert", "add", "--title-contains", "go", "--email", "ada@example.com"); err == nil || !strings.Contains(err.Error(), "smtp section") {
+		t.Errorf("alert add --email without smtp: err = %v", err)
+	}
+	s.cfg.SMTP = &config.SMTP{Host: mail.Host, Port: mail.Port, From: "gator@example.com"}
+	if _, err := runCommand(t, s, "alert", "add", "--title-contains", "go", "--email", "Ada <ada@example.com>"); err == nil || !strings.Contains(err.Error(), "not an email address") {
+		t.Errorf("alert add with a named address: err = %v", err)
+	}
+
+	mustRun(t, s, "alert", "add", "--title-regex", `(?i)\bgo\b`, "--webhook", hook.URL)
+	mustRun(t, s, "alert", "add", "--title-contains", "tests", "--feed", url, "--email", "ada@example.com")
+	mustRun(t, s, "alert", "add", "--title-contains", "beat", "--command", `printf '%s' "$GATOR_ALERT_TITLE" > `+out)
+
+	// Commands run as whoever runs agg
+	register(t, s, "bob")
+	mustRun(t, s, "follow", url)
+	if _, err := runCommand(t, s, "alert", "add", "--title-contains", "beat", "--command", "true"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("command alert as bob: err = %v", err)
+	}
+	mustRun(t, s, "alert", "add", "--description-contains", "yes", "--webhook", hook.URL)
+	// Carol doesn't follow the feed
+	register(t, s, "carol")
+	mustRun(t, s, "alert", "add", "--title-contains", "go", "--webhook", hook.URL)
+
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+
+	if len(hooked) != 2 || hooked[0].Title != "Is Go a good first language? — an honest answer" || hooked[0].Feed != "Boot.dev" {
+		t.Errorf("webhook got %+v", hooked)
+	}
+	if messages := mail.Messages(); len(messages) != 1 || messages[0].To[0] != "ada@example.com" || !strings.Contains(messages[0].Data, "Why I Write Tests") {
+		t.Errorf("emails = %+v", messages)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "The Boot.dev Beat. June 2025" {
+		t.Errorf("command wrote %q", data)
+	}
+
+	login(t, s, "alice")
+	history := mustRun(t, s, "alert", "history")
+	for _, want := range []string{"3 deliveries.", "webhook sent: Is Go a good first language?", "email sent: Why I Write Tests", "command sent: The Boot.dev Beat"} {
+		if !strings.Contains(history, want) {
+			t.Errorf("alert history doesn't have %q:\n%s", want, history)
+		}
+	}
+	if out := mustRun(t, s, "alert", "history", "--limit", "1"); !strings.Contains(out, "1 deliveries.") {
+		t.Errorf("alert history --limit 1 = %q", out)
+	}
+
+	list := mustRun(t, s, "alert", "list")
+	if !strings.Contains(list, "3 alert rules.") || !strings.Contains(list, `title contains "tests" in Boot.dev: email ada@example.com`) {
+		t.Errorf("alert list = %q", list)
+	}
+	var rules []map[string]any
+	json.Unmarshal([]byte(mustRun(t, s, "alert", "list", "--output", "json")), &rules)
+	if len(rules) != 3 {
+		t.Fatalf("alert list --output json = %v", rules)
+	}
+	id := rules[0]["id"].(string)
+
+	login(t, s, "bob")
+	if _, err := runCommand(t, s, "alert", "remove", id); err == nil || !strings.Contains(err.Error(), "no alert rule") {
+		t.Errorf("alert remove of alice's rule as bob: err = %v", err)
+	}
+	if out := mustRun(t, s, "alert", "history"); !strings.Contains(out, "1 deliveries.") {
+		t.Errorf("bob's alert history = %q", out)
+	}
+
+	// The history goes with the rule
+	login(t, s, "alice")
+	mustRun(t, s, "alert", "remove", id)
+	if out := mustRun(t, s, "alert", "history"); !strings.Contains(out, "2 deliveries.") {
+		t.Errorf("alert history after alert remove = %q", out)
+	}
+
+	// What counts is who the owners are now: bob is deactivated and alice
+	// isn't an admin anymore, so only carol's rule fires
+	later := feeds.FeedURL("/later.xml")
+	mustRun(t, s, "addfeed", "Later", later)
+	login(t, s, "bob")
+	mustRun(t, s, "follow", later)
+	login(t, s, "carol")
+	mustRun(t, s, "follow", later)
+	login(t, s, "alice")
+	mustRun(t, s, "admin", "grant", "carol")
+	mustRun(t, s, "user", "deactivate", "bob")
+	login(t, s, "carol")
+	mustRun(t, s, "admin", "revoke", "alice")
+	os.Remove(out)
+
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+	if len(hooked) != 3 || hooked[2].Title != "Go beats the odds" {
+		t.Errorf("webhook got %+v", hooked)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("the command of a former admin ran: %v", err)
+	}
+}
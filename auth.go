@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/storage"
+)
+
+// How long a login lasts
+const sessionLifetime = 30 * 24 * time.Hour
+
+// Passwords shorter than this are refused
+const minPasswordLength = 8
+
+// bcryptCost is a variable so the tests can hash faster.
+var bcryptCost = bcrypt.DefaultCost
+
+func hashPassword(password string) (sql.NullString, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("hashing password. %v", err)
+	}
+
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword asks for the password of user and compares it with its hash.
+func checkPassword(user database.User) error {
+	password, err := promptSecret("Password")
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return errors.New("wrong password")
+	}
+
+	return nil
+}
+
+// promptNewPassword asks for a new password twice and returns its hash.
+func promptNewPassword() (sql.NullString, error) {
+	password, err := promptSecret("Password")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if len(password) < minPasswordLength {
+		return sql.NullString{}, fmt.Errorf("the password needs at least %d characters", minPasswordLength)
+	}
+
+	repeated, err := promptSecret("Repeat password")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if repeated != password {
+		return sql.NullString{}, errors.New("the passwords don't match")
+	}
+
+	return hashPassword(password)
+}
+
+// authenticate checks the password of user. Users created before passwords
+// existed can't log in until an admin sets one, or else anyone could claim
+// them by logging in first.
+func authenticate(user database.User) error {
+	if user.DeactivatedAt.Valid {
+		return fmt.Errorf("%s is deactivated, ask an admin to activate it", user.Name)
+	}
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("%s has no password yet, ask an admin to run `gator user set-password %s`", user.Name, user.Name)
+	}
+
+	return checkPassword(user)
+}
+
+// setPassword asks for a new password for user and saves it.
+func setPassword(db storage.Store, user database.User) error {
+	hash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+
+	err = db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: hash,
+		UpdatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("setting password. %v", err)
+	}
+
+	return nil
+}
+
+// hashToken is what the database keeps of a session token, so a copy of the
+// database doesn't let anyone log in.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSession creates a session for user and returns its token.
+func newSession(db storage.Store, user database.User) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating session token. %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	// Nobody else cleans them up
+	now := time.Now()
+	err := db.DeleteExpiredSessions(context.Background(), now)
+	if err != nil {
+		return "", fmt.Errorf("deleting expired sessions. %v", err)
+	}
+
+	_, err = db.CreateSession(context.Background(), database.CreateSessionParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(sessionLifetime),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating session. %v", err)
+	}
+
+	return token, nil
+}
+
+// startSession logs in as user and saves the token in the config.
+func startSession(s *state, user database.User) error {
+	token, err := newSession(s.db, user)
+	if err != nil {
+		return err
+	}
+
+	err = s.cfg.SetSession(user.Name, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sessionUser returns the user of the session in the config.
+func sessionUser(s *state) (database.User, error) {
+	login := "gator login <username>"
+	if s.cfg.CurrentUserName != "" {
+		login = "gator login " + s.cfg.CurrentUserName
+	}
+
+	if s.cfg.SessionToken == "" {
+		return database.User{}, fmt.Errorf("not logged in. Run `%s`", login)
+	}
+
+	session, err := s.db.GetSession(context.Background(), hashToken(s.cfg.SessionToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("your session is not valid. Run `%s`", login)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("getting session. %v", err)
+	}
+
+	if !session.ExpiresAt.After(time.Now()) {
+		s.db.DeleteSession(context.Background(), session.TokenHash)
+		return database.User{}, fmt.Errorf("your session expired. Run `%s`", login)
+	}
+
+	user, err := s.db.GetUserById(context.Background(), session.UserID)
+	if err != nil {
+		return database.User{}, fmt.Errorf("getting user. %v", err)
+	}
+	if user.DeactivatedAt.Valid {
+		return database.User{}, fmt.Errorf("%s is deactivated", user.Name)
+	}
+
+	return user, nil
+}
+
+// logout
+// Ends the session in the database too, so a copy of the token is useless.
+func handlerLogout(s *state, cmd command) error {
+	if s.cfg.SessionToken != "" {
+		err := s.db.DeleteSession(context.Background(), hashToken(s.cfg.SessionToken))
+		if err != nil {
+			return fmt.Errorf("deleting session. %v", err)
+		}
+	}
+
+	err := s.cfg.SetSession("", "")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Logged out.")
+
+	return nil
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/output"
+)
+
+// What a command needs before it runs
+const (
+	needsDatabase   = iota // the default, with an up to date schema
+	needsConnection        // a database, whatever its schema version
+	needsConfig
+	needsNothing
+)
+
+// commandSpec describes a command: the help text, the arguments and flags it
+// takes and the handler that runs it.
+type commandSpec struct {
+	name        string
+	description string
+	// Positional arguments as shown in the usage: <required> [optional],
+	// the last one can be repeated with "..."
+	args string
+	// Defines the flags of the command, if it has any
+	flags func(fs *flag.FlagSet)
+	// Candidates to complete the next positional argument, args are the ones before it
+	complete func(s *state, args []string) ([]string, error)
+	// nil for commands that only group subcommands
+	handler func(*state, command) error
+	// Subcommands share the needs of their command
+	needs       int
+	subcommands []*commandSpec
+	parent      *commandSpec
+	// Left out of help, suggestions and completion
+	hidden bool
+}
+
+// fullName is the name with the names of the parent commands, "migrate up".
+func (spec *commandSpec) fullName() string {
+	if spec.parent == nil {
+		return spec.name
+	}
+
+	return spec.parent.fullName() + " " + spec.name
+}
+
+// usage is the usage line of the command.
+func (spec *commandSpec) usage() string {
+	line := "gator " + spec.fullName()
+	if len(spec.subcommands) > 0 && spec.handler == nil {
+		line += " <command>"
+	} else if len(spec.subcommands) > 0 {
+		line += " [command]"
+	}
+	if spec.args != "" {
+		line += " " + spec.args
+	}
+	if spec.flags != nil {
+		line += " [flags]"
+	}
+
+	return line
+}
+
+// checkArgs returns an error if args don't match the positional arguments of the command.
+func (spec *commandSpec) checkArgs(args []string) error {
+	names := strings.Fields(spec.args)
+	required := 0
+	for _, name := range names {
+		if strings.HasPrefix(name, "<") {
+			required++
+		}
+	}
+
+	if len(args) < required {
+		missing := names[len(args):required]
+		if len(missing) == 1 {
+			return fmt.Errorf("missing argument %s", missing[0])
+		}
+		return fmt.Errorf("missing arguments %s", strings.Join(missing, " "))
+	}
+	for i, arg := range args {
+		if !spec.takesArg(i) {
+			return fmt.Errorf("unexpected argument %q", arg)
+		}
+	}
+
+	return nil
+}
+
+// takesArg reports whether the command takes a positional argument after n others.
+func (spec *commandSpec) takesArg(n int) bool {
+	names := strings.Fields(spec.args)
+	if len(names) > 0 && strings.HasSuffix(names[len(names)-1], "...") {
+		return true
+	}
+
+	return n < len(names)
+}
+
+// flagSet returns the flags of the command, global flags included.
+func (spec *commandSpec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.fullName(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineGlobalFlags(fs)
+	if spec.flags != nil {
+		spec.flags(fs)
+	}
+
+	return fs
+}
+
+// printHelp prints the usage, flags and subcommands of the command.
+func (spec *commandSpec) printHelp() {
+	fmt.Printf("Usage: %s\n\n%s.\n", spec.usage(), spec.description)
+
+	if spec.flags != nil {
+		fmt.Println("\nFlags:")
+		printFlags(spec.flagSet(), true)
+	}
+	if len(spec.subcommands) > 0 {
+		fmt.Println("\nCommands:")
+		printCommandList(spec.subcommands)
+	}
+}
+
+// defineGlobalFlags adds the flags every command takes to fs.
+func defineGlobalFlags(fs *flag.FlagSet) {
+	fs.Func("config", "read and write the config file at `path`", func(path string) error {
+		config.SetPath(path)
+		return nil
+	})
+	fs.Func("profile", "use the profile `name` for this run", func(name string) error {
+		config.SetProfile(name)
+		return nil
+	})
+	fs.Var(new(output.Format), "output", "print lists as `format` table, json, csv or tsv")
+	fs.String("fields", "", "print only these `fields` of lists, comma separated")
+}
+
+// isGlobalFlag reports whether name is one of the global flags.
+func isGlobalFlag(name string) bool {
+	globals := flag.NewFlagSet("gator", flag.ContinueOnError)
+	defineGlobalFlags(globals)
+
+	return globals.Lookup(name) != nil
+}
+
+// parseGlobalFlags takes the global flags (--config <path>, --profile <name>)
+// from the start of args and returns the rest.
+func parseGlobalFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineGlobalFlags(fs)
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.Args(), nil
+}
+
+// parseFlags parses the flags in args, wherever they are, and returns the
+// positional arguments. Everything after "--" is positional.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for len(args) > 0 {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		rest := fs.Args()
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	return positional, nil
+}
+
+// register adds a command, and its subcommands, to the list.
+func (c *commands) register(spec *commandSpec) {
+	for _, sub := range spec.subcommands {
+		sub.parent = spec
+		sub.needs = spec.needs
+	}
+
+	c.list = append(c.list, spec)
+}
+
+// find returns the command in args, going down into subcommands, and the
+// arguments left after its name.
+func (c *commands) find(args []string) (*commandSpec, []string, error) {
+	spec := findSpec(c.list, args[0])
+	if spec == nil {
+		return nil, nil, unknownCommand(args[0], args[0], c.list)
+	}
+
+	args = args[1:]
+	for len(spec.subcommands) > 0 && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub := findSpec(spec.subcommands, args[0])
+		if sub == nil {
+			return nil, nil, unknownCommand(spec.fullName()+" "+args[0], args[0], spec.subcommands)
+		}
+		spec, args = sub, args[1:]
+	}
+
+	return spec, args, nil
+}
+
+// errNoCommand is returned by lookup when args only have global flags.
+var errNoCommand = errors.New("missing command")
+
+// lookup finds the command in args, parses its flags and checks its
+// arguments. The error is flag.ErrHelp when --help was asked for, with a nil
+// spec if it came before the command.
+func (c *commands) lookup(args []string) (*commandSpec, command, error) {
+	// Global flags can also go before the command
+	rest, err := parseGlobalFlags(args)
+	if err != nil {
+		return nil, command{}, err
+	}
+	if len(rest) == 0 {
+		return nil, command{}, errNoCommand
+	}
+	leading := args[:len(args)-len(rest)]
+
+	spec, args, err := c.find(rest)
+	if err != nil {
+		return nil, command{}, err
+	}
+
+	fs := spec.flagSet()
+	positional, err := parseFlags(fs, append(slices.Clone(leading), args...))
+	if errors.Is(err, flag.ErrHelp) {
+		return spec, command{}, err
+	}
+	if err != nil {
+		return spec, command{}, fmt.Errorf("%v\nUsage: %s", err, spec.usage())
+	}
+
+	if spec.handler == nil {
+		return spec, command{}, fmt.Errorf("missing command\nUsage: %s\nRun `gator help %s` to see its commands", spec.usage(), spec.fullName())
+	}
+	err = spec.checkArgs(positional)
+	if err != nil {
+		return spec, command{}, fmt.Errorf("%v\nUsage: %s", err, spec.usage())
+	}
+
+	return spec, command{name: spec.fullName(), args: positional, flags: fs}, nil
+}
+
+func findSpec(specs []*commandSpec, name string) *commandSpec {
+	for _, spec := range specs {
+		if spec.name == name {
+			return spec
+		}
+	}
+
+	return nil
+}
+
+// unknownCommand returns the error for a command that doesn't exist,
+// suggesting the closest one in specs.
+func unknownCommand(fullName, name string, specs []*commandSpec) error {
+	msg := fmt.Sprintf("unknown command %q", fullName)
+	if suggestion := suggest(name, visibleNames(specs)); suggestion != "" {
+		msg += fmt.Sprintf(". Did you mean %q?", strings.TrimSuffix(fullName, name)+suggestion)
+	}
+
+	return errors.New(msg + "\nRun `gator help` to see all the commands")
+}
+
+// suggest returns the name closest to name when it's close enough to be a typo.
+func suggest(name string, names []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range names {
+		distance := levenshtein(name, candidate)
+		if distance < bestDistance && distance < len(name) {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best
+}
+
+// levenshtein is the number of single character edits that turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Only the previous row of the table is needed
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		row := make([]int, len(rb)+1)
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+		}
+		prev = row
+	}
+
+	return prev[len(rb)]
+}
+
+// printHelp prints the list of commands and the global flags.
+func (c *commands) printHelp() {
+	fmt.Println("gator aggregates RSS feeds from the command line.")
+	fmt.Println("\nUsage: gator [global flags] <command> [arguments]")
+	fmt.Println("\nCommands:")
+	printCommandList(c.list)
+	fmt.Println("\nGlobal flags:")
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	defineGlobalFlags(fs)
+	printFlags(fs, false)
+	fmt.Println("\nRun `gator help <command>` for the details of a command.")
+}
+
+// help [command] [subcommand]
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		c.printHelp()
+		return nil
+	}
+
+	spec, rest, err := c.find(cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return unknownCommand(spec.fullName()+" "+rest[0], rest[0], spec.subcommands)
+	}
+	spec.printHelp()
+
+	return nil
+}
+
+func printCommandList(specs []*commandSpec) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, spec := range specs {
+		if spec.hidden {
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", spec.name, spec.description)
+	}
+	w.Flush()
+}
+
+// printFlags lists the flags in fs, without the global ones if skipGlobal is set.
+func printFlags(fs *flag.FlagSet, skipGlobal bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		if skipGlobal && isGlobalFlag(f.Name) {
+			return
+		}
+
+		name, usage := flag.UnquoteUsage(f)
+		option := "--" + f.Name
+		if name != "" {
+			option += " <" + name + ">"
+		}
+		if f.DefValue != "" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", option, usage)
+	})
+	w.Flush()
+}
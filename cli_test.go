@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	cmds := newCommands()
+
+	// Flags go anywhere, everything after -- is an argument
+	spec, cmd, err := cmds.lookup([]string{"addfeed", "--auth", "basic", "News", "--", "--not-a-flag"})
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if spec.name != "addfeed" || strings.Join(cmd.args, " ") != "News --not-a-flag" || cmd.flag("auth") != "basic" {
+		t.Errorf("addfeed = %q %q auth %v", spec.name, cmd.args, cmd.flag("auth"))
+	}
+
+	_, cmd, err = cmds.lookup([]string{"agg", "30s", "--dry-run"})
+	if err != nil || cmd.flag("dry-run") != true || len(cmd.args) != 1 {
+		t.Errorf("agg = %q dry-run %v, %v", cmd.args, cmd.flag("dry-run"), err)
+	}
+	if cmd.flag("nope") != nil {
+		t.Errorf("unknown flag = %v", cmd.flag("nope"))
+	}
+
+	spec, cmd, err = cmds.lookup([]string{"migrate", "to", "3"})
+	if err != nil || spec.fullName() != "migrate to" || cmd.name != "migrate to" || cmd.args[0] != "3" {
+		t.Errorf("migrate to = %q %+v, %v", spec.fullName(), cmd, err)
+	}
+	if spec.needs != needsConnection {
+		t.Errorf("subcommands should share the needs of their command")
+	}
+
+	// Commands with subcommands may run on their own
+	spec, _, err = cmds.lookup([]string{"profile"})
+	if err != nil || spec.name != "profile" {
+		t.Errorf("profile = %v, %v", spec, err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"brwse"}, `unknown command "brwse". Did you mean "browse"?`},
+		{[]string{"xyz"}, `unknown command "xyz"` + "\nRun `gator help`"},
+		{[]string{"migrate", "stauts"}, `unknown command "migrate stauts". Did you mean "migrate status"?`},
+		{[]string{"migrate"}, "missing command\nUsage: gator migrate <command>"},
+		{[]string{"login"}, "missing argument <username>\nUsage: gator login <username>"},
+		{[]string{"addfeed"}, "missing arguments <name> <url>"},
+		{[]string{"users", "extra"}, `unexpected argument "extra"`},
+		{[]string{"agg", "1m", "--dryrun"}, "flag provided but not defined: -dryrun\nUsage: gator agg <time_between_reqs> [flags]"},
+		{[]string{"addfeed", "x", "y", "--auth"}, "flag needs an argument: -auth"},
+	}
+	for _, test := range tests {
+		_, _, err := cmds.lookup(test.args)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("lookup(%q) = %v, want %q", test.args, err, test.want)
+		}
+	}
+
+	spec, _, err = cmds.lookup([]string{"follow", "--help"})
+	if !errors.Is(err, flag.ErrHelp) || spec.name != "follow" {
+		t.Errorf("follow --help = %v, %v", spec, err)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	s := &state{}
+
+	out := mustRun(t, s, "help")
+	for _, want := range []string{"Usage: gator [global flags] <command>", "  browse ", "  migrate ", "--config <path>", "--profile <name>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help output misses %q:\n%s", want, out)
+		}
+	}
+
+	out = mustRun(t, s, "help", "addfeed")
+	if !strings.HasPrefix(out, "Usage: gator addfeed <name> <url> [flags]\n\nAdd a feed and follow it.\n") ||
+		!strings.Contains(out, "--auth <type>") || strings.Contains(out, "--config") {
+		t.Errorf("help addfeed output = %q", out)
+	}
+	if again := mustRun(t, s, "addfeed", "--help"); again != out {
+		t.Errorf("addfeed --help = %q, want %q", again, out)
+	}
+
+	out = mustRun(t, s, "help", "migrate")
+	if !strings.Contains(out, "Commands:\n") || !strings.Contains(out, "  to ") {
+		t.Errorf("help migrate output = %q", out)
+	}
+	out = mustRun(t, s, "help", "migrate", "to")
+	if !strings.HasPrefix(out, "Usage: gator migrate to <version>\n") {
+		t.Errorf("help migrate to output = %q", out)
+	}
+
+	for _, args := range [][]string{{"nope"}, {"users", "x"}, {"migrate", "sideways"}} {
+		if _, err := runCommand(t, s, "help", args...); err == nil {
+			t.Errorf("help %q should fail", args)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	if d := levenshtein("kitten", "sitting"); d != 3 {
+		t.Errorf("levenshtein = %d, want 3", d)
+	}
+
+	names := []string{"follow", "following", "feeds", "agg"}
+	tests := map[string]string{
+		"folow":     "follow",
+		"followng":  "following",
+		"feed":      "feeds",
+		"ag":        "agg",
+		"x":         "",
+		"something": "",
+	}
+	for name, want := range tests {
+		if got := suggest(name, names); got != want {
+			t.Errorf("suggest(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/output"
+	"github.com/neixir/gator/internal/rss"
+)
+
+// Completion scripts. They all ask `gator __complete` for the candidates,
+// passing the words typed after `gator`, the one being completed last.
+const bashCompletion = `# bash completion for gator
+# Load it with: source <(gator completion bash)
+_gator() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        # Don't split URLs on the colon
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur=${COMP_WORDS[COMP_CWORD]} words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+    fi
+
+    local IFS=$'\n'
+    COMPREPLY=($(gator __complete -- "${words[@]:1:cword}" 2>/dev/null))
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+# Load it with: source <(gator completion zsh), or save it as _gator in your $fpath
+_gator() {
+    local -a candidates
+    candidates=(${(f)"$(gator __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -Q -a candidates
+}
+
+if [ "$funcstack[1]" = "_gator" ]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`
+
+const fishCompletion = `# fish completion for gator
+# Load it with: gator completion fish | source
+function __gator_complete
+    set -l tokens (commandline -opc)
+    set -l current (commandline -ct)
+    gator __complete -- $tokens[2..-1] "$current" 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+// completion <shell>
+func handlerCompletion(s *state, cmd command) error {
+	script, ok := completionScripts[cmd.args[0]]
+	if !ok {
+		return fmt.Errorf("unknown shell %q (bash, zsh or fish)", cmd.args[0])
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+func completeShells(s *state, args []string) ([]string, error) {
+	return []string{"bash", "fish", "zsh"}, nil
+}
+
+// __complete [word]...
+// Prints the candidates for the last word, one per line. Completion must
+// never get in the way, so errors just mean no candidates.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	for _, candidate := range c.complete(s, cmd.args) {
+		fmt.Println(candidate)
+	}
+
+	return nil
+}
+
+// complete returns the candidates for the last of words, the words typed
+// after `gator`.
+func (c *commands) complete(s *state, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	words = words[:len(words)-1]
+
+	// The global flags before the command, --config and --profile change
+	// where the candidates come from
+	for i := 0; i < len(words) && strings.HasPrefix(words[i], "-"); i++ {
+		if !strings.Contains(words[i], "=") {
+			if i == len(words)-1 {
+				// current is the value of this flag
+				return filterPrefix(flagValues(s, words[i]), current)
+			}
+			i++
+		}
+	}
+	words, err := parseGlobalFlags(words)
+	if err != nil {
+		return nil
+	}
+	if len(words) == 0 {
+		if strings.HasPrefix(current, "-") {
+			global := flag.NewFlagSet("gator", flag.ContinueOnError)
+			defineGlobalFlags(global)
+			return filterPrefix(flagNames(global), current)
+		}
+		return filterPrefix(visibleNames(c.list), current)
+	}
+
+	spec := findSpec(c.list, words[0])
+	if spec == nil {
+		return nil
+	}
+	rest := words[1:]
+	for len(spec.subcommands) > 0 && len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		spec = findSpec(spec.subcommands, rest[0])
+		if spec == nil {
+			return nil
+		}
+		rest = rest[1:]
+	}
+
+	fs := spec.flagSet()
+	positional := []string{}
+	for i := 0; i < len(rest); i++ {
+		if !strings.HasPrefix(rest[i], "-") || rest[i] == "-" {
+			positional = append(positional, rest[i])
+			continue
+		}
+		f := fs.Lookup(strings.TrimLeft(rest[i], "-"))
+		if f != nil && !isBoolFlag(f) && !strings.Contains(rest[i], "=") {
+			if i == len(rest)-1 {
+				return filterPrefix(flagValues(s, rest[i]), current)
+			}
+			i++
+		}
+	}
+
+	var candidates []string
+	switch {
+	case strings.HasPrefix(current, "-"):
+		candidates = flagNames(fs)
+	case len(spec.subcommands) > 0 && len(positional) == 0:
+		candidates = visibleNames(spec.subcommands)
+	case spec.complete != nil && spec.takesArg(len(positional)):
+		cs, done, err := completionState(s, spec.needs)
+		if err != nil {
+			return nil
+		}
+		defer done()
+		candidates, _ = spec.complete(cs, positional)
+	}
+
+	return filterPrefix(candidates, current)
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	names := []string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+
+	return names
+}
+
+// flagValues returns the values of the flag in word, for the flags that
+// have a known set of them.
+func flagValues(s *state, word string) []string {
+	switch strings.TrimLeft(word, "-") {
+	case "profile":
+		cs, done, err := completionState(s, needsConfig)
+		if err != nil {
+			return nil
+		}
+		defer done()
+		profiles, _ := completeProfiles(cs, nil)
+		return profiles
+	case "transfer-to":
+		cs, done, err := completionState(s, needsDatabase)
+		if err != nil {
+			return nil
+		}
+		defer done()
+		users, _ := completeUsernames(cs, nil)
+		return users
+	case "auth":
+		return []string{rss.AuthBasic, rss.AuthBearer, rss.AuthCookie}
+	case "output":
+		formats := []string{}
+		for _, format := range output.Formats {
+			formats = append(formats, string(format))
+		}
+		return formats
+	}
+
+	return nil
+}
+
+// completionState returns s with what a command with needs requires, opened
+// like main does. done closes what was opened.
+func completionState(s *state, needs int) (cs *state, done func(), err error) {
+	done = func() {}
+	if needs == needsNothing || s.db != nil {
+		return s, done, nil
+	}
+
+	cfg, err := loadConfig(needs)
+	if err != nil {
+		return nil, nil, err
+	}
+	cs = &state{cfg: cfg}
+	if needs == needsConfig {
+		return cs, done, nil
+	}
+
+	conn, err := connect(cfg.DbUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	cs.db, cs.conn = conn, conn
+
+	return cs, func() { conn.Close() }, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func visibleNames(specs []*commandSpec) []string {
+	names := []string{}
+	for _, spec := range specs {
+		if !spec.hidden {
+			names = append(names, spec.name)
+		}
+	}
+
+	return names
+}
+
+func filterPrefix(candidates []string, prefix string) []string {
+	matches := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	return matches
+}
+
+// Completers of positional arguments
+
+// firstArg completes only the first argument with complete.
+func firstArg(complete func(s *state, args []string) ([]string, error)) func(s *state, args []string) ([]string, error) {
+	return func(s *state, args []string) ([]string, error) {
+		if len(args) > 0 {
+			return nil, nil
+		}
+		return complete(s, args)
+	}
+}
+
+func completeUsernames(s *state, args []string) ([]string, error) {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names, nil
+}
+
+func completeFeedURLs(s *state, args []string) ([]string, error) {
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for _, feed := range feeds {
+		urls = append(urls, feed.Url)
+	}
+	return urls, nil
+}
+
+// completeFollowedURLs returns the URLs of the feeds the current user follows.
+func completeFollowedURLs(s *state, args []string) ([]string, error) {
+	user, err := sessionUser(s)
+	if err != nil {
+		return nil, err
+	}
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for _, feed := range feeds {
+		followed := slices.ContainsFunc(follows, func(follow database.GetFeedFollowsForUserRow) bool {
+			return follow.FeedID == feed.ID
+		})
+		if followed {
+			urls = append(urls, feed.Url)
+		}
+	}
+	return urls, nil
+}
+
+func completeProfiles(s *state, args []string) ([]string, error) {
+	return append([]string{defaultProfile}, s.cfg.ProfileNames()...), nil
+}
+
+// completeCommands completes help [command] [subcommand].
+func (c *commands) completeCommands(s *state, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return visibleNames(c.list), nil
+	}
+
+	spec := findSpec(c.list, args[0])
+	if spec == nil || len(args) > 1 {
+		return nil, nil
+	}
+	return visibleNames(spec.subcommands), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neixir/gator/internal/config"
+)
+
+// completions runs `gator __complete -- words...` and returns the candidates.
+func completions(t *testing.T, s *state, words ...string) string {
+	t.Helper()
+
+	out := mustRun(t, s, "__complete", append([]string{"--"}, words...)...)
+	return strings.Join(strings.Fields(out), " ")
+}
+
+func TestCompletion(t *testing.T) {
+	forEachBackend(t, testCompletion)
+}
+
+func testCompletion(t *testing.T, s *state) {
+	// --profile applies to the whole process
+	t.Cleanup(func() { config.SetProfile("") })
+
+	register(t, s, "alice")
+	mustRun(t, s, "addfeed", "One", "https://one.example/feed.xml")
+	mustRun(t, s, "addfeed", "Two", "https://two.example/feed.xml")
+	register(t, s, "bob")
+	mustRun(t, s, "follow", "https://two.example/feed.xml")
+
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		// Commands, hidden ones left out
+		{[]string{"fol"}, "follow following"},
+		{[]string{"__"}, ""},
+		{[]string{"--profile", "work", "us"}, "user users"},
+		// Subcommands, flags and their values
+		{[]string{"migrate", ""}, "up down status to"},
+		{[]string{"migrate", "up", ""}, ""},
+		{[]string{"agg", "--d"}, "--dry-run"},
+		{[]string{"addfeed", "x", "y", "--auth", "b"}, "basic bearer"},
+		{[]string{"--"}, "--config --fields --output --profile"},
+		{[]string{"feeds", "--output", "t"}, "table tsv"},
+		// Arguments from the database
+		{[]string{"login", ""}, "alice bob"},
+		{[]string{"login", "alice", ""}, ""},
+		{[]string{"user", "delete", "alice", "--transfer-to", "b"}, "bob"},
+		{[]string{"follow", "https://o"}, "https://one.example/feed.xml"},
+		{[]string{"unfollow", ""}, "https://two.example/feed.xml"},
+		{[]string{"feed", "rename", "https://t"}, "https://two.example/feed.xml"},
+		{[]string{"feed", "rename", "https://two.example/feed.xml", ""}, ""},
+		{[]string{"feed", "transfer", "https://two.example/feed.xml", "a"}, "alice"},
+		{[]string{"help", "mig"}, "migrate"},
+		{[]string{"help", "migrate", "s"}, "status"},
+		{[]string{"completion", ""}, "bash fish zsh"},
+		{[]string{"nope", ""}, ""},
+	}
+	for _, test := range tests {
+		if got := completions(t, s, test.words...); got != test.want {
+			t.Errorf("complete %q = %q, want %q", test.words, got, test.want)
+		}
+	}
+}
+
+func TestCompletionReadsTheConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GATOR_PROFILE", "")
+	cfg := &config.Config{DbUrl: "sqlite://home.db"}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := cfg.AddProfile("work", "sqlite://work.db"); err != nil {
+		t.Fatalf("AddProfile: %v", err)
+	}
+
+	// Like main, __complete starts with an empty state
+	s := &state{cfg: &config.Config{}}
+	if got := completions(t, s, "profile", "use", ""); got != "default work" {
+		t.Errorf("profile use completions = %q", got)
+	}
+	if got := completions(t, s, "--profile", "w"); got != "work" {
+		t.Errorf("--profile completions = %q", got)
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	s := &state{}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out := mustRun(t, s, "completion", shell)
+		if !strings.Contains(out, "gator __complete -- ") {
+			t.Errorf("%s script = %q", shell, out)
+		}
+	}
+
+	if _, err := runCommand(t, s, "completion", "powershell"); err == nil {
+		t.Errorf("completion for an unknown shell should fail")
+	}
+	if out := mustRun(t, s, "help"); strings.Contains(out, "__complete") {
+		t.Errorf("help lists the hidden command: %q", out)
+	}
+}
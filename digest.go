@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/digest"
+	"github.com/neixir/gator/internal/notify"
+)
+
+// How far back the first digest of a user goes
+const defaultDigestSince = 24 * time.Hour
+
+// buildDigest collects the unread posts of user that gator saved after
+// since. Filter rules apply: hidden posts and the ones they mark as read
+// are left out.
+func buildDigest(s *state, user database.User, since, until time.Time) (digest.Digest, error) {
+	ctx := context.Background()
+	d := digest.Digest{User: user.Name, Since: since, Until: until}
+
+	posts, err := s.db.GetNewPostsForUser(ctx, database.GetNewPostsForUserParams{UserID: user.ID, CreatedAt: since})
+	if err != nil {
+		return d, fmt.Errorf("getting new posts for [%s] -- %v", user.Name, err)
+	}
+	views, err := filterPosts(s, user, posts)
+	if err != nil {
+		return d, err
+	}
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return d, fmt.Errorf("getting feed list. %v", err)
+	}
+
+	byFeed := map[uuid.UUID]*digest.Feed{}
+	for _, feed := range feeds {
+		byFeed[feed.ID] = &digest.Feed{Name: feed.Name, URL: feed.Url}
+	}
+	for _, post := range views {
+		if post.Read || !post.FeedID.Valid || byFeed[post.FeedID.UUID] == nil {
+			continue
+		}
+		feed := byFeed[post.FeedID.UUID]
+		feed.Posts = append(feed.Posts, digest.Post{
+			Title:       post.Title,
+			URL:         post.Url,
+			Author:      post.Author.String,
+			PublishedAt: post.PublishedAt.Time,
+			Summary:     digest.Summarize(descriptionText(post.Post)),
+			Highlight:   post.Highlight,
+			Tags:        post.Tags,
+		})
+	}
+	for _, feed := range byFeed {
+		if len(feed.Posts) > 0 {
+			d.Feeds = append(d.Feeds, *feed)
+		}
+	}
+	slices.SortFunc(d.Feeds, func(a, b digest.Feed) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return d, nil
+}
+
+// digest [--since 24h] [--file <path>] [--email <address>] [--dry-run]
+// The unread posts since the user's last digest, or the last day for the
+// first one. Without --file or --email it is printed.
+func handlerDigest(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	sinceFlag, _ := cmd.flag("since").(time.Duration)
+	file, _ := cmd.flag("file").(string)
+	email, _ := cmd.flag("email").(string)
+	dryRun, _ := cmd.flag("dry-run").(bool)
+	if sinceFlag < 0 {
+		return errors.New("--since can't be negative")
+	}
+	if email != "" {
+		if s.cfg.SMTP == nil {
+			return errors.New("--email needs an smtp section in the config file")
+		}
+		if err := notify.ValidAddress(email); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	since := now.Add(-sinceFlag)
+	if sinceFlag == 0 {
+		last, err := s.db.GetLastDigest(ctx, user.ID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			since = now.Add(-defaultDigestSince)
+		case err != nil:
+			return fmt.Errorf("getting last digest. %v", err)
+		default:
+			since = last.SentAt
+		}
+	}
+
+	d, err := buildDigest(s, user, since, now)
+	if err != nil {
+		return err
+	}
+	if d.Count() == 0 {
+		fmt.Printf("No new posts since %s.\n", since.Format(time.DateTime))
+		return nil
+	}
+	text, err := d.Text()
+	if err != nil {
+		return err
+	}
+
+	if dryRun || (file == "" && email == "") {
+		fmt.Print(text)
+	}
+	if dryRun {
+		return nil
+	}
+
+	if file != "" {
+		content := text
+		if ext := strings.ToLower(filepath.Ext(file)); ext == ".html" || ext == ".htm" {
+			content, err = d.HTML()
+			if err != nil {
+				return err
+			}
+		}
+		err = os.WriteFile(file, []byte(content), 0644)
+		if err != nil {
+			return fmt.Errorf("writing digest. %v", err)
+		}
+		fmt.Printf("Wrote the digest of %d post(s) to %s.\n", d.Count(), file)
+	}
+
+	if email != "" {
+		html, err := d.HTML()
+		if err != nil {
+			return err
+		}
+		err = notify.SendMail(ctx, *s.cfg.SMTP, notify.Mail{
+			To:      []string{email},
+			Subject: d.Subject(),
+			Text:    text,
+			HTML:    html,
+		})
+		if err != nil {
+			return fmt.Errorf("sending digest. %v", err)
+		}
+		fmt.Printf("Sent the digest of %d post(s) to %s.\n", d.Count(), email)
+	}
+
+	err = s.db.SetLastDigest(ctx, database.SetLastDigestParams{UserID: user.ID, SentAt: now})
+	if err != nil {
+		return fmt.Errorf("saving last digest. %v", err)
+	}
+
+	return nil
+}
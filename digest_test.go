@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/feedtest"
+	"github.com/neixir/gator/internal/smtptest"
+)
+
+func TestDigest(t *testing.T) {
+	forEachBackend(t, testDigest)
+}
+
+func testDigest(t *testing.T, s *state) {
+	feeds := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml": {Fixture: "rss2.xml"},
+	})
+	url := feeds.FeedURL("/rss2.xml")
+
+	register(t, s, "alice")
+	if out := mustRun(t, s, "digest"); !strings.Contains(out, "No new posts since") {
+		t.Errorf("digest without posts = %q", out)
+	}
+	mustRun(t, s, "addfeed", "Boot.dev", url)
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+
+	// A dry run doesn't move the start of the next one
+	for range 2 {
+		out := mustRun(t, s, "digest", "--dry-run")
+		if !strings.Contains(out, "gator digest: 3 new posts in 1 feed") || !strings.Contains(out, "== Boot.dev (3 posts)") || !strings.Contains(out, "* Why I Write Tests") {
+			t.Errorf("digest --dry-run = %q", out)
+		}
+	}
+
+	// Filter rules apply
+	mustRun(t, s, "filter", "add", "--title-contains", "tests", "--action", "hide")
+	mustRun(t, s, "filter", "add", "--title-contains", "beat", "--action", "mark-read")
+	mustRun(t, s, "filter", "add", "--title-contains", "go", "--action", "tag", "--tag", "go")
+	path := filepath.Join(t.TempDir(), "digest.html")
+	if out := mustRun(t, s, "digest", "--file", path); !strings.Contains(out, "Wrote the digest of 1 post(s)") {
+		t.Errorf("digest --file = %q", out)
+	}
+	html, _ := os.ReadFile(path)
+	if !strings.Contains(string(html), "<!DOCTYPE html>") || !strings.Contains(string(html), "Is Go a good first language?") || !strings.Contains(string(html), ">go</span>") || strings.Contains(string(html), "Why I Write Tests") {
+		t.Errorf("digest.html = %s", html)
+	}
+	if out := mustRun(t, s, "digest"); !strings.Contains(out, "No new posts since") {
+		t.Errorf("second digest = %q", out)
+	}
+	if out := mustRun(t, s, "digest", "--since", "1h"); !strings.Contains(out, "1 new post in 1 feed") {
+		t.Errorf("digest --since 1h = %q", out)
+	}
+
+	// Each user has their own
+	register(t, s, "bob")
+	mustRun(t, s, "follow", url)
+	if _, err := runCommand(t, s, "digest", "--email", "bob@example.com"); err == nil || !strings.Contains(err.Error(), "smtp section") {
+		t.Errorf("digest --email without smtp: err = %v", err)
+	}
+	server := smtptest.NewServer(t)
+	s.cfg.SMTP = &config.SMTP{Host: server.Host, Port: server.Port, From: "gator@example.com"}
+	if out := mustRun(t, s, "digest", "--email", "bob@example.com"); !strings.Contains(out, "Sent the digest of 3 post(s) to bob@example.com.") {
+		t.Errorf("digest --email = %q", out)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].To[0] != "bob@example.com" {
+		t.Fatalf("emails = %+v", messages)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if m.Header.Get("Subject") != "gator digest: 3 new posts in 1 feed" || !strings.HasPrefix(m.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("header = %v", m.Header)
+	}
+	if out := mustRun(t, s, "digest", "--email", "bob@example.com"); !strings.Contains(out, "No new posts since") || len(server.Messages()) != 1 {
+		t.Errorf("second digest --email = %q", out)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/storage"
+	"github.com/neixir/gator/internal/storage/memory"
+)
+
+// newDryRunStore copies users, feeds with their fetch times, readability and
+// posts, credentials and follows from src into memory. Scraping against the
+// copy fetches and parses everything but saves nothing, and only adds what
+// is really new.
+func newDryRunStore(src storage.Store) (storage.Store, error) {
+	ctx := context.Background()
+	dst := memory.New()
+
+	users, err := src.GetUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting user list. %v", err)
+	}
+	for _, user := range users {
+		_, err = dst.CreateUser(ctx, database.CreateUserParams{
+			ID:           user.ID,
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+			Name:         user.Name,
+			PasswordHash: user.PasswordHash,
+			IsAdmin:      user.IsAdmin,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("copying user %s. %v", user.Name, err)
+		}
+	}
+
+	feeds, err := src.GetFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting feed list. %v", err)
+	}
+	for _, feed := range feeds {
+		_, err = dst.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        feed.ID,
+			CreatedAt: feed.CreatedAt,
+			UpdatedAt: feed.UpdatedAt,
+			Name:      feed.Name,
+			Url:       feed.Url,
+			UserID:    feed.UserID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("copying feed %s. %v", feed.Name, err)
+		}
+
+		// Keeps the order the real scraper would fetch them in
+		if feed.LastFetchedAt.Valid {
+			err = dst.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: feed.ID, LastFetchedAt: feed.LastFetchedAt})
+			if err != nil {
+				return nil, fmt.Errorf("copying feed %s. %v", feed.Name, err)
+			}
+		}
+
+		// So the articles are fetched and extracted too
+		if feed.Readability {
+			err = dst.SetFeedReadability(ctx, database.SetFeedReadabilityParams{ID: feed.ID, Readability: true, UpdatedAt: feed.UpdatedAt})
+			if err != nil {
+				return nil, fmt.Errorf("copying feed %s. %v", feed.Name, err)
+			}
+		}
+
+		// Posts are told apart by URL, the ones saved already aren't new
+		posts, err := src.GetPostsForFeed(ctx, feed.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting posts of %s. %v", feed.Name, err)
+		}
+		for _, post := range posts {
+			_, err = dst.CreatePost(ctx, database.CreatePostParams{
+				ID:              post.ID,
+				CreatedAt:       post.CreatedAt,
+				UpdatedAt:       post.UpdatedAt,
+				Title:           post.Title,
+				Url:             post.Url,
+				Description:     post.Description,
+				PublishedAt:     post.PublishedAt,
+				FeedID:          post.FeedID,
+				Author:          post.Author,
+				DescriptionText: post.DescriptionText,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("copying posts of %s. %v", feed.Name, err)
+			}
+		}
+
+		cred, err := src.GetFeedCredential(ctx, feed.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting credentials for %s. %v", feed.Name, err)
+		}
+		_, err = dst.SetFeedCredential(ctx, database.SetFeedCredentialParams{
+			FeedID:    cred.FeedID,
+			CreatedAt: cred.CreatedAt,
+			UpdatedAt: cred.UpdatedAt,
+			AuthType:  cred.AuthType,
+			Secret:    cred.Secret,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("copying credentials for %s. %v", feed.Name, err)
+		}
+	}
+
+	// Follows, so posts in the copy are visible like they would be, and
+	// because only followed feeds are fetched
+	for _, user := range users {
+		follows, err := src.GetFeedFollowsForUser(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting follows for %s. %v", user.Name, err)
+		}
+		for _, follow := range follows {
+			_, err = dst.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+				ID:        follow.ID,
+				CreatedAt: follow.CreatedAt,
+				UpdatedAt: follow.UpdatedAt,
+				UserID:    follow.UserID,
+				FeedID:    follow.FeedID,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("copying follows for %s. %v", user.Name, err)
+			}
+		}
+	}
+
+	return dst, nil
+}
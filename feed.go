@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/storage"
+)
+
+// ownedFeed returns the feed at url if user can change it: the user who
+// added it or an admin.
+func ownedFeed(s *state, user database.User, url string) (database.Feed, error) {
+	feed, err := s.db.GetFeedByUrl(context.Background(), url)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("the feed does not exist. %v", err)
+	}
+	if feed.UserID != user.ID && !user.IsAdmin {
+		return database.Feed{}, fmt.Errorf("only the user who added %q or an admin can change it", feed.Name)
+	}
+
+	return feed, nil
+}
+
+// feed rename <url> <new-name>
+func handlerFeedRename(s *state, cmd command, user database.User) error {
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	name := cmd.args[1]
+	if name == "" {
+		return errors.New("the new name is empty")
+	}
+
+	err = s.db.RenameFeed(context.Background(), database.RenameFeedParams{
+		ID:        feed.ID,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("renaming feed. %v", err)
+	}
+
+	fmt.Printf("Renamed %q to %q.\n", feed.Name, name)
+	return nil
+}
+
+// feed set-url <url> <new-url>
+// Follows, posts and credentials stay. The feed is fetched again first thing.
+func handlerFeedSetUrl(s *state, cmd command, user database.User) error {
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	url := cmd.args[1]
+
+	err = s.db.SetFeedUrl(context.Background(), database.SetFeedUrlParams{
+		ID:        feed.ID,
+		Url:       url,
+		UpdatedAt: time.Now(),
+	})
+	if storage.IsUniqueViolation(err) {
+		return fmt.Errorf("there is already a feed at %s", url)
+	}
+	if err != nil {
+		return fmt.Errorf("changing feed URL. %v", err)
+	}
+
+	fmt.Printf("%q is now at %s.\n", feed.Name, url)
+	return nil
+}
+
+// feed readability <url> <on|off>
+// Only the posts saved from now on get their content.
+func handlerFeedReadability(s *state, cmd command, user database.User) error {
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	var on bool
+	switch cmd.args[1] {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	default:
+		return fmt.Errorf("%q is not on or off", cmd.args[1])
+	}
+
+	err = s.db.SetFeedReadability(context.Background(), database.SetFeedReadabilityParams{
+		ID:          feed.ID,
+		Readability: on,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("changing readability. %v", err)
+	}
+
+	fmt.Printf("Readability is %s for %q.\n", cmd.args[1], feed.Name)
+	return nil
+}
+
+// feed remove <url>
+// Every follow and post of the feed goes with it, so it asks first and says
+// how many other users follow it.
+func handlerFeedRemove(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	followers, err := otherFollowers(s, feed, user)
+	if err != nil {
+		return err
+	}
+	if followers > 0 {
+		fmt.Printf("Warning: %d other users follow %q, they will lose it and its posts.\n", followers, feed.Name)
+	}
+
+	yes, _ := cmd.flag("yes").(bool)
+	if !yes {
+		answer, err := promptLine(fmt.Sprintf("Remove %q? [y/N]", feed.Name))
+		if err != nil {
+			return fmt.Errorf("%v. Pass --yes to remove without asking", err)
+		}
+		if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			return errors.New("remove cancelled")
+		}
+	}
+
+	err = s.db.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("removing feed. %v", err)
+	}
+
+	fmt.Printf("Removed %q.\n", feed.Name)
+	return nil
+}
+
+// otherFollowers counts the followers of feed other than user.
+func otherFollowers(s *state, feed database.Feed, user database.User) (int64, error) {
+	ctx := context.Background()
+	n, err := s.db.CountFeedFollowers(ctx, feed.ID)
+	if err != nil {
+		return 0, fmt.Errorf("counting followers. %v", err)
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("getting follows. %v", err)
+	}
+	for _, follow := range follows {
+		if follow.FeedID == feed.ID {
+			n--
+		}
+	}
+
+	return n, nil
+}
+
+// feed transfer <url> <username>
+func handlerFeedTransfer(s *state, cmd command, user database.User) error {
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	owner, err := s.db.GetUser(context.Background(), cmd.args[1])
+	if err != nil {
+		return fmt.Errorf("the user does not exist. %v", err)
+	}
+	if owner.DeactivatedAt.Valid {
+		return fmt.Errorf("%s is deactivated", owner.Name)
+	}
+
+	err = s.db.TransferFeed(context.Background(), database.TransferFeedParams{
+		ID:        feed.ID,
+		UserID:    owner.ID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("transferring feed. %v", err)
+	}
+
+	fmt.Printf("Gave %q to %s.\n", feed.Name, owner.Name)
+	return nil
+}
+
+// completeFeedTransfer completes feed transfer <url> <username>.
+func completeFeedTransfer(s *state, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return completeFeedURLs(s, args)
+	}
+
+	return completeUsernames(s, args)
+}
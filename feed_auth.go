@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/rss"
+	"github.com/neixir/gator/internal/secret"
+)
+
+// credentialsKey returns the decoded key used to encrypt feed credentials.
+func credentialsKey(cfg *config.Config) ([]byte, error) {
+	key, err := secret.ParseKey(cfg.GetCredentialsKey())
+	if errors.Is(err, secret.ErrNoKey) {
+		return nil, fmt.Errorf("%v. Set GATOR_CREDENTIALS_KEY or \"credentials_key\" in the config file to a base64 encoded 32 byte key (openssl rand -base64 32)", err)
+	}
+
+	return key, err
+}
+
+// promptFeedAuth asks the user for the credentials needed by authType.
+func promptFeedAuth(authType string) (*rss.Auth, error) {
+	auth := rss.Auth{Type: authType}
+
+	var err error
+	switch authType {
+	case rss.AuthBasic:
+		auth.Username, err = promptLine("Username")
+		if err == nil {
+			auth.Password, err = promptSecret("Password")
+		}
+	case rss.AuthBearer:
+		auth.Token, err = promptSecret("Token")
+	case rss.AuthCookie:
+		auth.Cookie, err = promptSecret("Cookie")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = auth.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+// sealFeedAuth encrypts credentials for storage.
+func sealFeedAuth(cfg *config.Config, auth *rss.Auth) ([]byte, error) {
+	key, err := credentialsKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(auth)
+	if err != nil {
+		return nil, fmt.Errorf("encoding credentials. %v", err)
+	}
+
+	sealed, err := secret.Seal(key, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting credentials. %v", err)
+	}
+
+	return sealed, nil
+}
+
+// openFeedAuth decrypts credentials sealed by sealFeedAuth.
+func openFeedAuth(cfg *config.Config, sealed []byte) (*rss.Auth, error) {
+	key, err := credentialsKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := secret.Open(key, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := rss.Auth{}
+	err = json.Unmarshal(plaintext, &auth)
+	if err != nil {
+		return nil, fmt.Errorf("decoding credentials. %v", err)
+	}
+
+	return &auth, nil
+}
+
+// saveFeedAuth encrypts the credentials and stores them for the feed.
+func saveFeedAuth(s *state, feedID uuid.UUID, auth *rss.Auth) error {
+	sealed, err := sealFeedAuth(s.cfg, auth)
+	if err != nil {
+		return err
+	}
+
+	arg := database.SetFeedCredentialParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		AuthType:  auth.Type,
+		Secret:    sealed,
+	}
+
+	_, err = s.db.SetFeedCredential(context.Background(), arg)
+	if err != nil {
+		return fmt.Errorf("saving credentials. %v", err)
+	}
+
+	return nil
+}
+
+// loadFeedAuth returns the decrypted credentials of a feed, or nil if it has none.
+func loadFeedAuth(db scraperDB, cfg *config.Config, feedID uuid.UUID) (*rss.Auth, error) {
+	cred, err := db.GetFeedCredential(context.Background(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting credentials. %v", err)
+	}
+
+	return openFeedAuth(cfg, cred.Secret)
+}
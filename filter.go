@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/output"
+	"github.com/neixir/gator/internal/sanitize"
+)
+
+// What a filter rule does with the posts that match
+const (
+	actionHide      = "hide"
+	actionMarkRead  = "mark-read"
+	actionHighlight = "highlight"
+	actionTag       = "tag"
+)
+
+var filterActions = []string{actionHide, actionMarkRead, actionHighlight, actionTag}
+
+// The parts of a post a rule looks at, and how
+var (
+	filterFields  = []string{"title", "description", "author"}
+	filterMatches = []string{"contains", "regex"}
+)
+
+// postConditionFlags defines --title-contains, --title-regex and the rest,
+// for the rules that have one of them, and --feed.
+func postConditionFlags(fs *flag.FlagSet) {
+	for _, field := range filterFields {
+		fs.String(field+"-contains", "", "match the posts whose "+field+" contains `text`, ignoring case")
+		fs.String(field+"-regex", "", "match the posts whose "+field+" matches the regular expression `re`")
+	}
+	fs.String("feed", "", "only the posts of the feed at `url`")
+}
+
+// postConditionFromFlags returns the condition given to cmd with
+// postConditionFlags.
+func postConditionFromFlags(s *state, cmd command) (postCondition, error) {
+	var field, match, pattern string
+	var given []string
+	for _, f := range filterFields {
+		for _, m := range filterMatches {
+			value, _ := cmd.flag(f + "-" + m).(string)
+			if value != "" {
+				field, match, pattern = f, m, value
+				given = append(given, "--"+f+"-"+m)
+			}
+		}
+	}
+	if len(given) != 1 {
+		return postCondition{}, fmt.Errorf("give one condition, like --title-contains <text> or --author-regex <re> (got %d)", len(given))
+	}
+
+	var feedID uuid.NullUUID
+	if url, _ := cmd.flag("feed").(string); url != "" {
+		feed, err := s.db.GetFeedByUrl(context.Background(), url)
+		if err != nil {
+			return postCondition{}, fmt.Errorf("the feed does not exist. %v", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	return newPostCondition(feedID, field, match, pattern)
+}
+
+// postCondition is what a filter or alert rule looks for in a post.
+type postCondition struct {
+	feedID  uuid.NullUUID
+	field   string
+	match   string
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newPostCondition(feedID uuid.NullUUID, field, match, pattern string) (postCondition, error) {
+	c := postCondition{feedID: feedID, field: field, match: match, pattern: pattern}
+	if match == "regex" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return c, fmt.Errorf("bad regular expression. %v", err)
+		}
+		c.re = re
+	}
+
+	return c, nil
+}
+
+func (c postCondition) matches(post database.Post) bool {
+	if c.feedID.Valid && (!post.FeedID.Valid || post.FeedID.UUID != c.feedID.UUID) {
+		return false
+	}
+
+	var text string
+	switch c.field {
+	case "title":
+		text = post.Title
+	case "description":
+		text = descriptionText(post)
+	case "author":
+		text = post.Author.String
+	}
+	if c.re != nil {
+		return c.re.MatchString(text)
+	}
+
+	return strings.Contains(strings.ToLower(text), strings.ToLower(c.pattern))
+}
+
+// descriptionText returns the plain text of the description of post. Posts
+// saved before description_text existed don't have it, so it's rendered here.
+func descriptionText(post database.Post) string {
+	if post.DescriptionText.Valid {
+		return post.DescriptionText.String
+	}
+
+	return sanitize.Text(post.Description.String)
+}
+
+// describe says what the condition looks for, like `title contains "go" in Go blog`.
+func (c postCondition) describe(feeds map[uuid.UUID]string) string {
+	desc := fmt.Sprintf("%s %s %q", c.field, c.match, c.pattern)
+	if c.feedID.Valid {
+		desc += fmt.Sprintf(" in %s", feeds[c.feedID.UUID])
+	}
+
+	return desc
+}
+
+// filterRule is a rule ready to match posts.
+type filterRule struct {
+	database.FilterRule
+	postCondition
+}
+
+func compileFilterRule(rule database.FilterRule) (filterRule, error) {
+	c, err := newPostCondition(rule.FeedID, rule.Field, rule.Match, rule.Pattern)
+	if err != nil {
+		return filterRule{}, fmt.Errorf("filter rule %s: %v", rule.ID, err)
+	}
+
+	return filterRule{FilterRule: rule, postCondition: c}, nil
+}
+
+// describeFilterRule says what a rule does, like `title contains "sponsored": hide`.
+func describeFilterRule(rule database.FilterRule, feeds map[uuid.UUID]string) string {
+	c := postCondition{feedID: rule.FeedID, field: rule.Field, match: rule.Match, pattern: rule.Pattern}
+	desc := c.describe(feeds) + ": " + rule.Action
+	if rule.Tag.Valid {
+		desc += " " + rule.Tag.String
+	}
+
+	return desc
+}
+
+// postView is a post as a user sees it after their filter rules.
+type postView struct {
+	database.Post
+	Read      bool
+	Highlight bool
+	Tags      []string
+}
+
+// filterPosts applies the rules of user to posts: hidden posts are left
+// out and the ones a rule marks as read are saved as read.
+func filterPosts(s *state, user database.User, posts []database.Post) ([]postView, error) {
+	ctx := context.Background()
+	rules, err := s.db.GetFilterRulesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("getting filter rules. %v", err)
+	}
+	var compiled []filterRule
+	for _, rule := range rules {
+		r, err := compileFilterRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, r)
+	}
+
+	reads, err := s.db.GetPostReadsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("getting read posts. %v", err)
+	}
+	read := map[uuid.UUID]bool{}
+	for _, r := range reads {
+		read[r.PostID] = true
+	}
+
+	views := []postView{}
+	for _, post := range posts {
+		view := postView{Post: post, Read: read[post.ID]}
+		hidden := false
+		for _, rule := range compiled {
+			if !rule.matches(post) {
+				continue
+			}
+			switch rule.Action {
+			case actionHide:
+				hidden = true
+			case actionMarkRead:
+				if !view.Read {
+					err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID, ReadAt: time.Now()})
+					if err != nil {
+						return nil, fmt.Errorf("marking post as read. %v", err)
+					}
+					view.Read = true
+				}
+			case actionHighlight:
+				view.Highlight = true
+			case actionTag:
+				if !slices.Contains(view.Tags, rule.Tag.String) {
+					view.Tags = append(view.Tags, rule.Tag.String)
+				}
+			}
+		}
+		if !hidden {
+			views = append(views, view)
+		}
+	}
+
+	return views, nil
+}
+
+// postList is the list of posts browse and saved print.
+func postList(views []postView) *output.List {
+	list := output.NewList("id", "title", "url", "feed_id", "author", "published_at", "description", "read", "highlight", "tags")
+	for _, post := range views {
+		list.Add(post.ID, post.Title, post.Url, post.FeedID, post.Author, post.PublishedAt, post.DescriptionText,
+			post.Read, post.Highlight, strings.Join(post.Tags, ","))
+	}
+
+	return list
+}
+
+// printPostTitles prints a line per post, highlighted ones with ! instead of *.
+func printPostTitles(views []postView) {
+	for _, post := range views {
+		mark := "*"
+		if post.Highlight {
+			mark = "!"
+		}
+		// Titles come from the feeds, control characters included
+		line := fmt.Sprintf("%s %s", mark, sanitize.Line(post.Title))
+		if len(post.Tags) > 0 {
+			line += fmt.Sprintf(" [%s]", strings.Join(post.Tags, ", "))
+		}
+		if post.Read {
+			line += " (read)"
+		}
+		fmt.Println(line)
+	}
+}
+
+// filter add <condition> --action hide|mark-read|highlight|tag [--tag <name>] [--feed <url>]
+func handlerFilterAdd(s *state, cmd command, user database.User) error {
+	condition, err := postConditionFromFlags(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	action, _ := cmd.flag("action").(string)
+	if !slices.Contains(filterActions, action) {
+		return fmt.Errorf("--action has to be one of %s", strings.Join(filterActions, ", "))
+	}
+	tagName, _ := cmd.flag("tag").(string)
+	tag := sql.NullString{String: tagName, Valid: tagName != ""}
+	if action == actionTag && !tag.Valid {
+		return errors.New("--action tag needs --tag <name>")
+	}
+	if action != actionTag && tag.Valid {
+		return errors.New("--tag only goes with --action tag")
+	}
+
+	rule, err := s.db.CreateFilterRule(context.Background(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    condition.feedID,
+		Field:     condition.field,
+		Match:     condition.match,
+		Pattern:   condition.pattern,
+		Action:    action,
+		Tag:       tag,
+	})
+	if err != nil {
+		return fmt.Errorf("creating filter rule. %v", err)
+	}
+
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added filter %s: %s\n", rule.ID, describeFilterRule(rule, feeds))
+	return nil
+}
+
+// feedNames maps the id of every feed to its name.
+func feedNames(s *state) (map[uuid.UUID]string, error) {
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("getting feed list. %v", err)
+	}
+
+	names := map[uuid.UUID]string{}
+	for _, feed := range feeds {
+		names[feed.ID] = feed.Name
+	}
+
+	return names, nil
+}
+
+func handlerFilterList(s *state, cmd command, user database.User) error {
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("getting filter rules. %v", err)
+	}
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+
+	list := output.NewList("id", "field", "match", "pattern", "feed", "action", "tag", "created_at")
+	for _, rule := range rules {
+		feed := ""
+		if rule.FeedID.Valid {
+			feed = feeds[rule.FeedID.UUID]
+		}
+		list.Add(rule.ID, rule.Field, rule.Match, rule.Pattern, feed, rule.Action, rule.Tag, rule.CreatedAt)
+	}
+
+	return printList(cmd, list, func() {
+		fmt.Printf("%d filter rules.\n", len(rules))
+		for _, rule := range rules {
+			fmt.Printf("* %s %s\n", rule.ID, describeFilterRule(rule, feeds))
+		}
+	})
+}
+
+// filter remove <id>
+func handlerFilterRemove(s *state, cmd command, user database.User) error {
+	id, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("%q is not a filter id. `gator filter list` lists them", cmd.args[0])
+	}
+
+	n, err := s.db.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("removing filter rule. %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you have no filter rule %s", id)
+	}
+
+	fmt.Printf("Removed filter %s.\n", id)
+	return nil
+}
+
+// filter test [id] [<condition>] [--feed <url>]
+// Lists the posts of the feeds the user follows that a saved rule, or the
+// condition given, matches. Nothing is hidden or marked.
+func handlerFilterTest(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	var condition postCondition
+	if len(cmd.args) > 0 {
+		rules, err := s.db.GetFilterRulesForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("getting filter rules. %v", err)
+		}
+		i := slices.IndexFunc(rules, func(r database.FilterRule) bool { return r.ID.String() == cmd.args[0] })
+		if i < 0 {
+			return fmt.Errorf("you have no filter rule %s", cmd.args[0])
+		}
+		rule, err := compileFilterRule(rules[i])
+		if err != nil {
+			return err
+		}
+		condition = rule.postCondition
+	} else {
+		var err error
+		condition, err = postConditionFromFlags(s, cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	posts, err := s.db.GetLimitedPostsForUser(ctx, database.GetLimitedPostsForUserParams{UserID: user.ID, Limit: math.MaxInt32})
+	if err != nil {
+		return fmt.Errorf("getting posts for [%s] -- %v", user.Name, err)
+	}
+	var matched []database.Post
+	for _, post := range posts {
+		if condition.matches(post) {
+			matched = append(matched, post)
+		}
+	}
+
+	list := output.NewList("id", "title", "url", "feed_id", "author", "published_at")
+	for _, post := range matched {
+		list.Add(post.ID, post.Title, post.Url, post.FeedID, post.Author, post.PublishedAt)
+	}
+
+	return printList(cmd, list, func() {
+		fmt.Printf("%d of %d posts match.\n", len(matched), len(posts))
+		for _, post := range matched {
+			fmt.Printf("* %s\n", post.Title)
+		}
+	})
+}
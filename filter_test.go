@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+func TestFilters(t *testing.T) {
+	forEachBackend(t, testFilters)
+}
+
+func testFilters(t *testing.T, s *state) {
+	ctx := context.Background()
+	register(t, s, "alice")
+	urls := []string{"https://a.example/feed.xml", "https://b.example/feed.xml"}
+	mustRun(t, s, "addfeed", "A", urls[0])
+	mustRun(t, s, "addfeed", "B", urls[1])
+
+	titles := []string{"Sponsored: buy this", "Go 1.30 is out", "Weekly links", "Go tips"}
+	for i, title := range titles {
+		feed, _ := s.db.GetFeedByUrl(ctx, urls[i%2])
+		var author, description sql.NullString
+		if i == 2 {
+			author = sql.NullString{String: "bot", Valid: true}
+		}
+		// Saved before description_text existed
+		if i == 1 {
+			description = sql.NullString{String: "<p>Release <b>notes</b></p>", Valid: true}
+		}
+		_, err := s.db.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(),
+			Title:       title,
+			Url:         feed.Url + "/" + title,
+			Author:      author,
+			Description: description,
+			PublishedAt: sql.NullTime{Time: time.Now().Add(-time.Duration(i) * time.Hour), Valid: true},
+			FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+	}
+
+	if _, err := runCommand(t, s, "filter", "add", "--action", "hide"); err == nil || !strings.Contains(err.Error(), "give one condition") {
+		t.Errorf("filter add without a condition: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "filter", "add", "--title-regex", "(", "--action", "hide"); err == nil || !strings.Contains(err.Error(), "bad regular expression") {
+		t.Errorf("filter add with a bad regex: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "filter", "add", "--title-contains", "x", "--action", "tag"); err == nil || !strings.Contains(err.Error(), "needs --tag") {
+		t.Errorf("filter add --action tag without --tag: err = %v", err)
+	}
+
+	// Testing doesn't hide anything
+	out := mustRun(t, s, "filter", "test", "--title-contains", "SPONSORED")
+	if !strings.Contains(out, "1 of 4 posts match.") || !strings.Contains(out, "* Sponsored: buy this") {
+		t.Errorf("filter test = %q", out)
+	}
+
+	out = mustRun(t, s, "filter", "test", "--description-contains", "release notes")
+	if !strings.Contains(out, "1 of 4 posts match.") || !strings.Contains(out, "* Go 1.30 is out") {
+		t.Errorf("filter test on a post without description_text = %q", out)
+	}
+
+	mustRun(t, s, "filter", "add", "--title-contains", "sponsored", "--action", "hide")
+	mustRun(t, s, "filter", "add", "--author-regex", "^bot$", "--action", "mark-read")
+	mustRun(t, s, "filter", "add", "--title-regex", `^Go\b`, "--action", "tag", "--tag", "go")
+	mustRun(t, s, "filter", "add", "--title-contains", "tips", "--feed", urls[1], "--action", "highlight")
+
+	// Oldest first, hidden posts don't use up the limit
+	out = mustRun(t, s, "browse", "3")
+	want := "3 new posts.\n! Go tips [go]\n* Weekly links (read)\n* Go 1.30 is out [go]\n"
+	if !strings.HasSuffix(out, want) {
+		t.Errorf("browse = %q, want %q", out, want)
+	}
+	if out := mustRun(t, s, "browse", "--output", "json", "--fields", "title,tags", "1"); !strings.Contains(out, `"tags": "go"`) {
+		t.Errorf("browse --output json = %q", out)
+	}
+
+	// Each user has their own
+	register(t, s, "bob")
+	mustRun(t, s, "follow", urls[0])
+	if out := mustRun(t, s, "browse", "2"); !strings.Contains(out, "* Weekly links\n* Sponsored: buy this") {
+		t.Errorf("browse as bob = %q", out)
+	}
+	if out := mustRun(t, s, "filter", "list"); !strings.Contains(out, "0 filter rules.") {
+		t.Errorf("filter list as bob = %q", out)
+	}
+
+	login(t, s, "alice")
+	alice, _ := s.db.GetUser(ctx, "alice")
+	rules, _ := s.db.GetFilterRulesForUser(ctx, alice.ID)
+	if len(rules) != 4 {
+		t.Fatalf("alice has %d rules", len(rules))
+	}
+	out = mustRun(t, s, "filter", "list")
+	if !strings.Contains(out, "4 filter rules.") || !strings.Contains(out, `title contains "tips" in B: highlight`) {
+		t.Errorf("filter list = %q", out)
+	}
+	if out := mustRun(t, s, "filter", "test", rules[2].ID.String()); !strings.Contains(out, "2 of 4 posts match.") {
+		t.Errorf("filter test <id> = %q", out)
+	}
+
+	login(t, s, "bob")
+	if _, err := runCommand(t, s, "filter", "remove", rules[0].ID.String()); err == nil || !strings.Contains(err.Error(), "no filter rule") {
+		t.Errorf("filter remove of alice's rule as bob: err = %v", err)
+	}
+	login(t, s, "alice")
+	mustRun(t, s, "filter", "remove", rules[0].ID.String())
+	if out := mustRun(t, s, "browse", "4"); !strings.Contains(out, "* Sponsored: buy this") {
+		t.Errorf("browse after filter remove = %q", out)
+	}
+}
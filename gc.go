@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/neixir/gator/internal/archive"
+	"github.com/neixir/gator/internal/database"
+)
+
+// How long gc waits after the last follower leaves
+const defaultGCGrace = 30 * 24 * time.Hour
+
+// updateOrphanedFeeds notes when feeds lose their last follower, and
+// forgets it for the ones followed again. Run it after the follows change.
+func updateOrphanedFeeds(s *state) error {
+	ctx := context.Background()
+	err := s.db.ClearOrphanedFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("updating orphaned feeds. %v", err)
+	}
+
+	_, err = s.db.MarkOrphanedFeeds(ctx, sql.NullTime{Time: time.Now(), Valid: true})
+	if err != nil {
+		return fmt.Errorf("updating orphaned feeds. %v", err)
+	}
+
+	return nil
+}
+
+// gc [--grace 720h] [--archive-dir <dir>] [--dry-run] [--yes]
+// Deletes the feeds nobody has followed for longer than the grace period,
+// with their posts. agg already skips them, this frees the space. Posts
+// somebody saved go too, so then it needs --yes.
+func handlerGC(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	grace, _ := cmd.flag("grace").(time.Duration)
+	archiveDir, _ := cmd.flag("archive-dir").(string)
+	dryRun, _ := cmd.flag("dry-run").(bool)
+	yes, _ := cmd.flag("yes").(bool)
+	if grace < 0 {
+		return fmt.Errorf("the grace period can't be negative")
+	}
+
+	err := updateOrphanedFeeds(s)
+	if err != nil {
+		return err
+	}
+
+	feeds, err := s.db.GetOrphanedFeeds(ctx, sql.NullTime{Time: time.Now().Add(-grace), Valid: true})
+	if err != nil {
+		return fmt.Errorf("getting orphaned feeds. %v", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Printf("No feeds without followers for longer than %s.\n", grace)
+		return nil
+	}
+
+	archived := []archive.Feed{}
+	savedPerFeed := []int{}
+	var saved int
+	for _, feed := range feeds {
+		posts, err := s.db.GetPostsForFeed(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("getting posts of %s. %v", feed.Name, err)
+		}
+		archived = append(archived, archive.NewFeed(feed, posts))
+		// The posts nobody saved
+		unsaved, err := s.db.GetPrunablePostsForFeed(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("getting posts of %s. %v", feed.Name, err)
+		}
+		savedPerFeed = append(savedPerFeed, len(posts)-len(unsaved))
+		saved += len(posts) - len(unsaved)
+	}
+
+	// Saved posts go with their feed, only on purpose
+	stop := dryRun || (saved > 0 && !yes)
+	for i, feed := range feeds {
+		verb := "Deleting"
+		if stop {
+			verb = "Would delete"
+		}
+		note := ""
+		if savedPerFeed[i] > 0 {
+			note = fmt.Sprintf(", %d of them saved", savedPerFeed[i])
+		}
+		fmt.Printf("* %s %q, %s: no followers since %s, %d posts%s.\n",
+			verb, feed.Name, feed.Url, feed.OrphanedAt.Time.Format(time.DateTime), len(archived[i].Posts), note)
+	}
+	if dryRun {
+		return nil
+	}
+	if stop {
+		return fmt.Errorf("%d saved post(s) would go with the feeds. Pass --yes to delete them anyway", saved)
+	}
+
+	// The copy first, nothing is deleted if it fails
+	if archiveDir != "" {
+		path, err := archive.Write(archiveDir, "feeds", archived)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Archived %d feed(s) to %s.\n", len(archived), path)
+	}
+
+	for _, feed := range feeds {
+		err = s.db.DeleteFeed(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("deleting %s. %v", feed.Name, err)
+		}
+	}
+
+	fmt.Printf("Deleted %d feed(s).\n", len(feeds))
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package archive keeps a copy of the feeds and posts gator deletes, as
+// gzipped JSON files that don't need gator, or a database, to be read.
+package archive
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// Feed is an archived feed with its posts.
+type Feed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	UserID        uuid.UUID  `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	OrphanedAt    *time.Time `json:"orphaned_at,omitempty"`
+	Posts         []Post     `json:"posts"`
+}
+
+// Post is an archived post.
+type Post struct {
+	ID              uuid.UUID  `json:"id"`
+	FeedID          *uuid.UUID `json:"feed_id,omitempty"`
+	Title           string     `json:"title"`
+	Url             string     `json:"url"`
+	Author          string     `json:"author,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	DescriptionText string     `json:"description_text,omitempty"`
+	Content         string     `json:"content,omitempty"`
+	PublishedAt     *time.Time `json:"published_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// NewFeed converts a feed and its posts.
+func NewFeed(feed database.Feed, posts []database.Post) Feed {
+	archived := Feed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		UserID:        feed.UserID,
+		CreatedAt:     feed.CreatedAt,
+		LastFetchedAt: timePtr(feed.LastFetchedAt),
+		OrphanedAt:    timePtr(feed.OrphanedAt),
+		Posts:         []Post{},
+	}
+	for _, post := range posts {
+		archived.Posts = append(archived.Posts, NewPost(post))
+	}
+
+	return archived
+}
+
+// NewPost converts a post.
+func NewPost(post database.Post) Post {
+	archived := Post{
+		ID:              post.ID,
+		Title:           post.Title,
+		Url:             post.Url,
+		Author:          post.Author.String,
+		Description:     post.Description.String,
+		DescriptionText: post.DescriptionText.String,
+		Content:         post.Content.String,
+		PublishedAt:     timePtr(post.PublishedAt),
+		CreatedAt:       post.CreatedAt,
+	}
+	if post.FeedID.Valid {
+		archived.FeedID = &post.FeedID.UUID
+	}
+
+	return archived
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+// Write saves v as gzipped JSON to a new file in dir, named after kind and
+// the current time, and returns its path. The file is only readable by the
+// owner, like the backups.
+func Write(dir, kind string, v any) (string, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return "", fmt.Errorf("creating archive directory: %v", err)
+	}
+
+	name := kind + "-" + time.Now().Format("20060102-150405")
+	path := filepath.Join(dir, name+".json.gz")
+	var f *os.File
+	for i := 2; ; i++ {
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.json.gz", name, i))
+	}
+	if err != nil {
+		return "", fmt.Errorf("creating archive: %v", err)
+	}
+
+	err = encode(f, v)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("writing archive %s: %v", path, err)
+	}
+
+	return path, nil
+}
+
+func encode(f *os.File, v any) error {
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// Read loads a file written by Write into v.
+func Read(path string, v any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening archive: %v", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("reading archive %s: %v", path, err)
+	}
+	defer zr.Close()
+
+	err = json.NewDecoder(zr).Decode(v)
+	if err != nil {
+		return fmt.Errorf("reading archive %s: %v", path, err)
+	}
+
+	return nil
+}
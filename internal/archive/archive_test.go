@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+func TestWriteRead(t *testing.T) {
+	created := time.Date(2025, 6, 25, 12, 30, 0, 0, time.UTC)
+	feed := database.Feed{
+		ID:         uuid.New(),
+		Name:       "Blog",
+		Url:        "https://example.com/rss",
+		UserID:     uuid.New(),
+		CreatedAt:  created,
+		OrphanedAt: sql.NullTime{Time: created.Add(time.Hour), Valid: true},
+	}
+	posts := []database.Post{{
+		ID:          uuid.New(),
+		CreatedAt:   created,
+		Title:       "Hello",
+		Url:         "https://example.com/1",
+		Description: sql.NullString{String: "<p>Hi</p>", Valid: true},
+		PublishedAt: sql.NullTime{Time: created, Valid: true},
+		FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+	}}
+	want := []Feed{NewFeed(feed, posts)}
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	path, err := Write(dir, "feeds", want)
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), "feeds-") || !strings.HasSuffix(path, ".json.gz") {
+		t.Errorf("archive path = %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("archive mode = %v, %v, want 0600", info, err)
+	}
+
+	var got []Feed
+	if err := Read(path, &got); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %+v, want %+v", got, want)
+	}
+	if got[0].LastFetchedAt != nil || got[0].Posts[0].Author != "" {
+		t.Errorf("NULLs were not kept: %+v", got[0])
+	}
+
+	// Never overwrites an earlier archive
+	again, err := Write(dir, "feeds", want)
+	if err != nil || again == path {
+		t.Errorf("second Write = %s, %v, want a new file", again, err)
+	}
+}
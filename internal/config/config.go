@@ -2,22 +2,68 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/neixir/gator/internal/secret"
 )
 
 const configFileName = ".gatorconfig.json"
 
+// ErrNotFound is returned by Read when there is no config file yet.
+var ErrNotFound = errors.New("config file not found")
+
+// ErrUnknownProfile is returned by Read when the active profile isn't in the
+// file. The config comes with it, using the settings at the top level.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Environment variable that overrides CredentialsKey.
+const credentialsKeyEnv = "GATOR_CREDENTIALS_KEY"
+
+// SMTP is the server email alerts are sent through.
+type SMTP struct {
+	Host string `json:"host"`
+	// Defaults to 587.
+	Port int `json:"port,omitempty"`
+	// No authentication when empty.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// The sender address.
+	From string `json:"from"`
+}
+
 type Config struct {
-    DbUrl           string `json:"db_url"`
-    CurrentUserName string `json:"current_user_name"`
+	DbUrl           string `json:"db_url"`
+	CurrentUserName string `json:"current_user_name"`
+	// Base64 encoded 32 byte key used to encrypt feed credentials.
+	CredentialsKey string `json:"credentials_key,omitempty"`
+	// Token of the session of the current user, set by login.
+	SessionToken string `json:"session_token,omitempty"`
+
+	// Active profile, empty for the settings above.
+	Profile  string             `json:"profile,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Mail server for alerts, shared by every profile. gator never writes it.
+	SMTP *SMTP `json:"smtp,omitempty"`
+
+	// What Read found in the file, before applying the profile and the
+	// overrides. Writes start from it so overrides are never persisted.
+	file *Config
+	// Values that came from the environment or the command line, by field
+	overrides map[string]string
 }
 
 /*
 Export a Read function that reads the JSON file found at ~/.gatorconfig.json and returns a Config struct.
 It should read the file from the HOME directory, then decode the JSON string into a new Config struct.
 I used os.UserHomeDir to get the location of HOME.
+
+The file can also come from --config, GATOR_CONFIG or the XDG config directory (see getConfigFilePath).
+The active profile and GATOR_* environment variables are applied on top of it.
 */
 func Read() (Config, error) {
 	config := Config{}
@@ -27,51 +73,422 @@ func Read() (Config, error) {
 		return config, fmt.Errorf("Error getting HOME directory: %v", err)
 	}
 
-	data, err := os.ReadFile(configFile)
+	currentConfig, err := readFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, fmt.Errorf("%w: %s. Run `gator init` to create it", ErrNotFound, configFile)
+	}
 	if err != nil {
 		return config, err
 	}
 
-	var currentConfig Config
-	err = json.Unmarshal(data, &currentConfig)
-	if err != nil {
-		return config, fmt.Errorf("Error parsing JSON data: %v", err)
+	return currentConfig.resolve()
+}
+
+// resolve applies the active profile and the overrides to the config as read
+// from the file. Without the profile, the top level settings are used and
+// ErrUnknownProfile is returned with them.
+func (c Config) resolve() (Config, error) {
+	file := c.clone()
+	c.file = &file
+	c.overrides = map[string]string{}
+
+	// The profile can be overridden too, so it goes first
+	if name, ok := lookupOverride(fieldProfile); ok {
+		c.Profile = name
+		c.overrides[fieldProfile] = name
+	}
+	var err error
+	if c.Profile != "" {
+		profile, ok := c.Profiles[c.Profile]
+		if ok {
+			c.DbUrl = profile.DbUrl
+			c.CurrentUserName = profile.CurrentUserName
+			c.SessionToken = profile.SessionToken
+			if profile.CredentialsKey != "" {
+				c.CredentialsKey = profile.CredentialsKey
+			}
+		} else {
+			err = fmt.Errorf("%w %q (%s)", ErrUnknownProfile, c.Profile, strings.Join(c.ProfileNames(), ", "))
+		}
+	}
+
+	for _, field := range overridable {
+		if value, ok := lookupOverride(field.name); ok {
+			*field.get(&c) = value
+			c.overrides[field.name] = value
+		}
+	}
+
+	return c, err
+}
+
+// ValidationError lists everything wrong with a config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	path, _ := getConfigFilePath()
+	return fmt.Sprintf("invalid config file %s:\n  - %s", path, strings.Join(e.Problems, "\n  - "))
+}
+
+// Validate checks the fields gator needs to start, and the format of the optional ones.
+// It returns a *ValidationError.
+func (c *Config) Validate() error {
+	var problems []string
+
+	where := ""
+	if c.Profile != "" {
+		where = fmt.Sprintf(" in profile %q", c.Profile)
+	}
+
+	if c.DbUrl == "" {
+		problems = append(problems, fmt.Sprintf("db_url is missing%s (postgres://... or sqlite://...)", where))
+	} else if !strings.Contains(c.DbUrl, ":") {
+		problems = append(problems, fmt.Sprintf("db_url %q%s has no scheme (postgres://... or sqlite://...)", c.DbUrl, where))
+	}
+
+	if strings.TrimSpace(c.CurrentUserName) != c.CurrentUserName {
+		problems = append(problems, fmt.Sprintf("current_user_name %q%s has leading or trailing spaces", c.CurrentUserName, where))
+	}
+
+	if c.CredentialsKey != "" {
+		if _, err := secret.ParseKey(c.CredentialsKey); err != nil {
+			problems = append(problems, fmt.Sprintf("credentials_key%s: %v", where, err))
+		}
+	}
+
+	if c.SMTP != nil {
+		if c.SMTP.Host == "" {
+			problems = append(problems, "smtp.host is missing")
+		}
+		if c.SMTP.From == "" {
+			problems = append(problems, "smtp.from is missing")
+		}
+		if c.SMTP.Port < 0 || c.SMTP.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("smtp.port %d is not a port", c.SMTP.Port))
+		}
+	}
+
+	for name := range c.Profiles {
+		if !validProfileName(name) {
+			problems = append(problems, fmt.Sprintf("profile name %q can only use letters, digits, - and _", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
 	}
 
-	return currentConfig, nil
+	return nil
+}
+
+// Save writes the whole config to the config file.
+func (c *Config) Save() error {
+	return write(c)
+}
+
+// Path returns where the config file is.
+func Path() (string, error) {
+	return getConfigFilePath()
 }
 
 // Export a SetUser method on the Config struct that writes the config struct to the JSON file after setting the current_user_name field.
 func (c *Config) SetUser(name string) error {
 	c.CurrentUserName = name
-	err := write(*c)
+	err := write(c)
+	if err != nil {
+		return fmt.Errorf("saving current user: %v", err)
+	}
+
+	return nil
+}
+
+// SetSession writes the user logged in and the token of their session, both
+// empty after logging out.
+func (c *Config) SetSession(name, token string) error {
+	c.CurrentUserName = name
+	c.SessionToken = token
+	err := write(c)
+	if err != nil {
+		return fmt.Errorf("saving session: %v", err)
+	}
+
+	return nil
+}
 
-	return err
+// GetCredentialsKey returns the key used to encrypt feed credentials.
+// The GATOR_CREDENTIALS_KEY environment variable takes precedence over the config file.
+func (c *Config) GetCredentialsKey() string {
+	if key := os.Getenv(credentialsKeyEnv); key != "" {
+		return key
+	}
+
+	return c.CredentialsKey
+}
+
+// Set by --config
+var pathOverride string
+
+// SetPath makes Read and writes use path instead of the default location.
+func SetPath(path string) {
+	pathOverride = path
 }
 
 // I also wrote a few non-exported helper functions and added a constant to hold the filename.
 // But you can implement the internals of the package however you like.
+//
+// The file is, in order: --config, $GATOR_CONFIG, ~/.gatorconfig.json if it
+// exists, $XDG_CONFIG_HOME/gator/config.json (~/.config/gator/config.json) if
+// it exists. A new file goes in the XDG directory when XDG_CONFIG_HOME is
+// set, in ~/.gatorconfig.json otherwise.
 func getConfigFilePath() (string, error) {
-	configFile := ""
+	if pathOverride != "" {
+		return pathOverride, nil
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path, nil
+	}
 
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
-	} else {
-		configFile = filepath.Join(homeDir, configFileName)
+	}
+	configFile := filepath.Join(homeDir, configFileName)
+
+	xdgDir := os.Getenv("XDG_CONFIG_HOME")
+	xdgFile := filepath.Join(homeDir, ".config", "gator", "config.json")
+	if xdgDir != "" {
+		xdgFile = filepath.Join(xdgDir, "gator", "config.json")
+	}
+
+	if fileExists(configFile) {
+		return configFile, nil
+	}
+	if fileExists(xdgFile) || xdgDir != "" {
+		return xdgFile, nil
 	}
 
 	return configFile, nil
 }
 
-func write(cfg Config) error {
-	configFile, err := getConfigFilePath()
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// write saves c and remembers what it wrote, so later writes only change
+// what changed since.
+func write(c *Config) error {
+	written, err := save(c.file, c.toFile())
 	if err != nil {
 		return err
 	}
 
-	newConfig, _ := json.Marshal(cfg)
-	err = os.WriteFile(configFile, []byte(newConfig), 0644)
-	
+	c.file = &written
 	return nil
 }
+
+// save writes ours to the config file and returns what was written.
+//
+// The file is locked, then read again: only what differs between base (the
+// file as we read it) and ours is applied, so two gator processes writing
+// at the same time don't undo each other's changes. It is written to a
+// temporary file renamed over the old one, so it is never half written, and
+// it is only readable by its owner because it holds the database password.
+func save(base *Config, ours Config) (Config, error) {
+	configFile, err := getConfigFilePath()
+	if err != nil {
+		return Config{}, err
+	}
+
+	// The XDG directory may not exist yet
+	dir := filepath.Dir(configFile)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return Config{}, fmt.Errorf("creating %s: %v", dir, err)
+	}
+
+	lock, err := lockFile(configFile + ".lock")
+	if err != nil {
+		return Config{}, fmt.Errorf("locking %s: %v", configFile, err)
+	}
+	defer lock.Close()
+
+	file := ours
+	if base != nil {
+		current, err := readFile(configFile)
+		if err == nil {
+			file = merge(current, *base, ours)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return Config{}, err
+		}
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return Config{}, fmt.Errorf("encoding config: %v", err)
+	}
+
+	err = writeAtomic(configFile, append(data, '\n'))
+	if err != nil {
+		return Config{}, fmt.Errorf("writing %s: %v", configFile, err)
+	}
+
+	return file, nil
+}
+
+// writeAtomic replaces path with data, mode 0600.
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Does nothing once renamed
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already uses 0600, but not on every platform
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// readFile decodes the config file as it is, without profiles or overrides applied.
+func readFile(path string) (Config, error) {
+	var file Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return file, fmt.Errorf("Error parsing JSON data in %s: %v", path, err)
+	}
+
+	return file, nil
+}
+
+// merge applies the changes from base to ours on top of current.
+func merge(current, base, ours Config) Config {
+	pick := func(current, base, ours string) string {
+		if ours != base {
+			return ours
+		}
+		return current
+	}
+
+	result := current.clone()
+	result.DbUrl = pick(current.DbUrl, base.DbUrl, ours.DbUrl)
+	result.CurrentUserName = pick(current.CurrentUserName, base.CurrentUserName, ours.CurrentUserName)
+	result.CredentialsKey = pick(current.CredentialsKey, base.CredentialsKey, ours.CredentialsKey)
+	result.SessionToken = pick(current.SessionToken, base.SessionToken, ours.SessionToken)
+	result.Profile = pick(current.Profile, base.Profile, ours.Profile)
+
+	for name, profile := range ours.Profiles {
+		if result.Profiles == nil {
+			result.Profiles = map[string]Profile{}
+		}
+
+		baseProfile, ok := base.Profiles[name]
+		if !ok {
+			result.Profiles[name] = profile
+			continue
+		}
+		currentProfile, ok := current.Profiles[name]
+		if !ok {
+			currentProfile = baseProfile
+		}
+		result.Profiles[name] = Profile{
+			DbUrl:           pick(currentProfile.DbUrl, baseProfile.DbUrl, profile.DbUrl),
+			CurrentUserName: pick(currentProfile.CurrentUserName, baseProfile.CurrentUserName, profile.CurrentUserName),
+			CredentialsKey:  pick(currentProfile.CredentialsKey, baseProfile.CredentialsKey, profile.CredentialsKey),
+			SessionToken:    pick(currentProfile.SessionToken, baseProfile.SessionToken, profile.SessionToken),
+		}
+	}
+
+	return result
+}
+
+// toFile returns what has to be written: the settings go to the active
+// profile, and overridden values keep what the file had.
+func (c Config) toFile() Config {
+	file := Config{}
+	if c.file != nil {
+		file = c.file.clone()
+	}
+
+	kept := func(field string, value, inFile string) string {
+		if override, ok := c.overrides[field]; ok && override == value {
+			return inFile
+		}
+		return value
+	}
+
+	file.Profile = kept(fieldProfile, c.Profile, file.Profile)
+	file.Profiles = c.clone().Profiles
+
+	if c.Profile == "" {
+		file.DbUrl = kept(fieldDbUrl, c.DbUrl, file.DbUrl)
+		file.CurrentUserName = kept(fieldCurrentUserName, c.CurrentUserName, file.CurrentUserName)
+		file.CredentialsKey = kept(fieldCredentialsKey, c.CredentialsKey, file.CredentialsKey)
+		file.SessionToken = kept(fieldSessionToken, c.SessionToken, file.SessionToken)
+		return file
+	}
+
+	profile := file.Profiles[c.Profile]
+	profile.DbUrl = kept(fieldDbUrl, c.DbUrl, profile.DbUrl)
+	profile.CurrentUserName = kept(fieldCurrentUserName, c.CurrentUserName, profile.CurrentUserName)
+	profile.SessionToken = kept(fieldSessionToken, c.SessionToken, profile.SessionToken)
+	// An inherited key stays at the top level
+	if key := kept(fieldCredentialsKey, c.CredentialsKey, profile.CredentialsKey); key != file.CredentialsKey {
+		profile.CredentialsKey = key
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]Profile{}
+	}
+	file.Profiles[c.Profile] = profile
+
+	return file
+}
+
+func (c Config) clone() Config {
+	copied := Config{
+		DbUrl:           c.DbUrl,
+		CurrentUserName: c.CurrentUserName,
+		CredentialsKey:  c.CredentialsKey,
+		SessionToken:    c.SessionToken,
+		Profile:         c.Profile,
+	}
+	if c.SMTP != nil {
+		smtp := *c.SMTP
+		copied.SMTP = &smtp
+	}
+	if c.Profiles != nil {
+		copied.Profiles = make(map[string]Profile, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			copied.Profiles[name] = profile
+		}
+	}
+
+	return copied
+}
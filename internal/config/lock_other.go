@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package config
+
+import "os"
+
+// lockFile only creates the lock file, there is no file locking on this platform.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+}
+
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Profile is a named set of settings, e.g. "work" and "personal", each with
+// its own database and current user. An empty credentials_key falls back to
+// the one at the top level of the file.
+type Profile struct {
+	DbUrl           string `json:"db_url"`
+	CurrentUserName string `json:"current_user_name"`
+	CredentialsKey  string `json:"credentials_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+}
+
+// Environment variable that overrides the config file location.
+const configPathEnv = "GATOR_CONFIG"
+
+// Fields that can be overridden, by JSON name
+const (
+	fieldDbUrl           = "db_url"
+	fieldCurrentUserName = "current_user_name"
+	fieldCredentialsKey  = "credentials_key"
+	fieldSessionToken    = "session_token"
+	fieldProfile         = "profile"
+)
+
+// overridable are the settings GATOR_<FIELD> overrides, e.g. GATOR_DB_URL.
+var overridable = []struct {
+	name string
+	get  func(c *Config) *string
+}{
+	{fieldDbUrl, func(c *Config) *string { return &c.DbUrl }},
+	{fieldCurrentUserName, func(c *Config) *string { return &c.CurrentUserName }},
+	{fieldCredentialsKey, func(c *Config) *string { return &c.CredentialsKey }},
+	{fieldSessionToken, func(c *Config) *string { return &c.SessionToken }},
+}
+
+// Set by --profile
+var profileOverride string
+
+// SetProfile makes Read use the named profile instead of the one in the file.
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// EnvName is the environment variable overriding a field, GATOR_ and its name in upper case.
+func EnvName(field string) string {
+	name := []byte("GATOR_" + field)
+	for i, c := range name {
+		if 'a' <= c && c <= 'z' {
+			name[i] = c - 'a' + 'A'
+		}
+	}
+
+	return string(name)
+}
+
+// lookupOverride returns the value set for field by the command line or the environment.
+func lookupOverride(field string) (string, bool) {
+	if field == fieldProfile && profileOverride != "" {
+		return profileOverride, true
+	}
+
+	value := os.Getenv(EnvName(field))
+	return value, value != ""
+}
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validProfileName(name string) bool {
+	return profileNameRe.MatchString(name)
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// AddProfile creates a profile using the database in dbURL and saves the config.
+func (c *Config) AddProfile(name, dbURL string) error {
+	if !validProfileName(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, - and _", name)
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = Profile{DbUrl: dbURL}
+
+	return write(c)
+}
+
+// UseProfile makes name the active profile and saves the config. An empty
+// name goes back to the settings at the top level of the file.
+func (c *Config) UseProfile(name string) error {
+	if name != "" {
+		if _, ok := c.Profiles[name]; !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+
+	file := c.toFile()
+	// A missing active profile isn't created on the way out of it
+	if _, ok := c.Profiles[c.Profile]; !ok {
+		delete(file.Profiles, c.Profile)
+	}
+	file.Profile = name
+	written, err := save(c.file, file)
+	if err != nil {
+		return err
+	}
+
+	used, err := written.resolve()
+	if err != nil {
+		return err
+	}
+
+	*c = used
+	return nil
+}
@@ -0,0 +1,68 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/dbtest"
+	"github.com/neixir/gator/internal/storage"
+	"github.com/neixir/gator/internal/storage/storagetest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m))
+}
+
+func TestMigrationsUpAndDown(t *testing.T) {
+	db, _ := dbtest.Empty(t)
+	provider := dbtest.Migrations(t, db)
+	ctx := context.Background()
+
+	sources := provider.ListSources()
+	latest := sources[len(sources)-1].Version
+
+	results, err := provider.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(results) != len(sources) {
+		t.Errorf("applied %d migrations, want %d", len(results), len(sources))
+	}
+
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		t.Fatalf("version: %v", err)
+	}
+	if version != latest {
+		t.Errorf("version = %d, want %d", version, latest)
+	}
+
+	// Every Down has to undo its Up
+	_, err = provider.DownTo(ctx, 0)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+
+	var tables int
+	err = db.QueryRow(`SELECT count(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name <> 'goose_db_version'`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("counting tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables left after migrating down", tables)
+	}
+
+	// And going up again works on the result
+	_, err = provider.Up(ctx)
+	if err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return database.New(dbtest.Postgres(t))
+	})
+}
@@ -13,6 +13,19 @@ import (
 	"github.com/google/uuid"
 )
 
+const clearOrphanedFeeds = `-- name: ClearOrphanedFeeds :exec
+UPDATE feeds
+SET orphaned_at = NULL
+WHERE orphaned_at IS NOT NULL
+AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+// Somebody followed them again.
+func (q *Queries) ClearOrphanedFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, clearOrphanedFeeds)
+	return err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +36,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability
 `
 
 type CreateFeedParams struct {
@@ -53,12 +66,26 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.OrphanedAt,
+		&i.KeepPosts,
+		&i.KeepDays,
+		&i.Readability,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability FROM feeds
 WHERE url=$1
 `
 
@@ -73,12 +100,16 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.OrphanedAt,
+		&i.KeepPosts,
+		&i.KeepDays,
+		&i.Readability,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -98,6 +129,10 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.OrphanedAt,
+			&i.KeepPosts,
+			&i.KeepDays,
+			&i.Readability,
 		); err != nil {
 			return nil, err
 		}
@@ -113,7 +148,8 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -124,6 +160,7 @@ LIMIT 1
 // A simple approach is to keep track of when a feed was last fetched,
 // and always fetch the oldest one first (or any that haven't ever been fetched).
 // SQL has a NULLS FIRST clause that can help with this.
+// Feeds nobody follows are skipped, gc deletes them.
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
 	var i Feed
@@ -135,10 +172,56 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.OrphanedAt,
+		&i.KeepPosts,
+		&i.KeepDays,
+		&i.Readability,
 	)
 	return i, err
 }
 
+const getOrphanedFeeds = `-- name: GetOrphanedFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability FROM feeds
+WHERE orphaned_at < $1
+ORDER BY orphaned_at ASC
+`
+
+// Feeds without followers since before the given time.
+func (q *Queries) GetOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getOrphanedFeeds, orphanedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.OrphanedAt,
+			&i.KeepPosts,
+			&i.KeepDays,
+			&i.Readability,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = $2, updated_at = $2
@@ -156,3 +239,133 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
 	return err
 }
+
+const markOrphanedFeeds = `-- name: MarkOrphanedFeeds :execrows
+UPDATE feeds
+SET orphaned_at = $1
+WHERE orphaned_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+// Feeds that just lost their last follower.
+func (q *Queries) MarkOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markOrphanedFeeds, orphanedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1
+`
+
+type RenameFeedParams struct {
+	ID        uuid.UUID
+	Name      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.ID, arg.Name, arg.UpdatedAt)
+	return err
+}
+
+const setFeedReadability = `-- name: SetFeedReadability :exec
+UPDATE feeds
+SET readability = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedReadabilityParams struct {
+	ID          uuid.UUID
+	Readability bool
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) SetFeedReadability(ctx context.Context, arg SetFeedReadabilityParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedReadability, arg.ID, arg.Readability, arg.UpdatedAt)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET keep_posts = $2, keep_days = $3, updated_at = $4
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID        uuid.UUID
+	KeepPosts sql.NullInt32
+	KeepDays  sql.NullInt32
+	UpdatedAt time.Time
+}
+
+// NULL uses the global setting.
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention,
+		arg.ID,
+		arg.KeepPosts,
+		arg.KeepDays,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :exec
+UPDATE feeds
+SET url = $2, last_fetched_at = NULL, updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedUrlParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+// A new URL is fetched first.
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUrl, arg.ID, arg.Url, arg.UpdatedAt)
+	return err
+}
+
+const transferFeed = `-- name: TransferFeed :exec
+UPDATE feeds
+SET user_id = $2, updated_at = $3
+WHERE id = $1
+`
+
+type TransferFeedParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) TransferFeed(ctx context.Context, arg TransferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, transferFeed, arg.ID, arg.UserID, arg.UpdatedAt)
+	return err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE user_id = $3
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.UUID
+	UpdatedAt  time.Time
+	FromUserID uuid.UUID
+}
+
+// Gives every feed of one user to another.
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.UpdatedAt, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
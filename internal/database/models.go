@@ -11,6 +11,34 @@ import (
 	"github.com/google/uuid"
 )
 
+type AlertDelivery struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	RuleID    uuid.UUID
+	PostTitle string
+	PostUrl   string
+	Status    string
+	Attempts  int32
+	Error     sql.NullString
+}
+
+type AlertRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Match     string
+	Pattern   string
+	Kind      string
+	Target    string
+}
+
+type Digest struct {
+	UserID uuid.UUID
+	SentAt time.Time
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +47,18 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	OrphanedAt    sql.NullTime
+	KeepPosts     sql.NullInt32
+	KeepDays      sql.NullInt32
+	Readability   bool
+}
+
+type FeedCredential struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	AuthType  string
+	Secret    []byte
 }
 
 type FeedFollow struct {
@@ -29,20 +69,63 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Match     string
+	Pattern   string
+	Action    string
+	Tag       sql.NullString
+}
+
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.NullUUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.NullUUID
+	Author          sql.NullString
+	DescriptionText sql.NullString
+	Content         sql.NullString
 }
 
-type User struct {
-	ID        uuid.UUID
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type SavedPost struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Session struct {
+	TokenHash string
+	UserID    uuid.UUID
 	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Setting struct {
+	Key       string
+	Value     string
 	UpdatedAt time.Time
-	Name      string
+}
+
+type User struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	PasswordHash  sql.NullString
+	IsAdmin       bool
+	DeactivatedAt sql.NullTime
 }
@@ -14,7 +14,7 @@ import (
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text)
 VALUES (
     $1,
     $2,
@@ -23,20 +23,24 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text, content
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.NullUUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          uuid.NullUUID
+	Author          sql.NullString
+	DescriptionText sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -49,6 +53,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.DescriptionText,
 	)
 	var i Post
 	err := row.Scan(
@@ -60,12 +66,25 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.DescriptionText,
+		&i.Content,
 	)
 	return i, err
 }
 
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
 const getLimitedPostsForUser = `-- name: GetLimitedPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.description_text, posts.content
 FROM posts
 INNER JOIN feed_follows
 ON feed_follows.feed_id = posts.feed_id and feed_follows.user_id = $1
@@ -96,6 +115,59 @@ func (q *Queries) GetLimitedPostsForUser(ctx context.Context, arg GetLimitedPost
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.DescriptionText,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNewPostsForUser = `-- name: GetNewPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.description_text, posts.content
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.created_at > $2
+ORDER BY posts.published_at ASC
+`
+
+type GetNewPostsForUserParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+// The posts of followed feeds gator saved after a time, for digest.
+func (q *Queries) GetNewPostsForUser(ctx context.Context, arg GetNewPostsForUserParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getNewPostsForUser, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.DescriptionText,
+			&i.Content,
 		); err != nil {
 			return nil, err
 		}
@@ -109,3 +181,130 @@ func (q *Queries) GetLimitedPostsForUser(ctx context.Context, arg GetLimitedPost
 	}
 	return items, nil
 }
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text, content FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.DescriptionText,
+		&i.Content,
+	)
+	return i, err
+}
+
+const getPostsForFeed = `-- name: GetPostsForFeed :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text, content FROM posts
+WHERE feed_id = $1
+ORDER BY published_at ASC
+`
+
+// For archiving a feed before gc deletes it.
+func (q *Queries) GetPostsForFeed(ctx context.Context, feedID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.DescriptionText,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePostsForFeed = `-- name: GetPrunablePostsForFeed :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text, content FROM posts
+WHERE feed_id = $1
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+ORDER BY published_at DESC
+`
+
+// The posts prune may delete, newest first: nobody saved them.
+func (q *Queries) GetPrunablePostsForFeed(ctx context.Context, feedID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePostsForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.DescriptionText,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID        uuid.UUID
+	Content   sql.NullString
+	UpdatedAt time.Time
+}
+
+// The main content of the article, extracted by readability.
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content, arg.UpdatedAt)
+	return err
+}
@@ -0,0 +1,238 @@
+// Package dbtest gives integration tests a real, migrated Postgres database.
+//
+// The server comes from, in order:
+//   - GATOR_TEST_DB_URL, a connection string to an existing server. The role
+//     needs the CREATEDB privilege.
+//   - A throwaway cluster created with initdb and started with pg_ctl, when
+//     those are on the PATH (or in /usr/lib/postgresql/*/bin). It lives in a
+//     temporary directory and is stopped by Main.
+//
+// Without either, tests calling Postgres are skipped. Every test gets its
+// own database, dropped when the test ends.
+//
+// SQLite databases are always available, each test gets a file in its
+// temporary directory.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+
+	"github.com/neixir/gator/internal/migrate"
+	"github.com/neixir/gator/internal/storage"
+)
+
+// Environment variable pointing at an existing server.
+const DBURLEnv = "GATOR_TEST_DB_URL"
+
+var (
+	serverOnce sync.Once
+	serverURL  string
+	serverErr  error
+	// Set when we started our own cluster
+	clusterDir string
+	pgCtl      string
+)
+
+// Main runs the tests and stops the cluster started for them, if any.
+// Use it from TestMain: os.Exit(dbtest.Main(m)).
+func Main(m *testing.M) int {
+	code := m.Run()
+
+	if clusterDir != "" {
+		stop := exec.Command(pgCtl, "stop", "-D", filepath.Join(clusterDir, "data"), "-m", "immediate")
+		if out, err := stop.CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "dbtest: stopping postgres: %v\n%s", err, out)
+		}
+		os.RemoveAll(clusterDir)
+	}
+
+	return code
+}
+
+// Postgres returns a connection to a new database with all migrations applied.
+func Postgres(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, _ := Empty(t)
+	provider := Migrations(t, db)
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+
+	return db
+}
+
+// Empty returns a connection to a new database without any tables, and its URL.
+func Empty(t testing.TB) (*sql.DB, string) {
+	t.Helper()
+
+	serverOnce.Do(startServer)
+	if serverErr != nil {
+		t.Skipf("no Postgres available: %v", serverErr)
+	}
+
+	admin, err := sql.Open("postgres", serverURL)
+	if err != nil {
+		t.Fatalf("connecting to postgres: %v", err)
+	}
+	defer admin.Close()
+
+	name := "gator_test_" + randomSuffix()
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("creating database %s: %v", name, err)
+	}
+
+	dbURL := withDatabase(serverURL, name)
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		admin, err := sql.Open("postgres", serverURL)
+		if err != nil {
+			return
+		}
+		defer admin.Close()
+		if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)"); err != nil {
+			t.Logf("dropping database %s: %v", name, err)
+		}
+	})
+
+	return db, dbURL
+}
+
+// Migrations returns a goose provider for the embedded Postgres migrations.
+func Migrations(t testing.TB, db *sql.DB) *goose.Provider {
+	t.Helper()
+
+	provider, err := migrate.Provider(db, storage.Postgres)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+
+	return provider
+}
+
+func startServer() {
+	if dbURL := os.Getenv(DBURLEnv); dbURL != "" {
+		serverURL = dbURL
+		serverErr = ping(dbURL)
+		return
+	}
+
+	initdb, err := findBinary("initdb")
+	if err != nil {
+		serverErr = fmt.Errorf("set %s or install postgres (%v)", DBURLEnv, err)
+		return
+	}
+	pgCtl, err = findBinary("pg_ctl")
+	if err != nil {
+		serverErr = err
+		return
+	}
+
+	clusterDir, err = os.MkdirTemp("", "gator-pg-")
+	if err != nil {
+		serverErr = err
+		return
+	}
+	dataDir := filepath.Join(clusterDir, "data")
+
+	out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "--auth=trust", "--no-sync", "-E", "UTF8").CombinedOutput()
+	if err != nil {
+		serverErr = fmt.Errorf("initdb: %v\n%s", err, out)
+		return
+	}
+
+	port, err := freePort()
+	if err != nil {
+		serverErr = err
+		return
+	}
+
+	// Unix socket in our own directory, so we don't need /var/run/postgresql
+	opts := fmt.Sprintf("-p %d -k %s -c fsync=off -c listen_addresses=127.0.0.1", port, clusterDir)
+	start := exec.Command(pgCtl, "start", "-w", "-D", dataDir, "-l", filepath.Join(clusterDir, "log"), "-o", opts)
+	if out, err := start.CombinedOutput(); err != nil {
+		serverErr = fmt.Errorf("pg_ctl start: %v\n%s", err, out)
+		return
+	}
+
+	serverURL = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	serverErr = ping(serverURL)
+}
+
+func ping(dbURL string) error {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// findBinary looks in the PATH and then in the usual Debian location.
+func findBinary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+
+	matches, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql", "*", "bin", name))
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	// Newest version last
+	sort.Strings(matches)
+
+	return matches[len(matches)-1], nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func withDatabase(dbURL, name string) string {
+	u, err := url.Parse(dbURL)
+	if err != nil || !strings.HasPrefix(u.Scheme, "postgres") {
+		return dbURL
+	}
+	u.Path = "/" + name
+
+	return u.String()
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
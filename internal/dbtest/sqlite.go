@@ -0,0 +1,45 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/neixir/gator/internal/migrate"
+	"github.com/neixir/gator/internal/storage"
+	"github.com/neixir/gator/internal/storage/sqlite"
+)
+
+// SQLite returns a connection to a new database file with all migrations
+// applied. Unlike Postgres it never skips: the driver is pure Go.
+func SQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider := SQLiteMigrations(t, db)
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+
+	return db
+}
+
+// SQLiteMigrations returns a goose provider for the embedded SQLite migrations.
+func SQLiteMigrations(t testing.TB, db *sql.DB) *goose.Provider {
+	t.Helper()
+
+	provider, err := migrate.Provider(db, storage.SQLite)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+
+	return provider
+}
@@ -0,0 +1,153 @@
+// Package digest renders the summary of a user's new posts, grouped by
+// feed, as plain text and as HTML for email.
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// How long a post's summary can be, in characters
+const summaryLength = 280
+
+// Digest is the posts of one user between Since and Until.
+type Digest struct {
+	User  string
+	Since time.Time
+	Until time.Time
+	Feeds []Feed
+}
+
+// Feed is a feed with its new posts, oldest first.
+type Feed struct {
+	Name  string
+	URL   string
+	Posts []Post
+}
+
+// Post is a post in a digest.
+type Post struct {
+	Title       string
+	URL         string
+	Author      string
+	PublishedAt time.Time
+	// The description as plain text, shortened with Summarize.
+	Summary   string
+	Highlight bool
+	Tags      []string
+}
+
+// Count returns how many posts the digest has.
+func (d Digest) Count() int {
+	n := 0
+	for _, feed := range d.Feeds {
+		n += len(feed.Posts)
+	}
+
+	return n
+}
+
+// Subject is the subject of the email, like "gator digest: 5 new posts in 2 feeds".
+func (d Digest) Subject() string {
+	return fmt.Sprintf("gator digest: %s in %s", plural(d.Count(), "new post"), plural(len(d.Feeds), "feed"))
+}
+
+// Text renders the digest as plain text.
+func (d Digest) Text() (string, error) {
+	var buf bytes.Buffer
+	err := textTemplate.Execute(&buf, d)
+	if err != nil {
+		return "", fmt.Errorf("rendering digest: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// HTML renders the digest as an HTML document, the styles inline because
+// mail clients drop style sheets.
+func (d Digest) HTML() (string, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, d)
+	if err != nil {
+		return "", fmt.Errorf("rendering digest: %v", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Summarize shortens text to about summaryLength characters, cutting
+// between words.
+func Summarize(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= summaryLength {
+		return text
+	}
+
+	cut := string([]rune(text)[:summaryLength])
+	if i := strings.LastIndex(cut, " "); i > summaryLength/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+var funcs = map[string]any{
+	"date":   func(t time.Time) string { return t.Format("Mon 2 Jan 2006 15:04") },
+	"join":   strings.Join,
+	"plural": plural,
+}
+
+const textSource = `{{.Subject}}
+For {{.User}}, from {{date .Since}} to {{date .Until}}.
+{{range .Feeds}}
+== {{.Name}} ({{plural (len .Posts) "post"}})
+{{range .Posts}}
+{{if .Highlight}}! {{else}}* {{end}}{{.Title}}{{if .Tags}} [{{join .Tags ", "}}]{{end}}
+  {{.URL}}
+  {{date .PublishedAt}}{{if .Author}} by {{.Author}}{{end}}
+{{- if .Summary}}
+  {{.Summary}}
+{{- end}}
+{{end}}{{end}}`
+
+const htmlSource = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Subject}}</title>
+</head>
+<body style="font-family: sans-serif; max-width: 40em; margin: auto; color: #222;">
+<h1 style="font-size: 1.3em;">{{.Subject}}</h1>
+<p style="color: #666;">For {{.User}}, from {{date .Since}} to {{date .Until}}.</p>
+{{range .Feeds}}
+<h2 style="font-size: 1.1em; border-bottom: 1px solid #ddd;">{{if .URL}}<a href="{{.URL}}" style="color: #222;">{{.Name}}</a>{{else}}{{.Name}}{{end}} <span style="color: #666; font-weight: normal;">({{plural (len .Posts) "post"}})</span></h2>
+{{range .Posts}}
+<div style="margin: 0 0 1em 0;{{if .Highlight}} border-left: 3px solid #e8a200; padding-left: 0.5em;{{end}}">
+<a href="{{.URL}}" style="font-weight: bold;">{{.Title}}</a>{{range .Tags}} <span style="background: #eee; border-radius: 3px; padding: 0 0.3em; font-size: 0.85em;">{{.}}</span>{{end}}<br>
+<small style="color: #666;">{{date .PublishedAt}}{{if .Author}} by {{.Author}}{{end}}</small>
+{{- if .Summary}}
+<p style="margin: 0.3em 0;">{{.Summary}}</p>
+{{- end}}
+</div>
+{{end}}{{end}}
+</body>
+</html>
+`
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(funcs).Parse(textSource))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlSource))
+)
@@ -0,0 +1,98 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = Digest{
+	User:  "ada",
+	Since: time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC),
+	Until: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC),
+	Feeds: []Feed{
+		{
+			Name: "Go blog",
+			URL:  "https://go.dev/blog/feed.atom",
+			Posts: []Post{
+				{Title: "Go 1.30 is released", URL: "https://go.dev/blog/go1.30", Author: "Gopher", PublishedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Summary: "Faster builds.", Highlight: true, Tags: []string{"go", "release"}},
+				{Title: "Generic <methods>", URL: "https://go.dev/blog/methods?a=1&b=2", PublishedAt: time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			Name:  "Boot.dev",
+			Posts: []Post{{Title: "Why I Write Tests", URL: "https://blog.boot.dev/why-tests", PublishedAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}},
+		},
+	},
+}
+
+func TestSubject(t *testing.T) {
+	if got := sample.Subject(); got != "gator digest: 3 new posts in 2 feeds" {
+		t.Errorf("Subject = %q", got)
+	}
+	one := Digest{Feeds: []Feed{{Posts: []Post{{}}}}}
+	if got := one.Subject(); got != "gator digest: 1 new post in 1 feed" {
+		t.Errorf("Subject = %q", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	text, err := sample.Text()
+	if err != nil {
+		t.Fatalf("Text: %v", err)
+	}
+
+	for _, want := range []string{
+		"For ada, from Sun 1 Mar 2026 08:00 to Mon 2 Mar 2026 08:00.",
+		"== Go blog (2 posts)\n",
+		"! Go 1.30 is released [go, release]\n  https://go.dev/blog/go1.30\n  Sun 1 Mar 2026 12:00 by Gopher\n  Faster builds.\n",
+		"* Generic <methods>\n",
+		"== Boot.dev (1 post)\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text doesn't have %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	html, err := sample.HTML()
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+
+	for _, want := range []string{
+		"<title>gator digest: 3 new posts in 2 feeds</title>",
+		`<a href="https://go.dev/blog/feed.atom" style="color: #222;">Go blog</a>`,
+		"Generic &lt;methods&gt;",
+		`href="https://go.dev/blog/methods?a=1&amp;b=2"`,
+		"border-left: 3px solid",
+		">release</span>",
+		"<p style=\"margin: 0.3em 0;\">Faster builds.</p>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML doesn't have %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "<methods>") {
+		t.Errorf("the title was not escaped")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if got := Summarize("  short\n\n text "); got != "short text" {
+		t.Errorf("Summarize = %q", got)
+	}
+
+	long := strings.Repeat("word ", 100)
+	got := Summarize(long)
+	if !strings.HasSuffix(got, "word…") || len([]rune(got)) > summaryLength+1 {
+		t.Errorf("Summarize(long) = %q", got)
+	}
+
+	// Runes, not bytes
+	accents := strings.Repeat("é", summaryLength)
+	if got := Summarize(accents); got != accents {
+		t.Errorf("Summarize cut %d runes", len([]rune(got)))
+	}
+}
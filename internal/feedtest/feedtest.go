@@ -0,0 +1,137 @@
+// Package feedtest serves the feed fixtures in testdata over HTTP for the
+// tests of the rss package and the scraper.
+package feedtest
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+)
+
+//go:embed testdata
+var fixtures embed.FS
+
+// Response describes what the server answers on a path.
+type Response struct {
+	// File in testdata used as the body. Body is used when empty.
+	Fixture string
+	Body    []byte
+	// Defaults to 200 OK.
+	Status int
+	// Extra headers. Content-Type is guessed from the fixture name if not set.
+	Header map[string]string
+}
+
+// Server is an httptest.Server with a configurable response per path that
+// remembers the requests it received.
+type Server struct {
+	*httptest.Server
+	t testing.TB
+
+	mu       sync.Mutex
+	routes   map[string]Response
+	requests map[string][]*http.Request
+}
+
+// NewServer starts a server answering routes. It is closed when the test ends.
+func NewServer(t testing.TB, routes map[string]Response) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:        t,
+		routes:   map[string]Response{},
+		requests: map[string][]*http.Request{},
+	}
+	for p, r := range routes {
+		s.routes[p] = r
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// FeedURL returns the absolute URL of a path on the server.
+func (s *Server) FeedURL(p string) string {
+	return s.URL + p
+}
+
+// Handle sets (or replaces) the response for a path.
+func (s *Server) Handle(p string, r Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes[p] = r
+}
+
+// Requests returns the requests received on a path, oldest first.
+func (s *Server) Requests(p string) []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*http.Request{}, s.requests[p]...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.requests[req.URL.Path] = append(s.requests[req.URL.Path], req.Clone(req.Context()))
+	r, ok := s.routes[req.URL.Path]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	body := r.Body
+	if r.Fixture != "" {
+		// Not Fixture(): t.Fatal can't be called from the server goroutine
+		data, err := fixtures.ReadFile(path.Join("testdata", r.Fixture))
+		if err != nil {
+			s.t.Errorf("reading fixture %s: %v", r.Fixture, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = data
+		w.Header().Set("Content-Type", contentType(r.Fixture))
+	}
+	for k, v := range r.Header {
+		w.Header().Set(k, v)
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Fixture returns the contents of a file in testdata.
+func Fixture(t testing.TB, name string) []byte {
+	t.Helper()
+
+	data, err := fixtures.ReadFile(path.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+
+	return data
+}
+
+// contentType mimics what real servers send. No charset on purpose, so the
+// decoder has to find it in the document.
+func contentType(name string) string {
+	switch path.Ext(name) {
+	case ".json":
+		return "application/feed+json"
+	case ".html":
+		return "text/html"
+	}
+
+	return "application/xml"
+}
@@ -0,0 +1,141 @@
+// Package migrate applies the migrations embedded from sql/schema (Postgres)
+// and sql/sqlite/schema (SQLite), so gator doesn't need the goose CLI. It
+// uses goose's own version table, so databases migrated by hand with goose
+// keep working.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+
+	"github.com/neixir/gator/internal/storage"
+	pgschema "github.com/neixir/gator/sql/schema"
+	sqliteschema "github.com/neixir/gator/sql/sqlite/schema"
+)
+
+// OutdatedError is returned by Check when the schema is behind this build.
+type OutdatedError struct {
+	Current int64
+	Latest  int64
+}
+
+func (e *OutdatedError) Error() string {
+	return fmt.Sprintf("the database schema is at version %d but gator needs version %d. Run `gator migrate up`", e.Current, e.Latest)
+}
+
+// Migrator runs the migrations of one database.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New returns a Migrator for a database opened with the given storage driver.
+func New(db *sql.DB, driver string) (*Migrator, error) {
+	provider, err := Provider(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{provider: provider}, nil
+}
+
+// Provider returns the goose provider with the migrations for driver.
+func Provider(db *sql.DB, driver string) (*goose.Provider, error) {
+	var dialect goose.Dialect
+	var migrations fs.FS
+	switch driver {
+	case storage.Postgres:
+		dialect, migrations = goose.DialectPostgres, pgschema.FS
+	case storage.SQLite:
+		dialect, migrations = goose.DialectSQLite3, sqliteschema.FS
+	default:
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+
+	provider, err := goose.NewProvider(dialect, db, migrations)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations. %v", err)
+	}
+
+	return provider, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the last applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return nil, errors.New("no migrations to roll back")
+	}
+
+	return result, err
+}
+
+// To migrates up or down to version.
+func (m *Migrator) To(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	if version < 0 || version > m.Latest() {
+		return nil, fmt.Errorf("unknown version %d (0 to %d)", version, m.Latest())
+	}
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version < current {
+		return m.provider.DownTo(ctx, version)
+	}
+
+	return m.provider.UpTo(ctx, version)
+}
+
+// Status lists every migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Version is the version of the last applied migration, 0 if none.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	version, err := m.provider.GetDBVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getting schema version. %v", err)
+	}
+
+	return version, nil
+}
+
+// Latest is the version of the newest migration in this build.
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+
+	return sources[len(sources)-1].Version
+}
+
+// Check returns an *OutdatedError if there are pending migrations, and an
+// error if the database was migrated by a newer gator.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := m.Latest()
+	if current < latest {
+		return &OutdatedError{Current: current, Latest: latest}
+	}
+	if current > latest {
+		return fmt.Errorf("the database schema is at version %d, newer than this gator knows (%d). Upgrade gator", current, latest)
+	}
+
+	return nil
+}
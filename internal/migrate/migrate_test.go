@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/neixir/gator/internal/storage"
+)
+
+func TestMigrator(t *testing.T) {
+	conn, err := storage.Open("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer conn.Close()
+
+	m, err := New(conn.DB, conn.Driver)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	latest := m.Latest()
+	if latest < 8 {
+		t.Fatalf("latest = %d, the embedded migrations are missing", latest)
+	}
+
+	// A new database needs every migration
+	var outdated *OutdatedError
+	if err := m.Check(ctx); !errors.As(err, &outdated) || outdated.Current != 0 || outdated.Latest != latest {
+		t.Errorf("Check on an empty database = %v", err)
+	}
+
+	results, err := m.Up(ctx)
+	if err != nil || int64(len(results)) != latest {
+		t.Fatalf("Up = %d results, %v", len(results), err)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+
+	result, err := m.Down(ctx)
+	if err != nil || result.Source.Version != latest {
+		t.Errorf("Down = %+v, %v", result, err)
+	}
+	if err := m.Check(ctx); !errors.As(err, &outdated) || outdated.Current != latest-1 {
+		t.Errorf("Check after Down = %v", err)
+	}
+
+	// To goes both ways
+	_, err = m.To(ctx, 3)
+	if err != nil {
+		t.Fatalf("To(3): %v", err)
+	}
+	if version, _ := m.Version(ctx); version != 3 {
+		t.Errorf("version = %d, want 3", version)
+	}
+	_, err = m.To(ctx, latest)
+	if err != nil {
+		t.Fatalf("To(latest): %v", err)
+	}
+	if version, _ := m.Version(ctx); version != latest {
+		t.Errorf("version = %d, want %d", version, latest)
+	}
+	if _, err := m.To(ctx, latest+1); err == nil {
+		t.Errorf("To an unknown version should fail")
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil || int64(len(statuses)) != latest {
+		t.Errorf("Status = %d, %v", len(statuses), err)
+	}
+
+	_, err = m.To(ctx, 0)
+	if err != nil {
+		t.Fatalf("To(0): %v", err)
+	}
+	if _, err := m.Down(ctx); err == nil {
+		t.Errorf("Down with nothing applied should fail")
+	}
+}
+
+func TestUnknownDriver(t *testing.T) {
+	if _, err := New(nil, "mysql"); err == nil {
+		t.Errorf("New with an unknown driver should fail")
+	}
+}
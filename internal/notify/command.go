@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const defaultCommandTimeout = 30 * time.Second
+
+// Command runs a shell command with the message as JSON on its standard
+// input and in GATOR_ALERT_* environment variables: GATOR_ALERT_RULE,
+// GATOR_ALERT_FEED, GATOR_ALERT_TITLE, GATOR_ALERT_URL, GATOR_ALERT_AUTHOR
+// and GATOR_ALERT_PUBLISHED_AT. The other GATOR_* variables are left out,
+// they can hold the database URL or the credentials key. It is not retried,
+// the command can do that itself.
+type Command struct {
+	Command string
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+func (c Command) Notify(ctx context.Context, msg Message) (int, error) {
+	input, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("encoding message: %v", err)
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.Command)
+	}
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(commandEnv(),
+		"GATOR_ALERT_RULE="+msg.Rule,
+		"GATOR_ALERT_FEED="+msg.Feed,
+		"GATOR_ALERT_TITLE="+msg.Title,
+		"GATOR_ALERT_URL="+msg.URL,
+		"GATOR_ALERT_AUTHOR="+msg.Author,
+		"GATOR_ALERT_PUBLISHED_AT="+msg.PublishedAt.Format(time.RFC3339),
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Children of the shell can keep the output open after it is killed
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return 1, fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		out := strings.TrimSpace(output.String())
+		if len(out) > 200 {
+			out = out[:200] + "..."
+		}
+		if out != "" {
+			return 1, fmt.Errorf("command failed: %v: %s", err, out)
+		}
+		return 1, fmt.Errorf("command failed: %v", err)
+	}
+
+	return 1, nil
+}
+
+// commandEnv is the environment of gator without its own GATOR_* variables.
+func commandEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		// Names aren't case sensitive on Windows
+		if !strings.HasPrefix(strings.ToUpper(kv), "GATOR_") {
+			env = append(env, kv)
+		}
+	}
+
+	return env
+}
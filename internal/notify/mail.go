@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neixir/gator/internal/config"
+)
+
+const (
+	defaultSMTPPort = 587
+	smtpTimeout     = 30 * time.Second
+)
+
+// Mail is an email to send with SendMail. With HTML it is sent as
+// multipart/alternative, Text being the plain version.
+type Mail struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// SendMail sends m through server. STARTTLS is used when the server offers
+// it, and port 465 is TLS from the start.
+func SendMail(ctx context.Context, server config.SMTP, m Mail) error {
+	data, err := m.bytes(server.From)
+	if err != nil {
+		return err
+	}
+
+	port := server.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: server.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, server.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %v", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: server.Host}); err != nil {
+			return fmt.Errorf("smtp starttls: %v", err)
+		}
+	}
+	if server.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", server.Username, server.Password, server.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %v", err)
+		}
+	}
+	if err := c.Mail(server.From); err != nil {
+		return fmt.Errorf("smtp from %s: %v", server.From, err)
+	}
+	for _, to := range m.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp to %s: %v", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp data: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %v", err)
+	}
+
+	return c.Quit()
+}
+
+// bytes renders the headers and the body, CRLF terminated.
+func (m Mail) bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		err := writeQuotedPrintable(&buf, m.Text)
+		return buf.Bytes(), err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, text string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.text); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	_, err := qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	if err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+// Email sends the message to an address. It is not retried.
+type Email struct {
+	Server config.SMTP
+	To     string
+}
+
+func (e Email) Notify(ctx context.Context, msg Message) (int, error) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n%s\n\n", msg.Title, msg.URL)
+	fmt.Fprintf(&text, "Feed: %s\n", msg.Feed)
+	if msg.Author != "" {
+		fmt.Fprintf(&text, "Author: %s\n", msg.Author)
+	}
+	fmt.Fprintf(&text, "Published: %s\n", msg.PublishedAt.Format(time.DateTime))
+	if msg.Description != "" {
+		fmt.Fprintf(&text, "\n%s\n", msg.Description)
+	}
+	fmt.Fprintf(&text, "\n-- \nSent by gator for the alert rule %s.\n", msg.Rule)
+
+	err := SendMail(ctx, e.Server, Mail{
+		To:      []string{e.To},
+		Subject: fmt.Sprintf("[gator] %s", msg.Title),
+		Text:    text.String(),
+	})
+
+	return 1, err
+}
+
+// ValidAddress checks an address to send alerts to, like
+// "ada@example.com". Names and lists are not accepted.
+func ValidAddress(addr string) error {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not an email address: %v", addr, err)
+	}
+	if parsed.Address != addr {
+		return fmt.Errorf("%q is not an email address, give only the address like %s", addr, parsed.Address)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package notify delivers alerts about new posts: a JSON POST to a
+// webhook, a shell command, or an email through an SMTP server.
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Message is what an alert says about a post. Webhooks get it as JSON and
+// commands on their standard input.
+type Message struct {
+	// The id of the alert rule that matched.
+	Rule        string    `json:"rule"`
+	Feed        string    `json:"feed"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Author      string    `json:"author,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Notifier delivers a message. It returns how many attempts it made, the
+// error is the one of the last.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) (attempts int, err error)
+}
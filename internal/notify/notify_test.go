@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/smtptest"
+)
+
+var msg = Message{
+	Rule:        "rule-1",
+	Feed:        "Go blog",
+	Title:       "Go 1.30 is released",
+	URL:         "https://go.dev/blog/go1.30",
+	Author:      "Gopher",
+	PublishedAt: time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestWebhook(t *testing.T) {
+	var calls atomic.Int32
+	var got Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fails once, then works
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	attempts, err := Webhook{URL: server.URL, Backoff: time.Millisecond}.Notify(context.Background(), msg)
+	if err != nil || attempts != 2 {
+		t.Fatalf("Notify = %d, %v, want 2 attempts", attempts, err)
+	}
+	if got != msg {
+		t.Errorf("webhook got %+v", got)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	tests := []struct {
+		status       int
+		wantAttempts int
+	}{
+		{http.StatusInternalServerError, 3},
+		{http.StatusTooManyRequests, 3},
+		// Retrying won't help
+		{http.StatusNotFound, 1},
+	}
+	for _, test := range tests {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(test.status)
+		}))
+
+		attempts, err := Webhook{URL: server.URL, Backoff: time.Millisecond}.Notify(context.Background(), msg)
+		if err == nil || !strings.Contains(err.Error(), http.StatusText(test.status)) {
+			t.Errorf("%d: err = %v", test.status, err)
+		}
+		if attempts != test.wantAttempts || int(calls.Load()) != test.wantAttempts {
+			t.Errorf("%d: %d attempts, %d calls, want %d", test.status, attempts, calls.Load(), test.wantAttempts)
+		}
+		server.Close()
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	// gator's own settings aren't passed on
+	t.Setenv("GATOR_CREDENTIALS_KEY", "secret key")
+	t.Setenv("GATOR_DB_URL", "postgres://bob:secret@db/gator")
+	out := t.TempDir() + "/out"
+	_, err := Command{Command: `printf '%s|' "$GATOR_ALERT_TITLE" "$GATOR_ALERT_URL" "$GATOR_CREDENTIALS_KEY$GATOR_DB_URL" > ` + out + ` && cat >> ` + out}.Notify(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	title, rest, _ := strings.Cut(string(data), "|")
+	url, rest, _ := strings.Cut(rest, "|")
+	settings, input, _ := strings.Cut(rest, "|")
+	var got Message
+	json.Unmarshal([]byte(input), &got)
+	if title != msg.Title || url != msg.URL || got != msg {
+		t.Errorf("command got %q %q %+v", title, url, got)
+	}
+	if settings != "" {
+		t.Errorf("the command got gator's settings: %q", settings)
+	}
+
+	_, err = Command{Command: "echo broken >&2; exit 3"}.Notify(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "exit status 3: broken") {
+		t.Errorf("failing command: err = %v", err)
+	}
+
+	_, err = Command{Command: "sleep 5", Timeout: 50 * time.Millisecond}.Notify(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow command: err = %v", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	server := smtptest.NewServer(t)
+	smtp := config.SMTP{Host: server.Host, Port: server.Port, From: "gator@example.com"}
+
+	attempts, err := Email{Server: smtp, To: "ada@example.com"}.Notify(context.Background(), msg)
+	if err != nil || attempts != 1 {
+		t.Fatalf("Notify = %d, %v", attempts, err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].From != "gator@example.com" || len(messages[0].To) != 1 || messages[0].To[0] != "ada@example.com" {
+		t.Fatalf("messages = %+v", messages)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subject != "[gator] Go 1.30 is released" {
+		t.Errorf("Subject = %q", subject)
+	}
+	body, _ := io.ReadAll(m.Body)
+	if !strings.Contains(string(body), msg.URL) || !strings.Contains(string(body), "Feed: Go blog") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestSendMailHTML(t *testing.T) {
+	server := smtptest.NewServer(t)
+	smtp := config.SMTP{Host: server.Host, Port: server.Port, From: "gator@example.com"}
+
+	err := SendMail(context.Background(), smtp, Mail{
+		To:      []string{"ada@example.com", "bob@example.com"},
+		Subject: "Digest – 3 posts",
+		Text:    "plain\n.starts with a dot",
+		HTML:    "<p>rich</p>",
+	})
+	if err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || len(messages[0].To) != 2 {
+		t.Fatalf("messages = %+v", messages)
+	}
+
+	m, _ := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	mediaType, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", m.Header.Get("Content-Type"))
+	}
+	var parts []string
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		// Decodes the quoted-printable
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.Header.Get("Content-Type")+": "+string(data))
+	}
+	want := []string{"text/plain; charset=utf-8: plain\r\n.starts with a dot", "text/html; charset=utf-8: <p>rich</p>"}
+	if len(parts) != 2 || parts[0] != want[0] || parts[1] != want[1] {
+		t.Errorf("parts = %q, want %q", parts, want)
+	}
+}
+
+func TestValidAddress(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"ada@example.com":        true,
+		"Ada <ada@example.com>":  false,
+		"ada@example.com, bob@x": false,
+		"nope":                   false,
+	} {
+		if err := ValidAddress(addr); (err == nil) != ok {
+			t.Errorf("ValidAddress(%q) = %v", addr, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Defaults of a Webhook
+const (
+	defaultAttempts       = 3
+	defaultBackoff        = time.Second
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// Webhook POSTs the message as JSON to URL. Network errors, 429 and 5xx
+// answers are retried, waiting Backoff, then twice as long each time.
+type Webhook struct {
+	URL string
+	// Defaults to 3.
+	Attempts int
+	// Defaults to one second.
+	Backoff time.Duration
+	// Defaults to a client with a 10 second timeout.
+	Client *http.Client
+}
+
+func (w Webhook) Notify(ctx context.Context, msg Message) (int, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("encoding message: %v", err)
+	}
+
+	attempts := w.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, client, body)
+		if err == nil || !retry || attempt == attempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends body once and says if a failure is worth retrying.
+func (w Webhook) post(ctx context.Context, client *http.Client, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("posting to webhook: %v", err)
+	}
+	defer res.Body.Close()
+	// Lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		retry := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+		return retry, fmt.Errorf("webhook answered %s", res.Status)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,245 @@
+// Package output prints listings as an aligned table, JSON, CSV or TSV, with
+// the same field names in every format so scripts can rely on them.
+package output
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Format is one of the output formats. Text, the empty one, is each
+// command's own human readable output.
+type Format string
+
+const (
+	Text  Format = ""
+	Table Format = "table"
+	JSON  Format = "json"
+	CSV   Format = "csv"
+	TSV   Format = "tsv"
+)
+
+// Formats lists the formats that can be asked for.
+var Formats = []Format{Table, JSON, CSV, TSV}
+
+// ParseFormat returns the format called name.
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+
+	return Text, fmt.Errorf("unknown output format %q (table, json, csv or tsv)", name)
+}
+
+// Set and String make a *Format a flag.Value, Get a flag.Getter.
+func (f *Format) Set(name string) error {
+	format, err := ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+func (f *Format) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *Format) Get() any {
+	return *f
+}
+
+// List is a listing: the names of its fields and a row of values for each item.
+type List struct {
+	Fields []string
+	Rows   [][]any
+}
+
+// NewList returns an empty list with fields.
+func NewList(fields ...string) *List {
+	return &List{Fields: fields}
+}
+
+// Add appends a row, values in the order of the fields.
+func (l *List) Add(values ...any) {
+	if len(values) != len(l.Fields) {
+		panic(fmt.Sprintf("output: %d values for %d fields", len(values), len(l.Fields)))
+	}
+	l.Rows = append(l.Rows, values)
+}
+
+// Select returns a list with only fields, in that order.
+func (l *List) Select(fields []string) (*List, error) {
+	index := make([]int, len(fields))
+	for i, field := range fields {
+		index[i] = -1
+		for j, name := range l.Fields {
+			if name == field {
+				index[i] = j
+			}
+		}
+		if index[i] < 0 {
+			return nil, fmt.Errorf("unknown field %q (%s)", field, strings.Join(l.Fields, ", "))
+		}
+	}
+
+	selected := NewList(fields...)
+	for _, row := range l.Rows {
+		values := make([]any, len(index))
+		for i, j := range index {
+			values[i] = row[j]
+		}
+		selected.Add(values...)
+	}
+
+	return selected, nil
+}
+
+// Write prints the list to w in format.
+func (l *List) Write(w io.Writer, format Format) error {
+	switch format {
+	case Table:
+		return l.writeTable(w)
+	case JSON:
+		return l.writeJSON(w)
+	case CSV:
+		return l.writeCSV(w)
+	case TSV:
+		return l.writeTSV(w)
+	}
+
+	return fmt.Errorf("can't write a list as %q", format)
+}
+
+func (l *List) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(l.Fields, "\t")))
+	for _, row := range l.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			// A line break would break the table
+			cells[i] = strings.Join(strings.Fields(text(v)), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// writeJSON writes an array of objects, keys in the order of the fields.
+func (l *List) writeJSON(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, row := range l.Rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for j, v := range row {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			key, _ := json.Marshal(l.Fields[j])
+			value, err := json.Marshal(jsonValue(v))
+			if err != nil {
+				return fmt.Errorf("encoding %s: %v", l.Fields[j], err)
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			buf.Write(value)
+		}
+		buf.WriteString("}")
+	}
+	if len(l.Rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (l *List) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(l.Fields)
+	for _, row := range l.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = text(v)
+		}
+		cw.Write(cells)
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// tsvEscaper keeps every value on one line and in one column.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (l *List) writeTSV(w io.Writer) error {
+	lines := []string{strings.Join(l.Fields, "\t")}
+	for _, row := range l.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = tsvEscaper.Replace(text(v))
+		}
+		lines = append(lines, strings.Join(cells, "\t"))
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// value unwraps the sql.Null* and uuid types, nil for NULL.
+func value(v any) any {
+	if valuer, ok := v.(driver.Valuer); ok {
+		unwrapped, err := valuer.Value()
+		if err == nil {
+			return unwrapped
+		}
+	}
+
+	return v
+}
+
+// jsonValue is v as it goes in JSON, times in the same format as the other outputs.
+func jsonValue(v any) any {
+	v = value(v)
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	return v
+}
+
+// text is v as it goes in a table, CSV or TSV cell. NULL is empty.
+func text(v any) string {
+	switch v := value(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testList() *List {
+	published := time.Date(2025, 6, 25, 12, 30, 0, 0, time.UTC)
+	id := uuid.MustParse("5b1e6c1e-3c8a-4a8e-9d1b-0c6f4f2b7a11")
+
+	list := NewList("id", "title", "author", "published_at", "read")
+	list.Add(id, "Tabs,\t\"quotes\"\nand lines", sql.NullString{String: "ada", Valid: true}, sql.NullTime{Time: published, Valid: true}, true)
+	list.Add(uuid.NullUUID{}, "Plain", sql.NullString{}, sql.NullTime{}, false)
+	return list
+}
+
+func TestWrite(t *testing.T) {
+	tests := map[Format]string{
+		Table: "" +
+			"ID                                    TITLE                     AUTHOR  PUBLISHED_AT          READ\n" +
+			"5b1e6c1e-3c8a-4a8e-9d1b-0c6f4f2b7a11  Tabs, \"quotes\" and lines  ada     2025-06-25T12:30:00Z  true\n" +
+			"                                      Plain                                                   false\n",
+		JSON: "[\n" +
+			`  {"id": "5b1e6c1e-3c8a-4a8e-9d1b-0c6f4f2b7a11", "title": "Tabs,\t\"quotes\"\nand lines", "author": "ada", "published_at": "2025-06-25T12:30:00Z", "read": true},` + "\n" +
+			`  {"id": null, "title": "Plain", "author": null, "published_at": null, "read": false}` + "\n" +
+			"]\n",
+		CSV: "id,title,author,published_at,read\n" +
+			"5b1e6c1e-3c8a-4a8e-9d1b-0c6f4f2b7a11,\"Tabs,\t\"\"quotes\"\"\nand lines\",ada,2025-06-25T12:30:00Z,true\n" +
+			",Plain,,,false\n",
+		TSV: "id\ttitle\tauthor\tpublished_at\tread\n" +
+			"5b1e6c1e-3c8a-4a8e-9d1b-0c6f4f2b7a11\tTabs,\\t\"quotes\"\\nand lines\tada\t2025-06-25T12:30:00Z\ttrue\n" +
+			"\tPlain\t\t\tfalse\n",
+	}
+
+	for format, want := range tests {
+		var buf bytes.Buffer
+		if err := testList().Write(&buf, format); err != nil {
+			t.Fatalf("Write(%s): %v", format, err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("Write(%s) =\n%s\nwant\n%s", format, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewList("name").Write(&buf, JSON); err != nil || buf.String() != "[]\n" {
+		t.Errorf("empty JSON list = %q, %v", buf.String(), err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	list, err := testList().Select([]string{"read", "title"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+
+	var buf bytes.Buffer
+	list.Write(&buf, CSV)
+	if buf.String() != "read,title\ntrue,\"Tabs,\t\"\"quotes\"\"\nand lines\"\nfalse,Plain\n" {
+		t.Errorf("selected CSV = %q", buf.String())
+	}
+
+	_, err = testList().Select([]string{"title", "body"})
+	if err == nil || !strings.Contains(err.Error(), `unknown field "body" (id, title, author, published_at, read)`) {
+		t.Errorf("Select of an unknown field = %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	var format Format
+	if err := format.Set("json"); err != nil || format != JSON || format.Get() != JSON {
+		t.Errorf("Set(json) = %q, %v", format, err)
+	}
+	if err := format.Set("xml"); err == nil {
+		t.Errorf("Set(xml) should fail")
+	}
+}
@@ -0,0 +1,381 @@
+// Package readability extracts the main content of an article page, the way
+// the reader mode of a browser does: the block with the most paragraph text
+// and the fewest links wins, together with the siblings that look like more
+// of the same text.
+package readability
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+
+	"github.com/neixir/gator/internal/sanitize"
+)
+
+// ErrNoContent is returned for pages without enough text to be an article.
+var ErrNoContent = errors.New("no article content found")
+
+const (
+	// Pages are cut at this size
+	maxPageSize  = 5 << 20
+	fetchTimeout = 20 * time.Second
+	// Shorter text is not an article, probably a paywall or a consent page
+	minContentLength = 140
+	// Shorter paragraphs don't count
+	minParagraphLength = 25
+)
+
+// Elements removed with everything inside them before scoring.
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Aside: true, atom.Footer: true,
+	atom.Svg: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Object: true, atom.Embed: true, atom.Template: true, atom.Head: true,
+}
+
+// Elements that make a div more than a paragraph.
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Div: true,
+	atom.Dl: true, atom.Figure: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Table: true, atom.Ul: true,
+}
+
+var (
+	// Class and id of the blocks that hold the article...
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	// ...and of the ones around it.
+	negativeNames = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|widget|share|social|related|promo|sponsor|banner|advert|\bads?\b|cookie|popup|modal|newsletter|subscribe|breadcrumb|masthead|menu|nav`)
+)
+
+// Fetch downloads the page at pageURL and returns its main content as
+// sanitized HTML, with the links and images made absolute.
+func Fetch(ctx context.Context, pageURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching article: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching article: unexpected status %s", res.Status)
+	}
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); contentType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("the article is not an HTML page but %s", mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(res.Body, maxPageSize), contentType)
+	if err != nil {
+		return "", fmt.Errorf("reading article: %v", err)
+	}
+
+	// Relative links are relative to where the redirects ended
+	return Extract(body, res.Request.URL)
+}
+
+// Extract returns the main content of the page read from r as sanitized
+// HTML. Relative links and images are resolved against base.
+func Extract(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("parsing article: %v", err)
+	}
+
+	clean(doc)
+	resolveURLs(doc, base)
+
+	top, scores := topCandidate(doc)
+	if top == nil {
+		return "", ErrNoContent
+	}
+
+	var out bytes.Buffer
+	for _, node := range withSiblings(top, scores) {
+		err := html.Render(&out, node)
+		if err != nil {
+			return "", fmt.Errorf("rendering article: %v", err)
+		}
+	}
+
+	content := sanitize.HTML(out.String())
+	if utf8.RuneCountInString(sanitize.Text(content)) < minContentLength {
+		return "", ErrNoContent
+	}
+
+	return content, nil
+}
+
+// clean removes the elements that are never part of the article, and the
+// ones named like what surrounds articles.
+func clean(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode || child.Type == html.ElementNode && (removedTags[child.DataAtom] || isUnlikely(child)) {
+			n.RemoveChild(child)
+		} else {
+			clean(child)
+		}
+		child = next
+	}
+}
+
+// isUnlikely says if the class or id of n are those of a sidebar, comments,
+// menus and the like. Elements that could hold the whole page stay.
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main, atom.A:
+		return false
+	}
+
+	names := attr(n, "class") + " " + attr(n, "id")
+	return negativeNames.MatchString(names) && !positiveNames.MatchString(names)
+}
+
+// resolveURLs makes links and images absolute, the content is shown away
+// from its page. Lazy loaded images get their real source.
+func resolveURLs(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		if n.DataAtom == atom.Img && attr(n, "src") == "" {
+			setAttr(n, "src", attr(n, "data-src"))
+		}
+		for i, a := range n.Attr {
+			if a.Key != "href" && a.Key != "src" || base == nil {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(a.Val))
+			if err != nil {
+				continue
+			}
+			n.Attr[i].Val = base.ResolveReference(ref).String()
+		}
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		resolveURLs(child, base)
+	}
+}
+
+// topCandidate scores the parents of every paragraph by the text of the
+// paragraph and returns the best one, with the scores of all of them.
+func topCandidate(doc *html.Node) (*html.Node, map[*html.Node]float64) {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode || n.DataAtom == atom.Html {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = baseScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if isParagraph(n) {
+			text := innerText(n)
+			if length := utf8.RuneCountInString(text); length >= minParagraphLength {
+				// One point for being a paragraph, one per comma and one per
+				// 100 characters, up to 3
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(length/100), 3)
+				addScore(n.Parent, score)
+				if n.Parent != nil {
+					addScore(n.Parent.Parent, score/2)
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	// Blocks made of links are menus, not articles
+	var top *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+
+	return top, scores
+}
+
+// isParagraph says if n holds text of its own: a p, pre or td, or a div
+// used as one.
+func isParagraph(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+		return true
+	case atom.Div:
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && blockTags[child.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// baseScore is what an element is worth before its paragraphs are counted.
+func baseScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article:
+		score = 10
+	case atom.Div, atom.Main, atom.Section:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			score -= 25
+		}
+		if positiveNames.MatchString(name) {
+			score += 25
+		}
+	}
+
+	return score
+}
+
+// withSiblings returns top and the siblings that belong with it: the ones
+// that scored close to it and the paragraphs with few links.
+func withSiblings(top *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	threshold := max(10, scores[top]*0.2)
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+
+		keep := sibling == top
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			keep = true
+		}
+		if sibling.DataAtom == atom.P {
+			text := innerText(sibling)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			switch {
+			case length > 80 && density < 0.25:
+				keep = true
+			case length > 0 && density == 0 && strings.HasSuffix(text, "."):
+				keep = true
+			}
+		}
+		if keep {
+			nodes = append(nodes, sibling)
+		}
+	}
+
+	return nodes
+}
+
+// innerText is the text of n with the whitespace collapsed.
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// linkDensity is how much of the text of n is inside links, from 0 to 1.
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(innerText(n))
+	if total == 0 {
+		return 0
+	}
+
+	links := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += utf8.RuneCountInString(innerText(n))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+
+	return float64(links) / float64(total)
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+func setAttr(n *html.Node, name, value string) {
+	if value == "" {
+		return
+	}
+	for i, a := range n.Attr {
+		if a.Key == name {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: name, Val: value})
+}
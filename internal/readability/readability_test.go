@@ -0,0 +1,108 @@
+package readability
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	page, err := os.Open("testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer page.Close()
+	base, _ := url.Parse("https://blog.boot.dev/blog/why-i-write-tests?utm=rss")
+
+	content, err := Extract(page, base)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	for _, want := range []string{
+		"<h1>Why I Write Tests</h1>",
+		"Tests are the cheapest way I know",
+		"I don&#39;t test everything.",
+		`<a href="https://blog.boot.dev/blog/table-tests">`,
+		`src="https://blog.boot.dev/img/coverage.png"`,
+		"Coverage of the Boot.dev backend",
+		"code that slowly rots.",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"Pricing", "Popular posts", "Share on Twitter", "Great post", "all rights reserved", "trackRead", "analytics", "font-family"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("content has %q:\n%s", unwanted, content)
+		}
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	pages := map[string]string{
+		"empty":   "",
+		"short":   "<html><body><p>Please accept our cookies to continue.</p></body></html>",
+		"links":   `<html><body><div><a href="/a">One, two, three, four, five, six, seven</a> <a href="/b">Eight, nine, ten, eleven, twelve</a></div></body></html>`,
+		"scripts": "<html><body><script>" + strings.Repeat("render(), ", 100) + "</script></body></html>",
+	}
+	for name, page := range pages {
+		t.Run(name, func(t *testing.T) {
+			content, err := Extract(strings.NewReader(page), nil)
+			if !errors.Is(err, ErrNoContent) {
+				t.Errorf("Extract = %q, %v, want ErrNoContent", content, err)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	article, err := os.ReadFile("testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latin1 := strings.ReplaceAll(string(article), "I don't test everything.", "Caf\xe9 tests, I don't test everything.")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "gator" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		w.Write([]byte(latin1))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/articles/post", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/articles/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(article)
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	content, err := Fetch(context.Background(), server.URL+"/post")
+	if err != nil || !strings.Contains(content, "Café tests") {
+		t.Errorf("Fetch = %q, %v", content, err)
+	}
+
+	// Links are relative to the page after the redirect
+	content, err = Fetch(context.Background(), server.URL+"/moved")
+	if err != nil || !strings.Contains(content, `href="`+server.URL+`/blog/table-tests"`) {
+		t.Errorf("Fetch after a redirect = %q, %v", content, err)
+	}
+
+	for _, path := range []string{"/image.png", "/missing"} {
+		if _, err := Fetch(context.Background(), server.URL+path); err == nil {
+			t.Errorf("Fetch %s: want an error", path)
+		}
+	}
+}
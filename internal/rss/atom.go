@@ -0,0 +1,81 @@
+package rss
+
+import "strings"
+
+// Atom (RFC 4287) and RSS 1.0 (RDF) documents are decoded into these types
+// and then converted to an RSSFeed, so the rest of gator only deals with one shape.
+
+type atomFeed struct {
+	Title    string      `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle string      `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []AtomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Entries  []atomEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"http://www.w3.org/2005/Atom id"`
+	Title     string     `xml:"http://www.w3.org/2005/Atom title"`
+	Links     []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Summary   string     `xml:"http://www.w3.org/2005/Atom summary"`
+	Content   string     `xml:"http://www.w3.org/2005/Atom content"`
+	Published string     `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string     `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors   []struct {
+		Name string `xml:"http://www.w3.org/2005/Atom name"`
+	} `xml:"http://www.w3.org/2005/Atom author"`
+	Media      []Media `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []Media `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+func (a *atomFeed) toRSS() *RSSFeed {
+	feed := RSSFeed{}
+	feed.Channel.Title = strings.TrimSpace(a.Title)
+	feed.Channel.Description = strings.TrimSpace(a.Subtitle)
+	feed.Channel.AtomLinks = a.Links
+
+	for _, entry := range a.Entries {
+		item := RSSItem{
+			Title:       entry.Title,
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary,
+			Content:     entry.Content,
+			PubDate:     entry.Published,
+			GUID:        entry.ID,
+			Media:       entry.Media,
+			Thumbnails:  entry.Thumbnails,
+		}
+		if item.Description == "" {
+			item.Description = entry.Content
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+		if len(entry.Authors) > 0 {
+			item.Author = strings.TrimSpace(entry.Authors[0].Name)
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return &feed
+}
+
+// In RSS 1.0 the items are siblings of the channel, not children.
+type rdfFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel"`
+	Item []RSSItem `xml:"item"`
+}
+
+func (r *rdfFeed) toRSS() *RSSFeed {
+	feed := RSSFeed{}
+	feed.Channel.Title = r.Channel.Title
+	feed.Channel.Link = r.Channel.Link
+	feed.Channel.Description = r.Channel.Description
+	feed.Channel.Item = r.Item
+
+	return &feed
+}
@@ -0,0 +1,45 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, 6, 23, 7, 1, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"Mon, 23 Jun 2025 07:01:00 +0000", want},
+		{"Mon, 23 Jun 2025 07:01:00 GMT", want},
+		{"Mon, 23 Jun 2025 09:01:00 +0200", want},
+		{"  Mon, 23 Jun 2025 07:01:00 +0000\n", want},
+		{"Tue, 3 Jun 2025 07:01:00 +0000", time.Date(2025, 6, 3, 7, 1, 0, 0, time.UTC)},
+		{"23 Jun 2025 07:01:00 +0000", want},
+		{"2025-06-23T07:01:00Z", want},
+		{"2025-06-23T09:01:00+02:00", want},
+		{"2025-06-23T07:01:00.000Z", want},
+		{"2025-06-23 07:01:00", want},
+		{"2025-06-23", time.Date(2025, 6, 23, 0, 0, 0, 0, time.UTC)},
+		{"23 Jun 25 07:01 +0000", want},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday-ish", "32/13/2025"} {
+		if _, err := ParseDate(bad); err == nil {
+			t.Errorf("ParseDate(%q) should fail", bad)
+		}
+	}
+}
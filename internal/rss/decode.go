@@ -0,0 +1,236 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var xmlDeclaration = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
+var xmlEncoding = regexp.MustCompile(`encoding=["']([A-Za-z0-9._:-]+)["']`)
+
+// HTML void elements that show up unclosed in descriptions. Unlike
+// xml.HTMLAutoClose this leaves out "link", which is a real element in feeds.
+var lenientAutoClose = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "meta", "param", "source", "wbr"}
+
+// parseFeed decodes an RSS 2.0, RSS 1.0 (RDF), Atom or JSON Feed document.
+//
+// It first tries a strict decode. If that fails it retries in lenient mode,
+// where the document is converted to clean UTF-8 beforehand and the decoder
+// tolerates unclosed tags and unknown entities. Whatever was decoded before
+// an error in lenient mode is kept, so a truncated feed still yields the
+// items before the damage.
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	data, hadBOM, err := stripBOM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONFeed(data, contentType) {
+		return parseJSONFeed(data)
+	}
+
+	// With a UTF-16 BOM the bytes are already UTF-8 now, whatever the declaration says.
+	feed, strictErr := decodeFeed(bytes.NewReader(data), true, hadBOM)
+	if strictErr == nil {
+		return feed, nil
+	}
+
+	cleaned, err := toValidUTF8(data, contentType, hadBOM)
+	if err != nil {
+		return nil, fmt.Errorf("decoding feed. %v", strictErr)
+	}
+
+	feed, err = decodeFeed(bytes.NewReader(cleaned), false, true)
+	if err != nil {
+		if feed == nil || len(feed.Channel.Item) == 0 {
+			return feed, fmt.Errorf("decoding feed. %v", strictErr)
+		}
+	}
+
+	return feed, nil
+}
+
+// stripBOM removes a byte order mark. UTF-16 documents are converted to UTF-8
+// and the second result reports that the bytes are now UTF-8.
+func stripBOM(data []byte) ([]byte, bool, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], false, nil
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}), bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		decoder := unicode.BOMOverride(unicode.UTF8.NewDecoder())
+		utf8Data, _, err := transform.Bytes(decoder, data)
+		if err != nil {
+			return nil, false, fmt.Errorf("decoding UTF-16 feed. %v", err)
+		}
+		return utf8Data, true, nil
+	}
+
+	return data, false, nil
+}
+
+func newDecoder(r io.Reader, strict bool, isUTF8 bool) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.Strict = strict
+	// Feeds are written by hand surprisingly often: &nbsp;, &eacute; and friends
+	// are HTML entities, not XML ones.
+	d.Entity = xml.HTMLEntity
+	if !strict {
+		d.AutoClose = lenientAutoClose
+	}
+
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if isUTF8 {
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+
+	return d
+}
+
+// decodeFeed looks at the root element to pick the format. In lenient mode
+// the feed is returned along with the error so partial results can be used.
+func decodeFeed(r io.Reader, strict bool, isUTF8 bool) (*RSSFeed, error) {
+	d := newDecoder(r, strict, isUTF8)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("empty document")
+			}
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case start.Name.Local == "rss":
+			feed := RSSFeed{}
+			err = d.DecodeElement(&feed, &start)
+			feed.normalize()
+			return &feed, err
+
+		case start.Name.Local == "feed" && (start.Name.Space == nsAtom || start.Name.Space == ""):
+			atom := atomFeed{}
+			err = d.DecodeElement(&atom, &start)
+			feed := atom.toRSS()
+			feed.normalize()
+			return feed, err
+
+		case start.Name.Local == "RDF":
+			rdf := rdfFeed{}
+			err = d.DecodeElement(&rdf, &start)
+			feed := rdf.toRSS()
+			feed.normalize()
+			return feed, err
+
+		default:
+			return nil, fmt.Errorf("unsupported feed format <%s>", start.Name.Local)
+		}
+	}
+}
+
+// toValidUTF8 converts the document to UTF-8 using the XML declaration, the
+// Content-Type header or a guess, and removes everything an XML parser
+// would choke on: the declaration itself, invalid bytes, control characters
+// and bare ampersands.
+func toValidUTF8(data []byte, contentType string, isUTF8 bool) ([]byte, error) {
+	if !isUTF8 {
+		label := ""
+		if decl := xmlDeclaration.Find(data); decl != nil {
+			if m := xmlEncoding.FindSubmatch(decl); m != nil {
+				label = string(m[1])
+			}
+		}
+		if label == "" {
+			if _, params, err := mime.ParseMediaType(contentType); err == nil {
+				label = params["charset"]
+			}
+		}
+		if label == "" && !utf8.Valid(data) {
+			// Most common culprit when nothing is declared
+			label = "windows-1252"
+		}
+
+		if label != "" {
+			enc, _ := charset.Lookup(label)
+			if enc == nil {
+				return nil, fmt.Errorf("unknown charset %q", label)
+			}
+			converted, _, err := transform.Bytes(enc.NewDecoder(), data)
+			if err != nil {
+				return nil, err
+			}
+			data = converted
+		}
+	}
+
+	data = xmlDeclaration.ReplaceAll(data, nil)
+	data = bytes.ToValidUTF8(data, []byte("�"))
+	data = bytes.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, data)
+
+	return escapeBareAmpersands(data), nil
+}
+
+// escapeBareAmpersands turns "&" that doesn't start an entity into "&amp;".
+func escapeBareAmpersands(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+
+	for i := 0; i < len(data); i++ {
+		if data[i] == '&' && !startsEntity(data[i+1:]) {
+			out.WriteString("&amp;")
+			continue
+		}
+		out.WriteByte(data[i])
+	}
+
+	return out.Bytes()
+}
+
+// startsEntity reports whether rest (the bytes after an "&") is a named or
+// numeric character reference.
+func startsEntity(rest []byte) bool {
+	end := bytes.IndexByte(rest, ';')
+	if end <= 0 || end > 32 {
+		return false
+	}
+
+	name := string(rest[:end])
+	if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+		return len(name) > 2 && strings.Trim(name[2:], "0123456789abcdefABCDEF") == ""
+	}
+	if strings.HasPrefix(name, "#") {
+		return len(name) > 1 && strings.Trim(name[1:], "0123456789") == ""
+	}
+
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && (i == 0 || !isDigit) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,93 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSON Feed 1.x (https://www.jsonfeed.org/version/1.1/), converted to an RSSFeed.
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Description string `json:"description"`
+	Items       []struct {
+		ID            string       `json:"id"`
+		URL           string       `json:"url"`
+		ExternalURL   string       `json:"external_url"`
+		Title         string       `json:"title"`
+		ContentHTML   string       `json:"content_html"`
+		ContentText   string       `json:"content_text"`
+		Summary       string       `json:"summary"`
+		Image         string       `json:"image"`
+		DatePublished string       `json:"date_published"`
+		DateModified  string       `json:"date_modified"`
+		Author        *jsonAuthor  `json:"author"`
+		Authors       []jsonAuthor `json:"authors"`
+	} `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+func isJSONFeed(data []byte, contentType string) bool {
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+func parseJSONFeed(data []byte) (*RSSFeed, error) {
+	raw := jsonFeed{}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("decoding JSON feed. %v", err)
+	}
+	if !strings.HasPrefix(raw.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("decoding JSON feed. unknown version %q", raw.Version)
+	}
+
+	feed := RSSFeed{}
+	feed.Channel.Title = raw.Title
+	feed.Channel.Link = raw.HomePageURL
+	feed.Channel.Description = raw.Description
+
+	for _, entry := range raw.Items {
+		item := RSSItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			Description: entry.Summary,
+			Content:     entry.ContentHTML,
+			PubDate:     entry.DatePublished,
+			GUID:        entry.ID,
+		}
+		if item.Link == "" {
+			item.Link = entry.ExternalURL
+		}
+		if item.Description == "" {
+			item.Description = entry.ContentHTML
+		}
+		if item.Description == "" {
+			item.Description = entry.ContentText
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+		if entry.Author != nil {
+			item.Author = entry.Author.Name
+		} else if len(entry.Authors) > 0 {
+			item.Author = entry.Authors[0].Name
+		}
+		if entry.Image != "" {
+			item.Thumbnails = []Media{{URL: entry.Image}}
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	feed.normalize()
+	return &feed, nil
+}
@@ -3,73 +3,184 @@ package rss
 
 import (
 	"context"
-	"encoding/xml"
+	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neixir/gator/internal/sanitize"
+)
+
+// XML namespaces of the extensions we understand.
+const (
+	nsAtom    = "http://www.w3.org/2005/Atom"
+	nsContent = "http://purl.org/rss/1.0/modules/content/"
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+	nsMedia   = "http://search.yahoo.com/mrss/"
 )
 
+// Namespaced fields go before the plain ones with the same local name:
+// encoding/xml fills the first field that matches, so <atom:link> lands in
+// AtomLinks and doesn't overwrite Link.
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title       string     `xml:"title"`
+		AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Item        []RSSItem  `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string     `xml:"title"`
+	AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Link        string     `xml:"link"`
+	Description string     `xml:"description"`
+	PubDate     string     `xml:"pubDate"`
+	Author      string     `xml:"author"`
+	GUID        string     `xml:"guid"`
+
+	// content:encoded, usually the full HTML of the post
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	// dc:creator and dc:date, used when author and pubDate are missing
+	Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	// media:content and media:thumbnail
+	Media      []Media `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []Media `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type Media struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+// PublishedAt parses the publication date of the item.
+func (i RSSItem) PublishedAt() (time.Time, error) {
+	return ParseDate(i.PubDate)
+}
+
+// normalize fills the common fields from their namespaced alternatives.
+func (f *RSSFeed) normalize() {
+	if f.Channel.Link == "" {
+		f.Channel.Link = alternateLink(f.Channel.AtomLinks)
+	}
+
+	for i := range f.Channel.Item {
+		item := &f.Channel.Item[i]
+		item.Title = strings.TrimSpace(item.Title)
+		item.Link = strings.TrimSpace(item.Link)
+		if item.Link == "" {
+			item.Link = alternateLink(item.AtomLinks)
+		}
+		if item.Author == "" {
+			item.Author = item.Creator
+		}
+		if item.PubDate == "" {
+			item.PubDate = item.Date
+		}
+		item.PubDate = strings.TrimSpace(item.PubDate)
+		if item.Link == "" && strings.HasPrefix(item.GUID, "http") {
+			item.Link = item.GUID
+		}
+	}
+}
+
+// alternateLink returns the link to the HTML version of a feed or entry.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+
+	return ""
 }
 
 // It should fetch a feed from the given URL, and, assuming that nothing goes wrong,
 // return a filled-out RSSFeed struct.
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// If auth is not nil its credentials are attached to the request.
+func fetchFeed(ctx context.Context, feedURL string, auth *Auth) (*RSSFeed, error) {
 	// Overviews
 	// https://pkg.go.dev/net/http#pkg-overview
-	
+
 	// http.NewRequestWithContext
 	// https://pkg.go.dev/net/http#NewRequestWithContext
 	// body := io.Reader
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request. %v", err)
+	}
 
 	// I set the User-Agent header to gator in the request with request.Header.Set.
 	// This is a common practice to identify your program to the server.
 	// https://pkg.go.dev/net/http#Header.Set
 	req.Header.Set("User-Agent", "gator")
 
+	if auth != nil {
+		auth.apply(req)
+	}
+
 	// http.Client.Do
 	// https://pkg.go.dev/net/http#Client.Do
 	client := http.Client{}
 	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching feed. %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed. unexpected status %s", res.Status)
+	}
 
 	// io.ReadAll
 	// https://pkg.go.dev/io#ReadAll
 	data, err := io.ReadAll(res.Body)
-	defer res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading feed. %v", err)
+	}
 
-	// xml.Unmarshal (works the same as json.Unmarshal)
-	// https://pkg.go.dev/encoding/xml#Unmarshal
-	feed := RSSFeed{}
-	err = xml.Unmarshal([]byte(data), &feed)
+	// Decoding takes care of charsets, BOMs, HTML entities and the
+	// different feed formats (see decode.go).
+	feed, err := parseFeed(data, res.Header.Get("Content-Type"))
 	if err != nil {
-		return &feed, err
+		return nil, err
 	}
 
 	// Use the html.UnescapeString function to decode escaped HTML entities (like &ldquo;).
-	// You'll need to run the Title and Description fields
-	// (of both the entire channel as well as the items) through this function.
-	for _, item := range feed.Channel.Item {
+	// Only titles go through it: they are plain text. Descriptions are HTML
+	// the decoder already unescaped once, a second pass would turn text like
+	// "&lt;script&gt;" into markup. They are sanitized as they are.
+	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
+	feed.Channel.Description = sanitize.HTML(feed.Channel.Description)
+
+	// Index the slice, ranging over values would only modify copies
+	for i := range feed.Channel.Item {
+		item := &feed.Channel.Item[i]
 		item.Title = html.UnescapeString(item.Title)
-		item.Description = html.UnescapeString(item.Description)
+		item.Description = sanitize.HTML(item.Description)
+		item.Content = sanitize.HTML(item.Content)
 	}
 
-	return &feed, nil
+	return feed, nil
 }
 
 func FetchFeed(feedURL string) (*RSSFeed, error) {
-	return fetchFeed(context.Background(), feedURL)
-}
\ No newline at end of file
+	return fetchFeed(context.Background(), feedURL, nil)
+}
+
+// FetchFeedWithAuth fetches a feed that needs credentials.
+func FetchFeedWithAuth(feedURL string, auth *Auth) (*RSSFeed, error) {
+	return fetchFeed(context.Background(), feedURL, auth)
+}
@@ -0,0 +1,351 @@
+package rss
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neixir/gator/internal/feedtest"
+)
+
+func TestFetchFeedFormats(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		title       string
+		link        string
+		items       int
+		firstTitle  string
+		firstLink   string
+		firstAuthor string
+		firstDate   time.Time
+	}{
+		{
+			fixture:    "rss2.xml",
+			title:      "Boot.dev Blog",
+			link:       "https://blog.boot.dev/",
+			items:      3,
+			firstTitle: "The Boot.dev Beat. June 2025",
+			firstLink:  "https://blog.boot.dev/news/bootdev-beat-2025-06/",
+			firstDate:  time.Date(2025, 6, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:     "namespaces.xml",
+			title:       "Planet Example",
+			link:        "https://planet.example.org/",
+			items:       2,
+			firstTitle:  "Releasing 2.0",
+			firstLink:   "https://planet.example.org/2025/releasing-2-0",
+			firstAuthor: "Ada Lovelace",
+			firstDate:   time.Date(2025, 5, 4, 10, 20, 30, 0, time.UTC),
+		},
+		{
+			fixture:     "atom.xml",
+			title:       "Example Atom Feed",
+			link:        "https://atom.example.com/",
+			items:       2,
+			firstTitle:  "Atom-Powered Robots Run Amok",
+			firstLink:   "https://atom.example.com/2025/06/01/atom",
+			firstAuthor: "John Doe",
+			firstDate:   time.Date(2025, 6, 1, 18, 30, 2, 0, time.UTC),
+		},
+		{
+			fixture:     "rdf.xml",
+			title:       "RDF Site Summary",
+			link:        "https://rdf.example.net/",
+			items:       2,
+			firstTitle:  "First",
+			firstLink:   "https://rdf.example.net/one",
+			firstAuthor: "Tim",
+			firstDate:   time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:     "jsonfeed.json",
+			title:       "My JSON Feed",
+			link:        "https://json.example.com/",
+			items:       2,
+			firstTitle:  "Second item",
+			firstLink:   "https://json.example.com/second",
+			firstAuthor: "Brent",
+			firstDate:   time.Date(2025, 2, 10, 17, 30, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "bom_utf8.xml",
+			title:      "Boot.dev Blog",
+			link:       "https://blog.boot.dev/",
+			items:      3,
+			firstTitle: "The Boot.dev Beat. June 2025",
+			firstLink:  "https://blog.boot.dev/news/bootdev-beat-2025-06/",
+			firstDate:  time.Date(2025, 6, 25, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "bom_utf16.xml",
+			title:      "Café & Bar News",
+			link:       "https://cafe.example.fr/",
+			items:      1,
+			firstTitle: "Menú del día — lunes",
+			firstLink:  "https://cafe.example.fr/menu",
+			firstDate:  time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "latin1.xml",
+			title:      "Noticias de España",
+			link:       "https://es.example.com/",
+			items:      1,
+			firstTitle: "El niño y el pingüino",
+			firstLink:  "https://es.example.com/nino",
+			firstDate:  time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "windows1252_undeclared.xml",
+			title:      "“Smart” quotes – undeclared",
+			link:       "https://cp.example.com/",
+			items:      1,
+			firstTitle: "Café €5",
+			firstLink:  "https://cp.example.com/1",
+			firstDate:  time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "html_entities.xml",
+			title:      "Café & Bar News",
+			link:       "https://cafe.example.fr/",
+			items:      1,
+			firstTitle: "Menú del día — lunes",
+			firstLink:  "https://cafe.example.fr/menu",
+			firstDate:  time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "broken_ampersand.xml",
+			title:      "Tom & Jerry Fan Club",
+			link:       "https://toons.example.com/?a=1&b=2",
+			items:      1,
+			firstTitle: "Cats & Mice",
+			firstLink:  "https://toons.example.com/post?id=1&ref=rss",
+			firstDate:  time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			fixture:    "truncated.xml",
+			title:      "Cut short",
+			link:       "https://cut.example.com/",
+			items:      1,
+			firstTitle: "Complete item",
+			firstLink:  "https://cut.example.com/1",
+			firstDate:  time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	routes := map[string]feedtest.Response{}
+	for _, tt := range tests {
+		routes["/"+tt.fixture] = feedtest.Response{Fixture: tt.fixture}
+	}
+	server := feedtest.NewServer(t, routes)
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			feed, err := FetchFeed(server.FeedURL("/" + tt.fixture))
+			if err != nil {
+				t.Fatalf("FetchFeed: %v", err)
+			}
+
+			if feed.Channel.Title != tt.title {
+				t.Errorf("channel title = %q, want %q", feed.Channel.Title, tt.title)
+			}
+			if feed.Channel.Link != tt.link {
+				t.Errorf("channel link = %q, want %q", feed.Channel.Link, tt.link)
+			}
+			if len(feed.Channel.Item) != tt.items {
+				t.Fatalf("got %d items, want %d", len(feed.Channel.Item), tt.items)
+			}
+
+			first := feed.Channel.Item[0]
+			if first.Title != tt.firstTitle {
+				t.Errorf("first title = %q, want %q", first.Title, tt.firstTitle)
+			}
+			if first.Link != tt.firstLink {
+				t.Errorf("first link = %q, want %q", first.Link, tt.firstLink)
+			}
+			if first.Author != tt.firstAuthor {
+				t.Errorf("first author = %q, want %q", first.Author, tt.firstAuthor)
+			}
+
+			published, err := first.PublishedAt()
+			if err != nil {
+				t.Fatalf("PublishedAt: %v", err)
+			}
+			if !published.Equal(tt.firstDate) {
+				t.Errorf("first date = %v, want %v", published, tt.firstDate)
+			}
+		})
+	}
+}
+
+func TestFetchFeedUnescapesAndSanitizes(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml":       {Fixture: "rss2.xml"},
+		"/namespaces.xml": {Fixture: "namespaces.xml"},
+		"/escaped.xml": {Body: []byte(`<rss version="2.0"><channel><title>T</title><item><title>Tags &amp;amp; you</title><link>https://e.example/1</link>` +
+			`<description>&lt;p&gt;Write &amp;lt;script&amp;gt; to add code.&lt;/p&gt;</description></item></channel></rss>`)},
+	})
+
+	feed, err := FetchFeed(server.FeedURL("/rss2.xml"))
+	if err != nil {
+		t.Fatalf("FetchFeed: %v", err)
+	}
+
+	if want := "Recent content on Boot.dev Blog – learn backend development"; feed.Channel.Description != want {
+		t.Errorf("channel description = %q, want %q", feed.Channel.Description, want)
+	}
+
+	items := feed.Channel.Item
+	if want := "<p>A new course “Learn Kubernetes” is out.</p>"; items[0].Description != want {
+		t.Errorf("description = %q, want %q", items[0].Description, want)
+	}
+	if want := "Is Go a good first language? — an honest answer"; items[1].Title != want {
+		t.Errorf("title = %q, want %q", items[1].Title, want)
+	}
+	if want := "<p>Short answer: yes.</p>"; items[1].Description != want {
+		t.Errorf("script and tracking pixel not removed: %q", items[1].Description)
+	}
+	if want := "<p>Because future me is <a>lazy</a>.</p>"; items[2].Description != want {
+		t.Errorf("dangerous attributes not removed: %q", items[2].Description)
+	}
+
+	feed, err = FetchFeed(server.FeedURL("/namespaces.xml"))
+	if err != nil {
+		t.Fatalf("FetchFeed: %v", err)
+	}
+
+	first := feed.Channel.Item[0]
+	if want := "<p>The <strong>full</strong> post.</p>"; first.Content != want {
+		t.Errorf("content = %q, want %q", first.Content, want)
+	}
+	if len(first.Media) != 1 || first.Media[0].URL != "https://planet.example.org/img/release.jpg" {
+		t.Errorf("media = %+v", first.Media)
+	}
+	if len(first.Thumbnails) != 1 {
+		t.Errorf("thumbnails = %+v", first.Thumbnails)
+	}
+
+	second := feed.Channel.Item[1]
+	if second.Link != "https://planet.example.org/2025/no-link" {
+		t.Errorf("link from guid = %q", second.Link)
+	}
+	if second.Author != "grace@example.org (Grace Hopper)" {
+		t.Errorf("author = %q", second.Author)
+	}
+
+	// Descriptions are unescaped once, by the decoder: escaped markup stays text
+	feed, err = FetchFeed(server.FeedURL("/escaped.xml"))
+	if err != nil {
+		t.Fatalf("FetchFeed: %v", err)
+	}
+	item := feed.Channel.Item[0]
+	if want := "<p>Write &lt;script&gt; to add code.</p>"; item.Description != want {
+		t.Errorf("description = %q, want %q", item.Description, want)
+	}
+	if want := "Tags & you"; item.Title != want {
+		t.Errorf("title = %q, want %q", item.Title, want)
+	}
+}
+
+// An item's <atom:link> is only its link when it points to the post and
+// there is no <link>.
+func TestFetchFeedItemAtomLinks(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/feed.xml": {Body: []byte(`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>T</title>` +
+			`<item><title>Both</title><link>https://e.example/1</link><atom:link rel="replies" href="https://e.example/1/comments"/></item>` +
+			`<item><title>Atom first</title><atom:link href="https://e.example/2/alt"/><link>https://e.example/2</link></item>` +
+			`<item><title>Alternate</title><atom:link rel="self" href="https://e.example/3.xml"/><atom:link rel="alternate" href="https://e.example/3"/></item>` +
+			`<item><title>Replies only</title><guid>https://e.example/4</guid><atom:link rel="replies" href="https://e.example/4/comments"/></item>` +
+			`</channel></rss>`)},
+	})
+
+	feed, err := FetchFeed(server.FeedURL("/feed.xml"))
+	if err != nil {
+		t.Fatalf("FetchFeed: %v", err)
+	}
+
+	want := []string{"https://e.example/1", "https://e.example/2", "https://e.example/3", "https://e.example/4"}
+	if len(feed.Channel.Item) != len(want) {
+		t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(want))
+	}
+	for i, item := range feed.Channel.Item {
+		if item.Link != want[i] {
+			t.Errorf("%s: link = %q, want %q", item.Title, item.Link, want[i])
+		}
+	}
+}
+
+func TestFetchFeedErrors(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/gone.xml":   {Fixture: "rss2.xml", Status: http.StatusGone},
+		"/broken.xml": {Fixture: "rss2.xml", Status: http.StatusInternalServerError},
+		"/html":       {Fixture: "not_a_feed.html"},
+		"/empty.xml":  {Fixture: "empty.xml"},
+		"/json":       {Body: []byte(`{"version": "https://jsonfeed.org/version/1.1", "items": [`)},
+	})
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{"not found", server.FeedURL("/missing.xml"), "404"},
+		{"gone", server.FeedURL("/gone.xml"), "410"},
+		{"server error", server.FeedURL("/broken.xml"), "500"},
+		{"html page", server.FeedURL("/html"), "unsupported feed format <html>"},
+		{"empty body", server.FeedURL("/empty.xml"), "empty document"},
+		{"truncated json", server.FeedURL("/json"), "JSON feed"},
+		{"bad url", "://nope", "creating request"},
+		{"connection refused", "http://127.0.0.1:1/feed.xml", "fetching feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FetchFeed(tt.url)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchFeedWithAuth(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/private.xml": {Fixture: "rss2.xml"},
+	})
+
+	tests := []struct {
+		auth   Auth
+		header string
+		want   string
+	}{
+		{Auth{Type: AuthBasic, Username: "ada", Password: "s3cret"}, "Authorization", "Basic YWRhOnMzY3JldA=="},
+		{Auth{Type: AuthBearer, Token: "tok"}, "Authorization", "Bearer tok"},
+		{Auth{Type: AuthCookie, Cookie: "session=abc"}, "Cookie", "session=abc"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.auth.Type, func(t *testing.T) {
+			_, err := FetchFeedWithAuth(server.FeedURL("/private.xml"), &tt.auth)
+			if err != nil {
+				t.Fatalf("FetchFeedWithAuth: %v", err)
+			}
+
+			req := server.Requests("/private.xml")[i]
+			if got := req.Header.Get(tt.header); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+			if got := req.Header.Get("User-Agent"); got != "gator" {
+				t.Errorf("User-Agent = %q", got)
+			}
+		})
+	}
+
+	if s := (Auth{Type: AuthBasic, Password: "s3cret"}).String(); strings.Contains(s, "s3cret") {
+		t.Errorf("String() leaks the password: %q", s)
+	}
+}
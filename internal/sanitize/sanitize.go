@@ -0,0 +1,203 @@
+// Package sanitize cleans the HTML found in feed descriptions so it is safe
+// to store and display, and renders it as plain text.
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Tags kept in the output. Anything else is removed but its text is kept.
+var allowedTags = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.B: true, atom.Blockquote: true, atom.Br: true,
+	atom.Code: true, atom.Dd: true, atom.Del: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Em: true, atom.Figcaption: true, atom.Figure: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Hr: true, atom.I: true, atom.Img: true, atom.Ins: true, atom.Li: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Q: true, atom.S: true, atom.Small: true, atom.Span: true,
+	atom.Strong: true, atom.Sub: true, atom.Sup: true, atom.Table: true, atom.Tbody: true,
+	atom.Td: true, atom.Tfoot: true, atom.Th: true, atom.Thead: true, atom.Tr: true,
+	atom.U: true, atom.Ul: true,
+}
+
+// Tags removed together with everything inside them.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Noscript: true, atom.Template: true, atom.Svg: true,
+	atom.Math: true, atom.Form: true, atom.Textarea: true, atom.Select: true,
+	atom.Button: true, atom.Head: true, atom.Title: true, atom.Frameset: true,
+}
+
+// Attributes kept per tag. No style, class, id or event handlers anywhere.
+var allowedAttrs = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Abbr:       {"title"},
+	atom.Blockquote: {"cite"},
+	atom.Q:          {"cite"},
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Th:         {"colspan", "rowspan"},
+}
+
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+// Hosts and paths that only serve tracking pixels.
+var trackerHints = []string{
+	"feeds.feedburner.com/~r/",
+	"feedburner.com/~ff/",
+	"stats.wordpress.com",
+	"pixel.wp.com",
+	"doubleclick.net",
+	"google-analytics.com",
+	"feedsportal.com",
+	"/pixel.gif",
+	"/tracking/",
+}
+
+// HTML returns s with scripts, styles, tracking pixels, unknown tags and
+// dangerous attributes removed.
+func HTML(s string) string {
+	var out bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(s))
+	// Depth inside a dropped element, while > 0 nothing is written.
+	skipping := 0
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, the input is a string so there are no other read errors
+			break
+		}
+
+		token := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.DataAtom] {
+				if tt == html.StartTagToken && !isVoid(token.DataAtom) {
+					skipping++
+				}
+				continue
+			}
+			if skipping > 0 || !allowedTags[token.DataAtom] {
+				continue
+			}
+			if token.DataAtom == atom.Img && isTrackingPixel(token) {
+				continue
+			}
+
+			token.Attr = cleanAttrs(token)
+			if token.DataAtom == atom.Img && attr(token, "src") == "" {
+				continue
+			}
+			out.WriteString(token.String())
+
+		case html.EndTagToken:
+			if droppedTags[token.DataAtom] {
+				if skipping > 0 {
+					skipping--
+				}
+				continue
+			}
+			if skipping > 0 || !allowedTags[token.DataAtom] || isVoid(token.DataAtom) {
+				continue
+			}
+			out.WriteString(token.String())
+
+		case html.TextToken:
+			if skipping > 0 {
+				continue
+			}
+			out.WriteString(html.EscapeString(token.Data))
+		}
+		// Comments and doctypes are dropped
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+func cleanAttrs(token html.Token) []html.Attribute {
+	allowed := allowedAttrs[token.DataAtom]
+	attrs := []html.Attribute{}
+
+	for _, a := range token.Attr {
+		name := strings.ToLower(a.Key)
+		if a.Namespace != "" || !slices.Contains(allowed, name) {
+			continue
+		}
+		if urlAttrs[name] {
+			cleaned, ok := safeURL(a.Val, name == "href")
+			if !ok {
+				continue
+			}
+			a.Val = cleaned
+		}
+		a.Key = name
+		attrs = append(attrs, a)
+	}
+
+	return attrs
+}
+
+// safeURL accepts http(s) and relative URLs, and mailto links when allowMailto is set.
+func safeURL(raw string, allowMailto bool) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		// Reject things like "java\tscript:" that browsers normalise into a scheme
+		if u.Scheme == "" && strings.Contains(strings.SplitN(raw, "/", 2)[0], ":") {
+			return "", false
+		}
+		return raw, true
+	case "mailto":
+		return raw, allowMailto
+	}
+
+	return "", false
+}
+
+func isTrackingPixel(token html.Token) bool {
+	width, wErr := strconv.Atoi(strings.TrimSuffix(attr(token, "width"), "px"))
+	height, hErr := strconv.Atoi(strings.TrimSuffix(attr(token, "height"), "px"))
+	if (wErr == nil && width <= 1) || (hErr == nil && height <= 1) {
+		return true
+	}
+
+	src := strings.ToLower(attr(token, "src"))
+	for _, hint := range trackerHints {
+		if strings.Contains(src, hint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if strings.EqualFold(a.Key, name) {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+func isVoid(a atom.Atom) bool {
+	switch a {
+	case atom.Br, atom.Hr, atom.Img, atom.Embed, atom.Input, atom.Meta, atom.Link, atom.Source, atom.Wbr:
+		return true
+	}
+
+	return false
+}
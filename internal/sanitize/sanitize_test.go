@@ -0,0 +1,107 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"allowed markup", `<p>Hello <strong>world</strong></p>`, `<p>Hello <strong>world</strong></p>`},
+		{"script", `<p>Hi</p><script>alert("xss")</script>`, `<p>Hi</p>`},
+		{"style", `<style>p { color: red }</style><p>Hi</p>`, `<p>Hi</p>`},
+		{"iframe", `<p>Watch</p><iframe src="https://video.example.com/embed/1"><p>fallback</p></iframe>`, `<p>Watch</p>`},
+		{"unknown tags keep their text", `<article><section>Text</section></article>`, `Text`},
+		{"event handlers", `<p onclick="steal()" onmouseover="steal()">Hi</p>`, `<p>Hi</p>`},
+		{"uppercase event handlers", `<a href="/x" ONCLICK="steal()">x</a>`, `<a href="/x">x</a>`},
+		{"style and class", `<span style="color:red" class="big" id="s">x</span>`, `<span>x</span>`},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript href with spaces", `<a href=" JavaScript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript href with a tab", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`, `<a>x</a>`},
+		{"data src", `<img src="data:image/png;base64,iVBORw0KGgo=" alt="x">`, ``},
+		{"javascript src", `<img src="javascript:alert(1)">`, ``},
+		{"safe links", `<a href="https://example.com/a?b=1&amp;c=2" title="t">x</a>`, `<a href="https://example.com/a?b=1&amp;c=2" title="t">x</a>`},
+		{"relative link", `<a href="/about">x</a>`, `<a href="/about">x</a>`},
+		{"mailto href", `<a href="mailto:ada@example.com">x</a>`, `<a href="mailto:ada@example.com">x</a>`},
+		{"mailto src", `<img src="mailto:ada@example.com">`, ``},
+		{"image", `<img src="https://example.com/a.png" alt="A" width="640" height="480">`, `<img src="https://example.com/a.png" alt="A" width="640" height="480">`},
+		{"1x1 pixel", `<p>Hi</p><img src="https://example.com/p.gif" width="1" height="1">`, `<p>Hi</p>`},
+		{"1px pixel", `<img src="https://example.com/p.gif" width="1px" height="1px">`, ``},
+		{"pixel by height", `<img src="https://example.com/p.gif" height="0">`, ``},
+		{"tracker host", `<img src="https://pixel.wp.com/g.gif?blog=1">`, ``},
+		{"feedburner tracker", `<img src="http://feeds.feedburner.com/~r/blog/~4/abc">`, ``},
+		{"nested allowed tags", `<ul><li><em><a href="/x">deep</a></em></li></ul>`, `<ul><li><em><a href="/x">deep</a></em></li></ul>`},
+		{"nested dropped tags", `<object><iframe src="/x"></iframe><p>inside</p></object><p>after</p>`, `<p>after</p>`},
+		{"script inside script", `<script><script>a</script>b</script>`, `b`},
+		{"allowed inside dropped", `<form><p>Name <input name="n"></p></form><p>after</p>`, `<p>after</p>`},
+		{"unclosed tags", `<p>One<p>Two <b>bold`, `<p>One<p>Two <b>bold`},
+		{"unclosed script", `<p>Hi</p><script>alert(1)`, `<p>Hi</p>`},
+		{"comments", `<!-- hidden --><p>Hi</p>`, `<p>Hi</p>`},
+		{"text is escaped", `1 &lt; 2 &amp;&amp; 3 &gt; 2`, `1 &lt; 2 &amp;&amp; 3 &gt; 2`},
+		{"empty", ``, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.in); got != tt.want {
+				t.Errorf("HTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", `Hello`, `Hello`},
+		{"inline tags", `Hello <strong>big</strong> <a href="/x">world</a>`, `Hello big world`},
+		{"entities", `Caf&eacute; &amp; bar &lt;3`, `Café & bar <3`},
+		{"whitespace", "  lots\n\tof   space  ", `lots of space`},
+		{"paragraphs", `<p>One</p><p>Two</p>`, "One\n\nTwo"},
+		{"line breaks", `One<br>Two<br/>Three`, "One\nTwo\nThree"},
+		{"list", `<ul><li>a</li><li>b</li></ul>`, "- a\n- b"},
+		{"script and style", `<style>p{}</style>Hi<script>alert(1)</script>`, `Hi`},
+		{"unclosed tags", `<p>One<p>Two <b>bold`, "One\n\nTwo bold"},
+		{"empty", ``, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.in); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineAndPrintable(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		line      string
+		printable string
+	}{
+		{"plain", "Café €5", "Café €5", "Café €5"},
+		{"escape sequence", "Clear\x1b[2J the screen", "Clear[2J the screen", "Clear[2J the screen"},
+		{"title sequence", "\x1b]0;pwned\x07Hi", "]0;pwnedHi", "]0;pwnedHi"},
+		{"C1 CSI", "a\u009b2Jb", "a2Jb", "a2Jb"},
+		{"carriage return and DEL", "over\rwrite\x7f", "overwrite", "overwrite"},
+		{"lines and tabs", "one\n\ttwo", "one  two", "one\n\ttwo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Line(tt.in); got != tt.line {
+				t.Errorf("Line(%q) = %q, want %q", tt.in, got, tt.line)
+			}
+			if got := Printable(tt.in); got != tt.printable {
+				t.Errorf("Printable(%q) = %q, want %q", tt.in, got, tt.printable)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package sanitize
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Elements separated from their surroundings by a blank line.
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Div: true,
+	atom.Dl: true, atom.Figure: true, atom.Footer: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+	atom.Hr: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Table: true, atom.Ul: true,
+}
+
+// Elements that start on their own line.
+var lineTags = map[atom.Atom]bool{
+	atom.Br: true, atom.Dd: true, atom.Dt: true, atom.Figcaption: true, atom.Li: true, atom.Tr: true,
+}
+
+// Text renders HTML as plain text: tags are removed, entities decoded,
+// whitespace collapsed and block elements separated by line breaks.
+func Text(s string) string {
+	text, _ := renderText(s, false)
+	return text
+}
+
+// TextWithLinks is Text with [n] after the text of each link, where n is the
+// position of its URL in links, from 1. A URL linked twice is listed once.
+func TextWithLinks(s string) (text string, links []string) {
+	return renderText(s, true)
+}
+
+func renderText(s string, footnotes bool) (string, []string) {
+	var out strings.Builder
+	var links []string
+	// URL of the link being written, if any
+	link := ""
+	z := html.NewTokenizer(strings.NewReader(s))
+	skipping := 0
+	// Whether a space or newline is pending before the next word
+	pendingSpace := false
+
+	// lineBreak ends the current line, paragraph also leaves a blank line.
+	lineBreak := func(paragraph bool) {
+		text := out.String()
+		if text == "" || strings.HasSuffix(text, "\n\n") {
+			return
+		}
+		if !strings.HasSuffix(text, "\n") {
+			out.WriteString("\n")
+		}
+		if paragraph {
+			out.WriteString("\n")
+		}
+		pendingSpace = false
+	}
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.DataAtom] {
+				if tt == html.StartTagToken && !isVoid(token.DataAtom) {
+					skipping++
+				}
+				continue
+			}
+			if skipping > 0 {
+				continue
+			}
+			if blockTags[token.DataAtom] || lineTags[token.DataAtom] {
+				lineBreak(blockTags[token.DataAtom])
+			}
+			if token.DataAtom == atom.Li {
+				out.WriteString("- ")
+				pendingSpace = false
+			}
+			if footnotes && token.DataAtom == atom.A {
+				link = attr(token, "href")
+				if strings.HasPrefix(link, "#") {
+					link = ""
+				}
+			}
+
+		case html.EndTagToken:
+			if droppedTags[token.DataAtom] {
+				if skipping > 0 {
+					skipping--
+				}
+				continue
+			}
+			if skipping == 0 && blockTags[token.DataAtom] {
+				lineBreak(true)
+			}
+			if token.DataAtom == atom.A && link != "" {
+				n := slices.Index(links, link)
+				if n < 0 {
+					links = append(links, link)
+					n = len(links) - 1
+				}
+				out.WriteString("[" + strconv.Itoa(n+1) + "]")
+				link = ""
+			}
+
+		case html.TextToken:
+			if skipping > 0 {
+				continue
+			}
+			words := strings.Fields(token.Data)
+			if len(words) == 0 {
+				pendingSpace = pendingSpace || token.Data != ""
+				continue
+			}
+
+			text := out.String()
+			startsWithSpace := strings.TrimLeft(token.Data, " \t\r\n") != token.Data
+			if (pendingSpace || startsWithSpace) && text != "" && !strings.HasSuffix(text, "\n") && !strings.HasSuffix(text, " ") {
+				out.WriteString(" ")
+			}
+			out.WriteString(strings.Join(words, " "))
+			pendingSpace = strings.TrimRight(token.Data, " \t\r\n") != token.Data
+		}
+	}
+
+	return strings.TrimSpace(out.String()), links
+}
+
+// Line removes the control characters from s, ESC and the C1 ones included,
+// so text from a feed can't move the cursor or clear the terminal. Line
+// breaks and tabs become spaces.
+func Line(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		if isControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Printable is Line for text on several lines: line breaks and tabs stay.
+func Printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && isControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isControl reports whether r is a C0 or C1 control character, or DEL.
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0)
+}
+
+// Wrap breaks the lines of text longer than width at spaces. The lines of a
+// list item ("- ") are indented under its text. Words longer than width are
+// left alone on their line.
+func Wrap(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) <= width {
+			out = append(out, line)
+			continue
+		}
+
+		indent := ""
+		if strings.HasPrefix(line, "- ") {
+			indent = "  "
+		}
+		current := ""
+		for _, word := range strings.Fields(line) {
+			switch {
+			case current == "":
+				current = word
+			case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+				current += " " + word
+			default:
+				out = append(out, current)
+				current = indent + word
+			}
+		}
+		out = append(out, current)
+	}
+
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,91 @@
+// Package secret encrypts small values (feed credentials) before they are
+// stored in the database. Values are sealed with AES-256-GCM and the random
+// nonce is stored in front of the ciphertext.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length in bytes of a decoded key.
+const KeySize = 32
+
+var ErrNoKey = errors.New("no credentials key configured")
+
+// ParseKey decodes a base64 encoded key, as found in the config file or in
+// the environment.
+func ParseKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, ErrNoKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decoding credentials key. %v", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("credentials key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// GenerateKey returns a new random key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Seal encrypts plaintext with key.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts a value returned by Seal.
+func Open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		// Most likely the key changed since the value was stored.
+		return nil, fmt.Errorf("decrypting value (wrong credentials key?). %v", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatalf("ParseKey(%q): %v", encoded, err)
+	}
+
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	key := testKey(t)
+	plaintext := []byte(`{"type":"basic","username":"ada","password":"s3cret"}`)
+
+	sealed, err := Seal(key, plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) {
+		t.Errorf("the sealed value has the plaintext in it")
+	}
+
+	opened, err := Open(key, sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, want %q", opened, plaintext)
+	}
+
+	// A new nonce every time
+	again, _ := Seal(key, plaintext)
+	if bytes.Equal(again, sealed) {
+		t.Errorf("sealing twice gave the same value")
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	sealed, err := Seal(testKey(t), []byte("token"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	_, err = Open(testKey(t), sealed)
+	if err == nil || !strings.Contains(err.Error(), "wrong credentials key") {
+		t.Errorf("Open with another key: err = %v", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	key := testKey(t)
+	sealed, err := Seal(key, []byte("token"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Every byte is covered, the nonce included
+	for i := range sealed {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 0x01
+		if _, err := Open(key, tampered); err == nil {
+			t.Errorf("Open accepted a value with byte %d changed", i)
+		}
+	}
+
+	if _, err := Open(key, sealed[:len(sealed)-1]); err == nil {
+		t.Errorf("Open accepted a truncated value")
+	}
+	if _, err := Open(key, sealed[:4]); err == nil || !strings.Contains(err.Error(), "too short") {
+		t.Errorf("Open of a value shorter than the nonce: err = %v", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := ParseKey(""); !errors.Is(err, ErrNoKey) {
+		t.Errorf("ParseKey(\"\") = %v, want ErrNoKey", err)
+	}
+	if _, err := ParseKey("not base64!"); err == nil {
+		t.Errorf("ParseKey of bad base64 should fail")
+	}
+	if _, err := ParseKey("c2hvcnQ="); err == nil || !strings.Contains(err.Error(), "must be 32 bytes") {
+		t.Errorf("ParseKey of a short key: err = %v", err)
+	}
+}
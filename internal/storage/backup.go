@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backup writes a copy of the whole database to a new file in dir and
+// returns its path. SQLite copies itself; Postgres needs pg_dump, the
+// result is a SQL script for psql. The file is only readable by the owner,
+// it has the password hashes and the encrypted feed credentials.
+func (c *Conn) Backup(ctx context.Context, dir string) (string, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return "", fmt.Errorf("creating backup directory: %v", err)
+	}
+
+	ext := ".db"
+	if c.Driver == Postgres {
+		ext = ".sql"
+	}
+	path, err := createBackupFile(dir, ext)
+	if err != nil {
+		return "", fmt.Errorf("creating backup file: %v", err)
+	}
+
+	// Both write into the empty file, which keeps its mode
+	switch c.Driver {
+	case Postgres:
+		err = pgDump(ctx, c.dsn, path)
+	default:
+		_, err = c.DB.ExecContext(ctx, "VACUUM INTO ?", path)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("backing up to %s: %v", path, err)
+	}
+
+	return path, nil
+}
+
+// createBackupFile creates an empty file in dir named after the current
+// time, readable by the owner only from the start, and returns its path.
+func createBackupFile(dir, ext string) (string, error) {
+	name := "gator-" + time.Now().Format("20060102-150405")
+	path := filepath.Join(dir, name+ext)
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			return path, f.Close()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+	}
+}
+
+func pgDump(ctx context.Context, dsn, path string) error {
+	bin, err := exec.LookPath("pg_dump")
+	if err != nil {
+		return errors.New("pg_dump not found, install the PostgreSQL client tools")
+	}
+
+	out, err := exec.CommandContext(ctx, bin, "--no-owner", "--file", path, "--dbname", dsn).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pg_dump: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
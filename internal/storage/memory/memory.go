@@ -0,0 +1,1444 @@
+// Package memory implements the storage interface in memory, for unit tests
+// and for `agg --dry-run`. It enforces the same keys and constraints as the
+// SQL schema so code tested against it behaves the same on a real database.
+// Nothing is persisted.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// ConstraintError is what the SQL backends would report as a violated constraint.
+type ConstraintError struct {
+	// "unique" or "foreign key"
+	Kind       string
+	Constraint string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s constraint %q violated", e.Kind, e.Constraint)
+}
+
+// IsUniqueViolation reports whether err comes from a UNIQUE or PRIMARY KEY constraint.
+func IsUniqueViolation(err error) bool {
+	var cErr *ConstraintError
+	return errors.As(err, &cErr) && cErr.Kind == "unique"
+}
+
+func unique(constraint string) error {
+	return &ConstraintError{Kind: "unique", Constraint: constraint}
+}
+
+func foreignKey(constraint string) error {
+	return &ConstraintError{Kind: "foreign key", Constraint: constraint}
+}
+
+// Queries keeps every table in a slice, in insertion order, which is the
+// order the SQL backends return rows in without an ORDER BY.
+type Queries struct {
+	mu          sync.Mutex
+	users       []database.User
+	feeds       []database.Feed
+	follows     []database.FeedFollow
+	posts       []database.Post
+	credentials []database.FeedCredential
+	sessions    []database.Session
+	saved       []database.SavedPost
+	reads       []database.PostRead
+	rules       []database.FilterRule
+	alerts      []database.AlertRule
+	deliveries  []database.AlertDelivery
+	digests     []database.Digest
+	settings    []database.Setting
+}
+
+func New() *Queries {
+	return &Queries{}
+}
+
+// InTx runs f on q and puts every table back as it was if f fails, like a
+// rolled back transaction. Other goroutines see the changes before f returns.
+func (q *Queries) InTx(ctx context.Context, f func(*Queries) error) error {
+	q.mu.Lock()
+	saved := q.tables()
+	q.mu.Unlock()
+
+	err := f(q)
+	if err != nil {
+		q.mu.Lock()
+		q.restore(saved)
+		q.mu.Unlock()
+	}
+
+	return err
+}
+
+// tables is a copy of every table, for InTx.
+type tables struct {
+	users       []database.User
+	feeds       []database.Feed
+	follows     []database.FeedFollow
+	posts       []database.Post
+	credentials []database.FeedCredential
+	sessions    []database.Session
+	saved       []database.SavedPost
+	reads       []database.PostRead
+	rules       []database.FilterRule
+	alerts      []database.AlertRule
+	deliveries  []database.AlertDelivery
+	digests     []database.Digest
+	settings    []database.Setting
+}
+
+func (q *Queries) tables() tables {
+	return tables{
+		users:       slices.Clone(q.users),
+		feeds:       slices.Clone(q.feeds),
+		follows:     slices.Clone(q.follows),
+		posts:       slices.Clone(q.posts),
+		credentials: slices.Clone(q.credentials),
+		sessions:    slices.Clone(q.sessions),
+		saved:       slices.Clone(q.saved),
+		reads:       slices.Clone(q.reads),
+		rules:       slices.Clone(q.rules),
+		alerts:      slices.Clone(q.alerts),
+		deliveries:  slices.Clone(q.deliveries),
+		digests:     slices.Clone(q.digests),
+		settings:    slices.Clone(q.settings),
+	}
+}
+
+func (q *Queries) restore(t tables) {
+	q.users = t.users
+	q.feeds = t.feeds
+	q.follows = t.follows
+	q.posts = t.posts
+	q.credentials = t.credentials
+	q.sessions = t.sessions
+	q.saved = t.saved
+	q.reads = t.reads
+	q.rules = t.rules
+	q.alerts = t.alerts
+	q.deliveries = t.deliveries
+	q.digests = t.digests
+	q.settings = t.settings
+}
+
+// Users
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, u := range q.users {
+		if u.ID == arg.ID {
+			return database.User{}, unique("users_pkey")
+		}
+		if u.Name == arg.Name {
+			return database.User{}, unique("users_name_key")
+		}
+	}
+
+	user := database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+		IsAdmin:      arg.IsAdmin,
+	}
+	q.users = append(q.users, user)
+
+	return user, nil
+}
+
+func (q *Queries) GetUser(ctx context.Context, name string) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, u := range q.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+
+	return database.User{}, sql.ErrNoRows
+}
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	user, ok := q.user(id)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]database.User{}, q.users...), nil
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.users {
+		if q.users[i].ID == arg.ID {
+			q.users[i].PasswordHash = arg.PasswordHash
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.users {
+		if q.users[i].ID == arg.ID {
+			q.users[i].IsAdmin = arg.IsAdmin
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// DeleteUser cascades like DeleteAllUsers, for one user.
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	feeds := map[uuid.UUID]bool{}
+	for _, f := range q.feeds {
+		if f.UserID == id {
+			feeds[f.ID] = true
+		}
+	}
+
+	q.users = deleteWhere(q.users, func(u database.User) bool { return u.ID == id })
+	q.sessions = deleteWhere(q.sessions, func(s database.Session) bool { return s.UserID == id })
+	q.feeds = deleteWhere(q.feeds, func(f database.Feed) bool { return feeds[f.ID] })
+	q.follows = deleteWhere(q.follows, func(f database.FeedFollow) bool { return f.UserID == id || feeds[f.FeedID] })
+	q.credentials = deleteWhere(q.credentials, func(c database.FeedCredential) bool { return feeds[c.FeedID] })
+	q.posts = deleteWhere(q.posts, func(p database.Post) bool { return p.FeedID.Valid && feeds[p.FeedID.UUID] })
+	q.cascade()
+
+	return nil
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg database.RenameUserParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, u := range q.users {
+		if u.Name == arg.Name && u.ID != arg.ID {
+			return unique("users_name_key")
+		}
+	}
+	for i := range q.users {
+		if q.users[i].ID == arg.ID {
+			q.users[i].Name = arg.Name
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) SetUserDeactivated(ctx context.Context, arg database.SetUserDeactivatedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.users {
+		if q.users[i].ID == arg.ID {
+			q.users[i].DeactivatedAt = arg.DeactivatedAt
+			q.users[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// DeleteAllUsers cascades to feeds, follows, credentials and sessions like the
+// schema does, and through the feeds to their posts. Posts without a feed stay.
+func (q *Queries) DeleteAllUsers(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	posts := []database.Post{}
+	for _, p := range q.posts {
+		if !p.FeedID.Valid {
+			posts = append(posts, p)
+		}
+	}
+	q.posts = posts
+
+	q.users = nil
+	q.feeds = nil
+	q.follows = nil
+	q.credentials = nil
+	q.sessions = nil
+	q.saved = nil
+	q.reads = nil
+	q.rules = nil
+	q.alerts = nil
+	q.deliveries = nil
+	q.digests = nil
+
+	return nil
+}
+
+// Sessions
+
+func (q *Queries) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, session := range q.sessions {
+		if session.TokenHash == arg.TokenHash {
+			return database.Session{}, unique("sessions_pkey")
+		}
+	}
+	if _, ok := q.user(arg.UserID); !ok {
+		return database.Session{}, foreignKey("sessions_user_id_fkey")
+	}
+
+	session := database.Session{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: arg.CreatedAt,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	q.sessions = append(q.sessions, session)
+
+	return session, nil
+}
+
+func (q *Queries) GetSession(ctx context.Context, tokenHash string) (database.Session, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, session := range q.sessions {
+		if session.TokenHash == tokenHash {
+			return session, nil
+		}
+	}
+
+	return database.Session{}, sql.ErrNoRows
+}
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sessions = deleteWhere(q.sessions, func(session database.Session) bool {
+		return session.TokenHash == tokenHash
+	})
+
+	return nil
+}
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sessions = deleteWhere(q.sessions, func(session database.Session) bool {
+		return session.ExpiresAt.Before(expiresAt)
+	})
+
+	return nil
+}
+
+func (q *Queries) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sessions = deleteWhere(q.sessions, func(session database.Session) bool {
+		return session.UserID == userID
+	})
+
+	return nil
+}
+
+// Feeds
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, f := range q.feeds {
+		if f.ID == arg.ID {
+			return database.Feed{}, unique("feeds_pkey")
+		}
+		if f.Url == arg.Url {
+			return database.Feed{}, unique("feeds_url_key")
+		}
+	}
+	if _, ok := q.user(arg.UserID); !ok {
+		return database.Feed{}, foreignKey("feeds_user_id_fkey")
+	}
+
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	q.feeds = append(q.feeds, feed)
+
+	return feed, nil
+}
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]database.Feed{}, q.feeds...), nil
+}
+
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, f := range q.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].LastFetchedAt = arg.LastFetchedAt
+			q.feeds[i].UpdatedAt = arg.LastFetchedAt.Time
+		}
+	}
+
+	return nil
+}
+
+// GetNextFeedToFetch orders the followed feeds by last_fetched_at ASC NULLS FIRST.
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var feeds []database.Feed
+	for _, f := range q.feeds {
+		if q.followed(f.ID) {
+			feeds = append(feeds, f)
+		}
+	}
+	if len(feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	sort.SliceStable(feeds, func(i, j int) bool {
+		return nullTimeBefore(feeds[i].LastFetchedAt, feeds[j].LastFetchedAt)
+	})
+
+	return feeds[0], nil
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.user(arg.ToUserID); !ok {
+		return 0, foreignKey("feeds_user_id_fkey")
+	}
+
+	var n int64
+	for i := range q.feeds {
+		if q.feeds[i].UserID == arg.FromUserID {
+			q.feeds[i].UserID = arg.ToUserID
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg database.RenameFeedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].Name = arg.Name
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, f := range q.feeds {
+		if f.Url == arg.Url && f.ID != arg.ID {
+			return unique("feeds_url_key")
+		}
+	}
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].Url = arg.Url
+			q.feeds[i].LastFetchedAt = sql.NullTime{}
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) TransferFeed(ctx context.Context, arg database.TransferFeedParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.user(arg.UserID); !ok {
+		return foreignKey("feeds_user_id_fkey")
+	}
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].UserID = arg.UserID
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// DeleteFeed cascades to the feed's follows, credentials and posts.
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.feeds = deleteWhere(q.feeds, func(f database.Feed) bool { return f.ID == id })
+	q.follows = deleteWhere(q.follows, func(f database.FeedFollow) bool { return f.FeedID == id })
+	q.credentials = deleteWhere(q.credentials, func(c database.FeedCredential) bool { return c.FeedID == id })
+	q.posts = deleteWhere(q.posts, func(p database.Post) bool { return p.FeedID.Valid && p.FeedID.UUID == id })
+	q.cascade()
+
+	return nil
+}
+
+func (q *Queries) MarkOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var n int64
+	for i := range q.feeds {
+		if !q.feeds[i].OrphanedAt.Valid && !q.followed(q.feeds[i].ID) {
+			q.feeds[i].OrphanedAt = orphanedAt
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (q *Queries) ClearOrphanedFeeds(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.feeds {
+		if q.feeds[i].OrphanedAt.Valid && q.followed(q.feeds[i].ID) {
+			q.feeds[i].OrphanedAt = sql.NullTime{}
+		}
+	}
+
+	return nil
+}
+
+// GetOrphanedFeeds returns the feeds orphaned before orphanedAt, oldest first.
+func (q *Queries) GetOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) ([]database.Feed, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.Feed
+	for _, f := range q.feeds {
+		if f.OrphanedAt.Valid && orphanedAt.Valid && f.OrphanedAt.Time.Before(orphanedAt.Time) {
+			items = append(items, f)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OrphanedAt.Time.Before(items[j].OrphanedAt.Time)
+	})
+
+	return items, nil
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].KeepPosts = arg.KeepPosts
+			q.feeds[i].KeepDays = arg.KeepDays
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) SetFeedReadability(ctx context.Context, arg database.SetFeedReadabilityParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.feeds {
+		if q.feeds[i].ID == arg.ID {
+			q.feeds[i].Readability = arg.Readability
+			q.feeds[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// Feed credentials
+
+func (q *Queries) SetFeedCredential(ctx context.Context, arg database.SetFeedCredentialParams) (database.FeedCredential, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.feed(arg.FeedID); !ok {
+		return database.FeedCredential{}, foreignKey("feed_credentials_feed_id_fkey")
+	}
+
+	// ON CONFLICT (feed_id) DO UPDATE keeps created_at
+	for i, c := range q.credentials {
+		if c.FeedID == arg.FeedID {
+			q.credentials[i].UpdatedAt = arg.UpdatedAt
+			q.credentials[i].AuthType = arg.AuthType
+			q.credentials[i].Secret = clone(arg.Secret)
+			return q.credentials[i], nil
+		}
+	}
+
+	cred := database.FeedCredential{
+		FeedID:    arg.FeedID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		AuthType:  arg.AuthType,
+		Secret:    clone(arg.Secret),
+	}
+	q.credentials = append(q.credentials, cred)
+
+	return cred, nil
+}
+
+func (q *Queries) GetFeedCredential(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, c := range q.credentials {
+		if c.FeedID == feedID {
+			c.Secret = clone(c.Secret)
+			return c, nil
+		}
+	}
+
+	return database.FeedCredential{}, sql.ErrNoRows
+}
+
+func (q *Queries) DeleteFeedCredential(ctx context.Context, feedID uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.credentials = deleteWhere(q.credentials, func(c database.FeedCredential) bool {
+		return c.FeedID == feedID
+	})
+
+	return nil
+}
+
+// Follows
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, f := range q.follows {
+		if f.ID == arg.ID {
+			return database.CreateFeedFollowRow{}, unique("feed_follows_pkey")
+		}
+		if f.UserID == arg.UserID && f.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, unique("feed_follows_user_id_feed_id_key")
+		}
+	}
+	user, ok := q.user(arg.UserID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, foreignKey("feed_follows_user_id_fkey")
+	}
+	feed, ok := q.feed(arg.FeedID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, foreignKey("feed_follows_feed_id_fkey")
+	}
+
+	q.follows = append(q.follows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.GetFeedFollowsForUserRow
+	for _, f := range q.follows {
+		if f.UserID != userID {
+			continue
+		}
+		feed, _ := q.feed(f.FeedID)
+		user, _ := q.user(f.UserID)
+		items = append(items, database.GetFeedFollowsForUserRow{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			UserID:    f.UserID,
+			FeedID:    f.FeedID,
+			Name:      feed.Name,
+			Name_2:    user.Name,
+		})
+	}
+
+	return items, nil
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.follows = deleteWhere(q.follows, func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == arg.FeedID
+	})
+
+	return nil
+}
+
+func (q *Queries) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var count int64
+	for _, f := range q.follows {
+		if f.FeedID == feedID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Posts
+
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, p := range q.posts {
+		if p.ID == arg.ID {
+			return database.Post{}, unique("posts_pkey")
+		}
+		if p.Url == arg.Url {
+			return database.Post{}, unique("posts_url_key")
+		}
+	}
+	if arg.FeedID.Valid {
+		if _, ok := q.feed(arg.FeedID.UUID); !ok {
+			return database.Post{}, foreignKey("posts_feed_id_fkey")
+		}
+	}
+
+	post := database.Post{
+		ID:              arg.ID,
+		CreatedAt:       arg.CreatedAt,
+		UpdatedAt:       arg.UpdatedAt,
+		Title:           arg.Title,
+		Url:             arg.Url,
+		Description:     arg.Description,
+		PublishedAt:     arg.PublishedAt,
+		FeedID:          arg.FeedID,
+		Author:          arg.Author,
+		DescriptionText: arg.DescriptionText,
+	}
+	q.posts = append(q.posts, post)
+
+	return post, nil
+}
+
+// GetLimitedPostsForUser returns the posts of followed feeds, ORDER BY published_at ASC.
+func (q *Queries) GetLimitedPostsForUser(ctx context.Context, arg database.GetLimitedPostsForUserParams) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	followed := map[uuid.UUID]bool{}
+	for _, f := range q.follows {
+		if f.UserID == arg.UserID {
+			followed[f.FeedID] = true
+		}
+	}
+
+	var items []database.Post
+	for _, p := range q.posts {
+		if p.FeedID.Valid && followed[p.FeedID.UUID] {
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PublishedAt.Time.Before(items[j].PublishedAt.Time)
+	})
+
+	if limit := int(arg.Limit); limit >= 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+// GetNewPostsForUser returns the posts of followed feeds created after
+// arg.CreatedAt, ORDER BY published_at ASC.
+func (q *Queries) GetNewPostsForUser(ctx context.Context, arg database.GetNewPostsForUserParams) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	followed := map[uuid.UUID]bool{}
+	for _, f := range q.follows {
+		if f.UserID == arg.UserID {
+			followed[f.FeedID] = true
+		}
+	}
+
+	var items []database.Post
+	for _, p := range q.posts {
+		if p.FeedID.Valid && followed[p.FeedID.UUID] && p.CreatedAt.After(arg.CreatedAt) {
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PublishedAt.Time.Before(items[j].PublishedAt.Time)
+	})
+
+	return items, nil
+}
+
+// GetPostsForFeed returns the posts of a feed, ORDER BY published_at ASC.
+func (q *Queries) GetPostsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.Post
+	for _, p := range q.posts {
+		if p.FeedID.Valid && p.FeedID.UUID == feedID {
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PublishedAt.Time.Before(items[j].PublishedAt.Time)
+	})
+
+	return items, nil
+}
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, p := range q.posts {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.posts {
+		if q.posts[i].ID == arg.ID {
+			q.posts[i].Content = arg.Content
+			q.posts[i].UpdatedAt = arg.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.posts = deleteWhere(q.posts, func(p database.Post) bool { return p.ID == id })
+	q.cascade()
+
+	return nil
+}
+
+// GetPrunablePostsForFeed returns the posts of a feed nobody saved, ORDER BY published_at DESC.
+func (q *Queries) GetPrunablePostsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	saved := map[uuid.UUID]bool{}
+	for _, s := range q.saved {
+		saved[s.PostID] = true
+	}
+
+	var items []database.Post
+	for _, p := range q.posts {
+		if p.FeedID.Valid && p.FeedID.UUID == feedID && !saved[p.ID] {
+			items = append(items, p)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].PublishedAt.Time.Before(items[i].PublishedAt.Time)
+	})
+
+	return items, nil
+}
+
+// Saved posts
+
+func (q *Queries) SavePost(ctx context.Context, arg database.SavePostParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.user(arg.UserID); !ok {
+		return foreignKey("saved_posts_user_id_fkey")
+	}
+	if _, ok := q.post(arg.PostID); !ok {
+		return foreignKey("saved_posts_post_id_fkey")
+	}
+	for _, s := range q.saved {
+		if s.UserID == arg.UserID && s.PostID == arg.PostID {
+			return nil
+		}
+	}
+	q.saved = append(q.saved, database.SavedPost{UserID: arg.UserID, PostID: arg.PostID, CreatedAt: arg.CreatedAt})
+
+	return nil
+}
+
+func (q *Queries) UnsavePost(ctx context.Context, arg database.UnsavePostParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.saved = deleteWhere(q.saved, func(s database.SavedPost) bool {
+		return s.UserID == arg.UserID && s.PostID == arg.PostID
+	})
+
+	return nil
+}
+
+// GetSavedPostsForUser returns the posts user saved, ORDER BY saved_posts.created_at DESC.
+func (q *Queries) GetSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.Post, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var saved []database.SavedPost
+	for _, s := range q.saved {
+		if s.UserID == userID {
+			saved = append(saved, s)
+		}
+	}
+	sort.SliceStable(saved, func(i, j int) bool {
+		return saved[j].CreatedAt.Before(saved[i].CreatedAt)
+	})
+
+	var items []database.Post
+	for _, s := range saved {
+		if p, ok := q.post(s.PostID); ok {
+			items = append(items, p)
+		}
+	}
+
+	return items, nil
+}
+
+// Read posts
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.user(arg.UserID); !ok {
+		return foreignKey("post_reads_user_id_fkey")
+	}
+	if _, ok := q.post(arg.PostID); !ok {
+		return foreignKey("post_reads_post_id_fkey")
+	}
+	for _, r := range q.reads {
+		if r.UserID == arg.UserID && r.PostID == arg.PostID {
+			return nil
+		}
+	}
+	q.reads = append(q.reads, database.PostRead{UserID: arg.UserID, PostID: arg.PostID, ReadAt: arg.ReadAt})
+
+	return nil
+}
+
+func (q *Queries) GetPostReadsForUser(ctx context.Context, userID uuid.UUID) ([]database.PostRead, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.PostRead
+	for _, r := range q.reads {
+		if r.UserID == userID {
+			items = append(items, r)
+		}
+	}
+
+	return items, nil
+}
+
+// Filter rules
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, r := range q.rules {
+		if r.ID == arg.ID {
+			return database.FilterRule{}, unique("filter_rules_pkey")
+		}
+	}
+	if _, ok := q.user(arg.UserID); !ok {
+		return database.FilterRule{}, foreignKey("filter_rules_user_id_fkey")
+	}
+	if arg.FeedID.Valid {
+		if _, ok := q.feed(arg.FeedID.UUID); !ok {
+			return database.FilterRule{}, foreignKey("filter_rules_feed_id_fkey")
+		}
+	}
+
+	rule := database.FilterRule{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Field:     arg.Field,
+		Match:     arg.Match,
+		Pattern:   arg.Pattern,
+		Action:    arg.Action,
+		Tag:       arg.Tag,
+	}
+	q.rules = append(q.rules, rule)
+
+	return rule, nil
+}
+
+// GetFilterRulesForUser returns the rules of a user, ORDER BY created_at ASC.
+func (q *Queries) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.FilterRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.FilterRule
+	for _, r := range q.rules {
+		if r.UserID == userID {
+			items = append(items, r)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, arg database.DeleteFilterRuleParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.rules)
+	q.rules = deleteWhere(q.rules, func(r database.FilterRule) bool {
+		return r.ID == arg.ID && r.UserID == arg.UserID
+	})
+
+	return int64(n - len(q.rules)), nil
+}
+
+// Alerts
+
+func (q *Queries) CreateAlertRule(ctx context.Context, arg database.CreateAlertRuleParams) (database.AlertRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, r := range q.alerts {
+		if r.ID == arg.ID {
+			return database.AlertRule{}, unique("alert_rules_pkey")
+		}
+	}
+	if _, ok := q.user(arg.UserID); !ok {
+		return database.AlertRule{}, foreignKey("alert_rules_user_id_fkey")
+	}
+	if arg.FeedID.Valid {
+		if _, ok := q.feed(arg.FeedID.UUID); !ok {
+			return database.AlertRule{}, foreignKey("alert_rules_feed_id_fkey")
+		}
+	}
+
+	rule := database.AlertRule{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Field:     arg.Field,
+		Match:     arg.Match,
+		Pattern:   arg.Pattern,
+		Kind:      arg.Kind,
+		Target:    arg.Target,
+	}
+	q.alerts = append(q.alerts, rule)
+
+	return rule, nil
+}
+
+// GetAlertRulesForUser returns the rules of a user, ORDER BY created_at ASC.
+func (q *Queries) GetAlertRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.AlertRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.AlertRule
+	for _, r := range q.alerts {
+		if r.UserID == userID {
+			items = append(items, r)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+// GetAlertRulesForFeed returns the rules of the followers of a feed that
+// apply to it, ORDER BY created_at ASC.
+func (q *Queries) GetAlertRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.AlertRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.AlertRule
+	for _, r := range q.alerts {
+		if r.FeedID.Valid && r.FeedID.UUID != feedID {
+			continue
+		}
+		for _, f := range q.follows {
+			if f.UserID == r.UserID && f.FeedID == feedID {
+				items = append(items, r)
+				break
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	return items, nil
+}
+
+func (q *Queries) DeleteAlertRule(ctx context.Context, arg database.DeleteAlertRuleParams) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.alerts)
+	q.alerts = deleteWhere(q.alerts, func(r database.AlertRule) bool {
+		return r.ID == arg.ID && r.UserID == arg.UserID
+	})
+	q.cascade()
+
+	return int64(n - len(q.alerts)), nil
+}
+
+func (q *Queries) CreateAlertDelivery(ctx context.Context, arg database.CreateAlertDeliveryParams) (database.AlertDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, d := range q.deliveries {
+		if d.ID == arg.ID {
+			return database.AlertDelivery{}, unique("alert_deliveries_pkey")
+		}
+	}
+	if _, ok := q.alert(arg.RuleID); !ok {
+		return database.AlertDelivery{}, foreignKey("alert_deliveries_rule_id_fkey")
+	}
+
+	delivery := database.AlertDelivery{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		RuleID:    arg.RuleID,
+		PostTitle: arg.PostTitle,
+		PostUrl:   arg.PostUrl,
+		Status:    arg.Status,
+		Attempts:  arg.Attempts,
+		Error:     arg.Error,
+	}
+	q.deliveries = append(q.deliveries, delivery)
+
+	return delivery, nil
+}
+
+// GetAlertDeliveriesForUser returns the deliveries of the rules of a user,
+// ORDER BY created_at DESC LIMIT arg.Limit.
+func (q *Queries) GetAlertDeliveriesForUser(ctx context.Context, arg database.GetAlertDeliveriesForUserParams) ([]database.AlertDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var items []database.AlertDelivery
+	for _, d := range q.deliveries {
+		if rule, ok := q.alert(d.RuleID); ok && rule.UserID == arg.UserID {
+			items = append(items, d)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[j].CreatedAt.Before(items[i].CreatedAt)
+	})
+	if len(items) > int(arg.Limit) {
+		items = items[:arg.Limit]
+	}
+
+	return items, nil
+}
+
+// Digests
+
+func (q *Queries) GetLastDigest(ctx context.Context, userID uuid.UUID) (database.Digest, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, d := range q.digests {
+		if d.UserID == userID {
+			return d, nil
+		}
+	}
+
+	return database.Digest{}, sql.ErrNoRows
+}
+
+func (q *Queries) SetLastDigest(ctx context.Context, arg database.SetLastDigestParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.user(arg.UserID); !ok {
+		return foreignKey("digests_user_id_fkey")
+	}
+	digest := database.Digest{UserID: arg.UserID, SentAt: arg.SentAt}
+	for i := range q.digests {
+		if q.digests[i].UserID == arg.UserID {
+			q.digests[i] = digest
+			return nil
+		}
+	}
+	q.digests = append(q.digests, digest)
+
+	return nil
+}
+
+// Settings
+
+func (q *Queries) GetSetting(ctx context.Context, key string) (database.Setting, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, s := range q.settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+
+	return database.Setting{}, sql.ErrNoRows
+}
+
+func (q *Queries) SetSetting(ctx context.Context, arg database.SetSettingParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	setting := database.Setting{Key: arg.Key, Value: arg.Value, UpdatedAt: arg.UpdatedAt}
+	for i := range q.settings {
+		if q.settings[i].Key == arg.Key {
+			q.settings[i] = setting
+			return nil
+		}
+	}
+	q.settings = append(q.settings, setting)
+
+	return nil
+}
+
+func (q *Queries) DeleteSetting(ctx context.Context, key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.settings = deleteWhere(q.settings, func(s database.Setting) bool { return s.Key == key })
+
+	return nil
+}
+
+// Helpers, called with the lock held
+
+func (q *Queries) user(id uuid.UUID) (database.User, bool) {
+	for _, u := range q.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+
+	return database.User{}, false
+}
+
+func (q *Queries) feed(id uuid.UUID) (database.Feed, bool) {
+	for _, f := range q.feeds {
+		if f.ID == id {
+			return f, true
+		}
+	}
+
+	return database.Feed{}, false
+}
+
+func (q *Queries) alert(id uuid.UUID) (database.AlertRule, bool) {
+	for _, r := range q.alerts {
+		if r.ID == id {
+			return r, true
+		}
+	}
+
+	return database.AlertRule{}, false
+}
+
+func (q *Queries) post(id uuid.UUID) (database.Post, bool) {
+	for _, p := range q.posts {
+		if p.ID == id {
+			return p, true
+		}
+	}
+
+	return database.Post{}, false
+}
+
+// cascade deletes the saved and read posts, the filter and alert rules, the
+// deliveries and the digests whose user, post, feed or rule is gone, like
+// ON DELETE CASCADE.
+func (q *Queries) cascade() {
+	q.saved = deleteWhere(q.saved, func(s database.SavedPost) bool {
+		_, userOK := q.user(s.UserID)
+		_, postOK := q.post(s.PostID)
+		return !userOK || !postOK
+	})
+	q.reads = deleteWhere(q.reads, func(r database.PostRead) bool {
+		_, userOK := q.user(r.UserID)
+		_, postOK := q.post(r.PostID)
+		return !userOK || !postOK
+	})
+	q.rules = deleteWhere(q.rules, func(r database.FilterRule) bool {
+		_, userOK := q.user(r.UserID)
+		if r.FeedID.Valid {
+			_, feedOK := q.feed(r.FeedID.UUID)
+			return !userOK || !feedOK
+		}
+		return !userOK
+	})
+	q.alerts = deleteWhere(q.alerts, func(r database.AlertRule) bool {
+		_, userOK := q.user(r.UserID)
+		if r.FeedID.Valid {
+			_, feedOK := q.feed(r.FeedID.UUID)
+			return !userOK || !feedOK
+		}
+		return !userOK
+	})
+	q.deliveries = deleteWhere(q.deliveries, func(d database.AlertDelivery) bool {
+		_, ok := q.alert(d.RuleID)
+		return !ok
+	})
+	q.digests = deleteWhere(q.digests, func(d database.Digest) bool {
+		_, ok := q.user(d.UserID)
+		return !ok
+	})
+}
+
+// followed tells if anyone follows the feed.
+func (q *Queries) followed(feedID uuid.UUID) bool {
+	for _, f := range q.follows {
+		if f.FeedID == feedID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nullTimeBefore sorts NULL before any time.
+func nullTimeBefore(a, b sql.NullTime) bool {
+	if !a.Valid || !b.Valid {
+		return !a.Valid && b.Valid
+	}
+
+	return a.Time.Before(b.Time)
+}
+
+func deleteWhere[T any](items []T, match func(T) bool) []T {
+	kept := items[:0]
+	for _, item := range items {
+		if !match(item) {
+			kept = append(kept, item)
+		}
+	}
+
+	return kept
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
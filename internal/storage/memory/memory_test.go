@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/neixir/gator/internal/storage"
+	"github.com/neixir/gator/internal/storage/memory"
+	"github.com/neixir/gator/internal/storage/storagetest"
+)
+
+func TestQueries(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return memory.New()
+	})
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createAlertRule = `
+INSERT INTO alert_rules (id, created_at, user_id, feed_id, field, match, pattern, kind, target)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING ` + alertRuleColumns
+
+func (q *Queries) CreateAlertRule(ctx context.Context, arg database.CreateAlertRuleParams) (database.AlertRule, error) {
+	row := q.db.QueryRowContext(ctx, createAlertRule,
+		arg.ID, arg.CreatedAt, arg.UserID, arg.FeedID, arg.Field, arg.Match, arg.Pattern, arg.Kind, arg.Target)
+	return scanAlertRule(row)
+}
+
+const getAlertRulesForUser = `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE user_id = ? ORDER BY created_at ASC`
+
+func (q *Queries) GetAlertRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.AlertRule, error) {
+	rows, err := q.db.QueryContext(ctx, getAlertRulesForUser, userID)
+	return scanAll(rows, err, scanAlertRule)
+}
+
+var getAlertRulesForFeed = `
+SELECT ` + prefixed("alert_rules", alertRuleColumns) + `
+FROM alert_rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = alert_rules.user_id AND feed_follows.feed_id = ?
+WHERE alert_rules.feed_id IS NULL OR alert_rules.feed_id = ?
+ORDER BY alert_rules.created_at ASC
+`
+
+func (q *Queries) GetAlertRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.AlertRule, error) {
+	rows, err := q.db.QueryContext(ctx, getAlertRulesForFeed, feedID, feedID)
+	return scanAll(rows, err, scanAlertRule)
+}
+
+const deleteAlertRule = `DELETE FROM alert_rules WHERE id = ? AND user_id = ?`
+
+func (q *Queries) DeleteAlertRule(ctx context.Context, arg database.DeleteAlertRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAlertRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createAlertDelivery = `
+INSERT INTO alert_deliveries (id, created_at, rule_id, post_title, post_url, status, attempts, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING ` + alertDeliveryColumns
+
+func (q *Queries) CreateAlertDelivery(ctx context.Context, arg database.CreateAlertDeliveryParams) (database.AlertDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createAlertDelivery,
+		arg.ID, arg.CreatedAt, arg.RuleID, arg.PostTitle, arg.PostUrl, arg.Status, arg.Attempts, arg.Error)
+	return scanAlertDelivery(row)
+}
+
+var getAlertDeliveriesForUser = `
+SELECT ` + prefixed("alert_deliveries", alertDeliveryColumns) + `
+FROM alert_deliveries
+INNER JOIN alert_rules
+ON alert_rules.id = alert_deliveries.rule_id
+WHERE alert_rules.user_id = ?
+ORDER BY alert_deliveries.created_at DESC
+LIMIT ?
+`
+
+func (q *Queries) GetAlertDeliveriesForUser(ctx context.Context, arg database.GetAlertDeliveriesForUserParams) ([]database.AlertDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getAlertDeliveriesForUser, arg.UserID, arg.Limit)
+	return scanAll(rows, err, scanAlertDelivery)
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const getLastDigest = `SELECT ` + digestColumns + ` FROM digests WHERE user_id = ?`
+
+func (q *Queries) GetLastDigest(ctx context.Context, userID uuid.UUID) (database.Digest, error) {
+	return scanDigest(q.db.QueryRowContext(ctx, getLastDigest, userID))
+}
+
+const setLastDigest = `
+INSERT INTO digests (user_id, sent_at)
+VALUES (?, ?)
+ON CONFLICT (user_id) DO UPDATE
+SET sent_at = excluded.sent_at
+`
+
+func (q *Queries) SetLastDigest(ctx context.Context, arg database.SetLastDigestParams) error {
+	_, err := q.db.ExecContext(ctx, setLastDigest, arg.UserID, arg.SentAt)
+	return err
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const setFeedCredential = `
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, secret)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = excluded.updated_at, auth_type = excluded.auth_type, secret = excluded.secret
+RETURNING ` + feedCredentialColumns
+
+func (q *Queries) SetFeedCredential(ctx context.Context, arg database.SetFeedCredentialParams) (database.FeedCredential, error) {
+	row := q.db.QueryRowContext(ctx, setFeedCredential, arg.FeedID, arg.CreatedAt, arg.UpdatedAt, arg.AuthType, arg.Secret)
+	return scanFeedCredential(row)
+}
+
+const getFeedCredential = `SELECT ` + feedCredentialColumns + ` FROM feed_credentials WHERE feed_id = ?`
+
+func (q *Queries) GetFeedCredential(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error) {
+	return scanFeedCredential(q.db.QueryRowContext(ctx, getFeedCredential, feedID))
+}
+
+const deleteFeedCredential = `DELETE FROM feed_credentials WHERE feed_id = ?`
+
+func (q *Queries) DeleteFeedCredential(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedCredential, feedID)
+	return err
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// SQLite has no INSERT inside a WITH, so the insert and the join are two statements.
+const createFeedFollow = `
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?)
+`
+
+const getFeedFollow = `
+SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = feed_follows.user_id
+WHERE feed_follows.id = ?
+`
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	var i database.CreateFeedFollowRow
+	_, err := q.db.ExecContext(ctx, createFeedFollow, arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.UserID, arg.FeedID)
+	if err != nil {
+		return i, err
+	}
+
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.ID)
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const getFeedFollowsForUser = `
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feeds.name, users.name
+FROM feed_follows
+INNER JOIN feeds
+ON feeds.id = feed_follows.feed_id
+INNER JOIN users
+ON users.id = feed_follows.user_id
+WHERE feed_follows.user_id = ?
+`
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	return scanAll(rows, err, func(s scanner) (database.GetFeedFollowsForUserRow, error) {
+		var i database.GetFeedFollowsForUserRow
+		err := s.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Name,
+			&i.Name_2,
+		)
+		return i, err
+	})
+}
+
+const deleteFeedFollow = `
+DELETE FROM feed_follows
+WHERE user_id = ? AND feed_id = ?
+`
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
+	return err
+}
+
+const countFeedFollowers = `SELECT COUNT(*) FROM feed_follows WHERE feed_id = ?`
+
+func (q *Queries) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	var count int64
+	err := q.db.QueryRowContext(ctx, countFeedFollowers, feedID).Scan(&count)
+	return count, err
+}
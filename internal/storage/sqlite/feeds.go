@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createFeed = `
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING ` + feedColumns
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed, arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.Name, arg.Url, arg.UserID)
+	return scanFeed(row)
+}
+
+const getFeeds = `SELECT ` + feedColumns + ` FROM feeds`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	return scanAll(rows, err, scanFeed)
+}
+
+const getFeedByUrl = `SELECT ` + feedColumns + ` FROM feeds WHERE url = ?`
+
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getFeedByUrl, url))
+}
+
+const markFeedFetched = `
+UPDATE feeds
+SET last_fetched_at = ?2, updated_at = ?2
+WHERE id = ?1
+`
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt)
+	return err
+}
+
+const getNextFeedToFetch = `
+SELECT ` + feedColumns + ` FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getNextFeedToFetch))
+}
+
+const transferFeeds = `UPDATE feeds SET user_id = ?, updated_at = ? WHERE user_id = ?`
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.UpdatedAt, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `UPDATE feeds SET name = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) RenameFeed(ctx context.Context, arg database.RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedUrl = `UPDATE feeds SET url = ?, last_fetched_at = NULL, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUrl, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedRetention = `UPDATE feeds SET keep_posts = ?, keep_days = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.KeepPosts, arg.KeepDays, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedReadability = `UPDATE feeds SET readability = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetFeedReadability(ctx context.Context, arg database.SetFeedReadabilityParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedReadability, arg.Readability, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const transferFeed = `UPDATE feeds SET user_id = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) TransferFeed(ctx context.Context, arg database.TransferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, transferFeed, arg.UserID, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const deleteFeed = `DELETE FROM feeds WHERE id = ?`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const markOrphanedFeeds = `
+UPDATE feeds SET orphaned_at = ?
+WHERE orphaned_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+func (q *Queries) MarkOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markOrphanedFeeds, orphanedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const clearOrphanedFeeds = `
+UPDATE feeds SET orphaned_at = NULL
+WHERE orphaned_at IS NOT NULL
+AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+func (q *Queries) ClearOrphanedFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, clearOrphanedFeeds)
+	return err
+}
+
+const getOrphanedFeeds = `
+SELECT ` + feedColumns + ` FROM feeds
+WHERE orphaned_at < ?
+ORDER BY orphaned_at ASC
+`
+
+func (q *Queries) GetOrphanedFeeds(ctx context.Context, orphanedAt sql.NullTime) ([]database.Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getOrphanedFeeds, orphanedAt)
+	return scanAll(rows, err, scanFeed)
+}
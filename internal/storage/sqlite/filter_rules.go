@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createFilterRule = `
+INSERT INTO filter_rules (id, created_at, user_id, feed_id, field, match, pattern, action, tag)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING ` + filterRuleColumns
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, createFilterRule,
+		arg.ID, arg.CreatedAt, arg.UserID, arg.FeedID, arg.Field, arg.Match, arg.Pattern, arg.Action, arg.Tag)
+	return scanFilterRule(row)
+}
+
+const getFilterRulesForUser = `SELECT ` + filterRuleColumns + ` FROM filter_rules WHERE user_id = ? ORDER BY created_at ASC`
+
+func (q *Queries) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRulesForUser, userID)
+	return scanAll(rows, err, scanFilterRule)
+}
+
+const deleteFilterRule = `DELETE FROM filter_rules WHERE id = ? AND user_id = ?`
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, arg database.DeleteFilterRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFilterRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
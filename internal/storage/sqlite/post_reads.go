@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const markPostRead = `
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const getPostReadsForUser = `SELECT ` + postReadColumns + ` FROM post_reads WHERE user_id = ?`
+
+func (q *Queries) GetPostReadsForUser(ctx context.Context, userID uuid.UUID) ([]database.PostRead, error) {
+	rows, err := q.db.QueryContext(ctx, getPostReadsForUser, userID)
+	return scanAll(rows, err, scanPostRead)
+}
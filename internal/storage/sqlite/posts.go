@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createPost = `
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING ` + postColumns
+
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Author,
+		arg.DescriptionText,
+	)
+	return scanPost(row)
+}
+
+var getLimitedPostsForUser = `
+SELECT ` + prefixed("posts", postColumns) + `
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?
+ORDER BY posts.published_at ASC
+LIMIT ?
+`
+
+func (q *Queries) GetLimitedPostsForUser(ctx context.Context, arg database.GetLimitedPostsForUserParams) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getLimitedPostsForUser, arg.UserID, arg.Limit)
+	return scanAll(rows, err, scanPost)
+}
+
+var getNewPostsForUser = `
+SELECT ` + prefixed("posts", postColumns) + `
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?
+WHERE posts.created_at > ?
+ORDER BY posts.published_at ASC
+`
+
+func (q *Queries) GetNewPostsForUser(ctx context.Context, arg database.GetNewPostsForUserParams) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getNewPostsForUser, arg.UserID, arg.CreatedAt)
+	return scanAll(rows, err, scanPost)
+}
+
+const getPostsForFeed = `
+SELECT ` + postColumns + ` FROM posts
+WHERE feed_id = ?
+ORDER BY published_at ASC
+`
+
+func (q *Queries) GetPostsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForFeed, feedID)
+	return scanAll(rows, err, scanPost)
+}
+
+const getPost = `SELECT ` + postColumns + ` FROM posts WHERE id = ?`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	return scanPost(q.db.QueryRowContext(ctx, getPost, id))
+}
+
+const setPostContent = `UPDATE posts SET content = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.Content, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const deletePost = `DELETE FROM posts WHERE id = ?`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const getPrunablePostsForFeed = `
+SELECT ` + postColumns + ` FROM posts
+WHERE feed_id = ?
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+ORDER BY published_at DESC
+`
+
+func (q *Queries) GetPrunablePostsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePostsForFeed, feedID)
+	return scanAll(rows, err, scanPost)
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const savePost = `
+INSERT INTO saved_posts (user_id, post_id, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+func (q *Queries) SavePost(ctx context.Context, arg database.SavePostParams) error {
+	_, err := q.db.ExecContext(ctx, savePost, arg.UserID, arg.PostID, arg.CreatedAt)
+	return err
+}
+
+const unsavePost = `DELETE FROM saved_posts WHERE user_id = ? AND post_id = ?`
+
+func (q *Queries) UnsavePost(ctx context.Context, arg database.UnsavePostParams) error {
+	_, err := q.db.ExecContext(ctx, unsavePost, arg.UserID, arg.PostID)
+	return err
+}
+
+var getSavedPostsForUser = `
+SELECT ` + prefixed("posts", postColumns) + `
+FROM posts
+INNER JOIN saved_posts
+ON saved_posts.post_id = posts.id
+WHERE saved_posts.user_id = ?
+ORDER BY saved_posts.created_at DESC
+`
+
+func (q *Queries) GetSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getSavedPostsForUser, userID)
+	return scanAll(rows, err, scanPost)
+}
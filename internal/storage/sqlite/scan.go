@@ -0,0 +1,222 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// Unlike the generated code, the column lists and scans of each table are
+// written once here, so a new column only needs to be added in one place.
+
+const userColumns = `id, created_at, updated_at, name, password_hash, is_admin, deactivated_at`
+
+const feedColumns = `id, created_at, updated_at, name, url, user_id, last_fetched_at, orphaned_at, keep_posts, keep_days, readability`
+
+const postColumns = `id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text, content`
+
+const feedCredentialColumns = `feed_id, created_at, updated_at, auth_type, secret`
+
+const sessionColumns = `token_hash, user_id, created_at, expires_at`
+
+const settingColumns = `key, value, updated_at`
+
+const filterRuleColumns = `id, created_at, user_id, feed_id, field, match, pattern, action, tag`
+
+const postReadColumns = `user_id, post_id, read_at`
+
+const alertRuleColumns = `id, created_at, user_id, feed_id, field, match, pattern, kind, target`
+
+const alertDeliveryColumns = `id, created_at, rule_id, post_title, post_url, status, attempts, error`
+
+const digestColumns = `user_id, sent_at`
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(s scanner) (database.User, error) {
+	var i database.User
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+func scanFeed(s scanner) (database.Feed, error) {
+	var i database.Feed
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.OrphanedAt,
+		&i.KeepPosts,
+		&i.KeepDays,
+		&i.Readability,
+	)
+	return i, err
+}
+
+func scanPost(s scanner) (database.Post, error) {
+	var i database.Post
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.DescriptionText,
+		&i.Content,
+	)
+	return i, err
+}
+
+func scanFeedCredential(s scanner) (database.FeedCredential, error) {
+	var i database.FeedCredential
+	err := s.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AuthType,
+		&i.Secret,
+	)
+	return i, err
+}
+
+func scanSession(s scanner) (database.Session, error) {
+	var i database.Session
+	err := s.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+func scanSetting(s scanner) (database.Setting, error) {
+	var i database.Setting
+	err := s.Scan(
+		&i.Key,
+		&i.Value,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+func scanFilterRule(s scanner) (database.FilterRule, error) {
+	var i database.FilterRule
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Field,
+		&i.Match,
+		&i.Pattern,
+		&i.Action,
+		&i.Tag,
+	)
+	return i, err
+}
+
+func scanPostRead(s scanner) (database.PostRead, error) {
+	var i database.PostRead
+	err := s.Scan(
+		&i.UserID,
+		&i.PostID,
+		&i.ReadAt,
+	)
+	return i, err
+}
+
+// scanAll reads every row with scan.
+func scanAll[T any](rows *sql.Rows, err error, scan func(scanner) (T, error)) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		i, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func scanAlertRule(s scanner) (database.AlertRule, error) {
+	var i database.AlertRule
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Field,
+		&i.Match,
+		&i.Pattern,
+		&i.Kind,
+		&i.Target,
+	)
+	return i, err
+}
+
+func scanAlertDelivery(s scanner) (database.AlertDelivery, error) {
+	var i database.AlertDelivery
+	err := s.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.RuleID,
+		&i.PostTitle,
+		&i.PostUrl,
+		&i.Status,
+		&i.Attempts,
+		&i.Error,
+	)
+	return i, err
+}
+
+func scanDigest(s scanner) (database.Digest, error) {
+	var i database.Digest
+	err := s.Scan(
+		&i.UserID,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+// prefixed qualifies a column list with a table name, for joins.
+func prefixed(table, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, column := range parts {
+		parts[i] = table + "." + column
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createSession = `
+INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+VALUES (?, ?, ?, ?)
+RETURNING ` + sessionColumns
+
+func (q *Queries) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession, arg.TokenHash, arg.UserID, arg.CreatedAt, arg.ExpiresAt)
+	return scanSession(row)
+}
+
+const getSession = `SELECT ` + sessionColumns + ` FROM sessions WHERE token_hash = ?`
+
+func (q *Queries) GetSession(ctx context.Context, tokenHash string) (database.Session, error) {
+	return scanSession(q.db.QueryRowContext(ctx, getSession, tokenHash))
+}
+
+const deleteSession = `DELETE FROM sessions WHERE token_hash = ?`
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteSession, tokenHash)
+	return err
+}
+
+const deleteExpiredSessions = `DELETE FROM sessions WHERE expires_at < ?`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredSessions, expiresAt)
+	return err
+}
+
+const deleteSessionsForUser = `DELETE FROM sessions WHERE user_id = ?`
+
+func (q *Queries) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteSessionsForUser, userID)
+	return err
+}
@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const getSetting = `SELECT ` + settingColumns + ` FROM settings WHERE key = ?`
+
+func (q *Queries) GetSetting(ctx context.Context, key string) (database.Setting, error) {
+	return scanSetting(q.db.QueryRowContext(ctx, getSetting, key))
+}
+
+const setSetting = `
+INSERT INTO settings (key, value, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (key) DO UPDATE
+SET value = excluded.value, updated_at = excluded.updated_at
+`
+
+func (q *Queries) SetSetting(ctx context.Context, arg database.SetSettingParams) error {
+	_, err := q.db.ExecContext(ctx, setSetting, arg.Key, arg.Value, arg.UpdatedAt)
+	return err
+}
+
+const deleteSetting = `DELETE FROM settings WHERE key = ?`
+
+func (q *Queries) DeleteSetting(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteSetting, key)
+	return err
+}
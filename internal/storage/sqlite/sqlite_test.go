@@ -0,0 +1,43 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neixir/gator/internal/dbtest"
+	"github.com/neixir/gator/internal/storage"
+	"github.com/neixir/gator/internal/storage/sqlite"
+	"github.com/neixir/gator/internal/storage/storagetest"
+)
+
+func TestMigrationsUpAndDown(t *testing.T) {
+	db := dbtest.SQLite(t)
+	provider := dbtest.SQLiteMigrations(t, db)
+	ctx := context.Background()
+
+	// Every Down has to undo its Up
+	_, err := provider.DownTo(ctx, 0)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+
+	var tables int
+	err = db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('goose_db_version', 'sqlite_sequence')`).Scan(&tables)
+	if err != nil {
+		t.Fatalf("counting tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables left after migrating down", tables)
+	}
+
+	_, err = provider.Up(ctx)
+	if err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return sqlite.New(dbtest.SQLite(t))
+	})
+}
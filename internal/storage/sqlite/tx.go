@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// InTx runs f with queries that share one transaction, committed if f
+// returns nil and rolled back otherwise. Inside a transaction already, f
+// joins it.
+func (q *Queries) InTx(ctx context.Context, f func(*Queries) error) error {
+	u, _ := q.db.(utcDB)
+	db, ok := u.db.(*sql.DB)
+	if !ok {
+		return f(q)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = f(q.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
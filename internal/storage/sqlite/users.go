@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+const createUser = `
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING ` + userColumns
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	row := q.db.QueryRowContext(ctx, createUser, arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.Name, arg.PasswordHash, arg.IsAdmin)
+	return scanUser(row)
+}
+
+const deleteAllUsers = `DELETE FROM users`
+
+func (q *Queries) DeleteAllUsers(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllUsers)
+	return err
+}
+
+const getUser = `SELECT ` + userColumns + ` FROM users WHERE name = ?`
+
+func (q *Queries) GetUser(ctx context.Context, name string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUser, name))
+}
+
+const getUserById = `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserById, id))
+}
+
+const getUsers = `SELECT ` + userColumns + ` FROM users`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsers)
+	return scanAll(rows, err, scanUser)
+}
+
+const setUserPassword = `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.PasswordHash, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setUserAdmin = `UPDATE users SET is_admin = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAdmin, arg.IsAdmin, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const deleteUser = `DELETE FROM users WHERE id = ?`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const renameUser = `UPDATE users SET name = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) RenameUser(ctx context.Context, arg database.RenameUserParams) error {
+	_, err := q.db.ExecContext(ctx, renameUser, arg.Name, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setUserDeactivated = `UPDATE users SET deactivated_at = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetUserDeactivated(ctx context.Context, arg database.SetUserDeactivatedParams) error {
+	_, err := q.db.ExecContext(ctx, setUserDeactivated, arg.DeactivatedAt, arg.UpdatedAt, arg.ID)
+	return err
+}
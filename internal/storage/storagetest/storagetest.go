@@ -0,0 +1,1076 @@
+// Package storagetest checks that a storage.Store behaves like the Postgres
+// queries, so every backend is held to the same rules.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/storage"
+)
+
+// Run runs the conformance tests. newStore must return an empty, migrated
+// store every time it is called.
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStore(t)) })
+	t.Run("Feeds", func(t *testing.T) { testFeeds(t, newStore(t)) })
+	t.Run("Follows", func(t *testing.T) { testFollows(t, newStore(t)) })
+	t.Run("Credentials", func(t *testing.T) { testCredentials(t, newStore(t)) })
+	t.Run("Posts", func(t *testing.T) { testPosts(t, newStore(t)) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newStore(t)) })
+	t.Run("UserManagement", func(t *testing.T) { testUserManagement(t, newStore(t)) })
+	t.Run("OrphanedFeeds", func(t *testing.T) { testOrphanedFeeds(t, newStore(t)) })
+	t.Run("SavedPosts", func(t *testing.T) { testSavedPosts(t, newStore(t)) })
+	t.Run("Settings", func(t *testing.T) { testSettings(t, newStore(t)) })
+	t.Run("PostReads", func(t *testing.T) { testPostReads(t, newStore(t)) })
+	t.Run("FilterRules", func(t *testing.T) { testFilterRules(t, newStore(t)) })
+	t.Run("Alerts", func(t *testing.T) { testAlerts(t, newStore(t)) })
+	t.Run("Digests", func(t *testing.T) { testDigests(t, newStore(t)) })
+	t.Run("Transactions", func(t *testing.T) { testTransactions(t, newStore(t)) })
+}
+
+// now is truncated to what every backend can store.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func createUser(t *testing.T, s storage.Store, name string) database.User {
+	t.Helper()
+
+	ts := now()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Name: name})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", name, err)
+	}
+
+	return user
+}
+
+func createFeed(t *testing.T, s storage.Store, user database.User, url string) database.Feed {
+	t.Helper()
+
+	ts := now()
+	feed, err := s.CreateFeed(context.Background(), database.CreateFeedParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Name: url, Url: url, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateFeed(%s): %v", url, err)
+	}
+
+	return feed
+}
+
+func follow(t *testing.T, s storage.Store, user database.User, feed database.Feed) database.CreateFeedFollowRow {
+	t.Helper()
+
+	ts := now()
+	row, err := s.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatalf("CreateFeedFollow: %v", err)
+	}
+
+	return row
+}
+
+func testUsers(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+
+	ada := createUser(t, s, "ada")
+	if !ada.CreatedAt.Equal(ada.UpdatedAt) || ada.CreatedAt.IsZero() {
+		t.Errorf("timestamps = %v, %v", ada.CreatedAt, ada.UpdatedAt)
+	}
+	createUser(t, s, "bob")
+
+	ts := now()
+	_, err := s.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Name: "ada"})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("duplicate user name: err = %v, want a unique violation", err)
+	}
+
+	got, err := s.GetUser(ctx, "ada")
+	if err != nil || got.ID != ada.ID {
+		t.Errorf("GetUser = %+v, %v", got, err)
+	}
+	if !got.CreatedAt.Equal(ada.CreatedAt) {
+		t.Errorf("created at = %v, want %v", got.CreatedAt, ada.CreatedAt)
+	}
+	got, err = s.GetUserById(ctx, ada.ID)
+	if err != nil || got.Name != "ada" {
+		t.Errorf("GetUserById = %+v, %v", got, err)
+	}
+	_, err = s.GetUser(ctx, "nobody")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown user: err = %v, want sql.ErrNoRows", err)
+	}
+
+	users, err := s.GetUsers(ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("GetUsers = %+v, %v", users, err)
+	}
+
+	// Users start without a password
+	if got.PasswordHash.Valid {
+		t.Errorf("password hash = %+v, want NULL", got.PasswordHash)
+	}
+	later := ts.Add(time.Minute)
+	err = s.SetUserPassword(ctx, database.SetUserPasswordParams{ID: ada.ID, PasswordHash: sql.NullString{String: "hash", Valid: true}, UpdatedAt: later})
+	if err != nil {
+		t.Fatalf("SetUserPassword: %v", err)
+	}
+	got, _ = s.GetUser(ctx, "ada")
+	if got.PasswordHash.String != "hash" || !got.UpdatedAt.Equal(later) {
+		t.Errorf("after SetUserPassword = %+v", got)
+	}
+
+	// Users aren't admins unless created as one
+	if got.IsAdmin {
+		t.Errorf("ada is an admin")
+	}
+	err = s.SetUserAdmin(ctx, database.SetUserAdminParams{ID: ada.ID, IsAdmin: true, UpdatedAt: later})
+	if err != nil {
+		t.Fatalf("SetUserAdmin: %v", err)
+	}
+	if got, _ = s.GetUserById(ctx, ada.ID); !got.IsAdmin {
+		t.Errorf("after SetUserAdmin = %+v", got)
+	}
+	admin, err := s.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Name: "root", IsAdmin: true})
+	if err != nil || !admin.IsAdmin {
+		t.Errorf("CreateUser admin = %+v, %v", admin, err)
+	}
+
+	// Feeds and follows go with their users
+	feed := createFeed(t, s, ada, "https://a.example.com/rss")
+	follow(t, s, ada, feed)
+
+	err = s.DeleteAllUsers(ctx)
+	if err != nil {
+		t.Fatalf("DeleteAllUsers: %v", err)
+	}
+	users, _ = s.GetUsers(ctx)
+	feeds, _ := s.GetFeeds(ctx)
+	if len(users) != 0 || len(feeds) != 0 {
+		t.Errorf("after DeleteAllUsers: %d users, %d feeds", len(users), len(feeds))
+	}
+}
+
+func testFeeds(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "ada")
+
+	a := createFeed(t, s, user, "https://a.example.com/rss")
+	b := createFeed(t, s, user, "https://b.example.com/rss")
+	if a.LastFetchedAt.Valid {
+		t.Errorf("new feed already fetched: %v", a.LastFetchedAt)
+	}
+
+	ts := now()
+	_, err := s.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Name: "again", Url: a.Url, UserID: user.ID})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("duplicate feed URL: err = %v, want a unique violation", err)
+	}
+
+	feeds, err := s.GetFeeds(ctx)
+	if err != nil || len(feeds) != 2 {
+		t.Errorf("GetFeeds = %+v, %v", feeds, err)
+	}
+	got, err := s.GetFeedByUrl(ctx, b.Url)
+	if err != nil || got.ID != b.ID {
+		t.Errorf("GetFeedByUrl = %+v, %v", got, err)
+	}
+	_, err = s.GetFeedByUrl(ctx, "https://nowhere.example.com/")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown feed: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// Never fetched feeds come first, then the oldest. Fetch times in
+	// another zone must still sort by instant.
+	madrid := time.FixedZone("CEST", 2*60*60)
+	mark := func(feed database.Feed, at time.Time) {
+		t.Helper()
+		err := s.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: feed.ID, LastFetchedAt: sql.NullTime{Time: at, Valid: true}})
+		if err != nil {
+			t.Fatalf("MarkFeedFetched: %v", err)
+		}
+	}
+	next := func() database.Feed {
+		t.Helper()
+		feed, err := s.GetNextFeedToFetch(ctx)
+		if err != nil {
+			t.Fatalf("GetNextFeedToFetch: %v", err)
+		}
+		return feed
+	}
+
+	// Feeds nobody follows are never fetched
+	if _, err := s.GetNextFeedToFetch(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("no followed feeds: err = %v, want sql.ErrNoRows", err)
+	}
+	follow(t, s, user, a)
+	follow(t, s, user, b)
+
+	mark(a, ts)
+	if got := next(); got.ID != b.ID {
+		t.Errorf("next feed = %s, want the one never fetched", got.Url)
+	}
+
+	mark(b, ts.Add(-time.Hour).In(madrid))
+	if got := next(); got.ID != b.ID {
+		t.Errorf("next feed = %s, want the one fetched longest ago", got.Url)
+	}
+	if got := next(); !got.LastFetchedAt.Time.Equal(ts.Add(-time.Hour)) {
+		t.Errorf("last fetched at = %v, want %v", got.LastFetchedAt.Time, ts.Add(-time.Hour))
+	}
+
+	mark(b, ts.Add(time.Hour).In(madrid))
+	if got := next(); got.ID != a.ID {
+		t.Errorf("next feed = %s, want %s", got.Url, a.Url)
+	}
+
+	// Editing
+	later := ts.Add(time.Minute)
+	if err := s.RenameFeed(ctx, database.RenameFeedParams{ID: a.ID, Name: "A", UpdatedAt: later}); err != nil {
+		t.Fatalf("RenameFeed: %v", err)
+	}
+	err = s.SetFeedUrl(ctx, database.SetFeedUrlParams{ID: a.ID, Url: b.Url, UpdatedAt: later})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("SetFeedUrl to a taken URL: err = %v, want a unique violation", err)
+	}
+	mark(a, ts)
+	err = s.SetFeedUrl(ctx, database.SetFeedUrlParams{ID: a.ID, Url: "https://new.example.com/rss", UpdatedAt: later})
+	if err != nil {
+		t.Fatalf("SetFeedUrl: %v", err)
+	}
+	got, err = s.GetFeedByUrl(ctx, "https://new.example.com/rss")
+	if err != nil || got.ID != a.ID || got.Name != "A" || !got.UpdatedAt.Equal(later) {
+		t.Errorf("edited feed = %+v, %v", got, err)
+	}
+	if got.LastFetchedAt.Valid {
+		t.Errorf("a new URL keeps the fetch time %v", got.LastFetchedAt)
+	}
+
+	// Owner
+	bob := createUser(t, s, "bob")
+	if err := s.TransferFeed(ctx, database.TransferFeedParams{ID: a.ID, UserID: uuid.New(), UpdatedAt: later}); err == nil {
+		t.Errorf("TransferFeed to an unknown user should fail")
+	}
+	if err := s.TransferFeed(ctx, database.TransferFeedParams{ID: a.ID, UserID: bob.ID, UpdatedAt: later}); err != nil {
+		t.Fatalf("TransferFeed: %v", err)
+	}
+	if got, _ := s.GetFeedByUrl(ctx, "https://new.example.com/rss"); got.UserID != bob.ID {
+		t.Errorf("transferred feed owner = %s, want bob", got.UserID)
+	}
+
+	// Deleting takes the follows and posts along
+	follow(t, s, bob, a)
+	_, err = s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Title: "post", Url: "https://new.example.com/1", PublishedAt: sql.NullTime{Time: ts, Valid: true}, FeedID: uuid.NullUUID{UUID: a.ID, Valid: true}})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if err := s.DeleteFeed(ctx, a.ID); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	if _, err := s.GetFeedByUrl(ctx, "https://new.example.com/rss"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted feed: err = %v, want sql.ErrNoRows", err)
+	}
+	if follows, _ := s.GetFeedFollowsForUser(ctx, bob.ID); len(follows) != 0 {
+		t.Errorf("follows of a deleted feed: %+v", follows)
+	}
+	_, err = s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Title: "post", Url: "https://new.example.com/1", PublishedAt: sql.NullTime{Time: ts, Valid: true}})
+	if err != nil {
+		t.Errorf("posts of a deleted feed are still there: %v", err)
+	}
+}
+
+func testFollows(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	a := createFeed(t, s, ada, "https://a.example.com/rss")
+	b := createFeed(t, s, ada, "https://b.example.com/rss")
+
+	row := follow(t, s, ada, a)
+	if row.UserName != "ada" || row.FeedName != a.Name || row.FeedID != a.ID {
+		t.Errorf("CreateFeedFollow = %+v", row)
+	}
+	follow(t, s, ada, b)
+	follow(t, s, bob, b)
+
+	ts := now()
+	_, err := s.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, UserID: ada.ID, FeedID: a.ID})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("following twice: err = %v, want a unique violation", err)
+	}
+
+	follows, err := s.GetFeedFollowsForUser(ctx, ada.ID)
+	if err != nil || len(follows) != 2 {
+		t.Fatalf("GetFeedFollowsForUser = %+v, %v", follows, err)
+	}
+
+	err = s.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: ada.ID, FeedID: b.ID})
+	if err != nil {
+		t.Fatalf("DeleteFeedFollow: %v", err)
+	}
+	follows, _ = s.GetFeedFollowsForUser(ctx, ada.ID)
+	if len(follows) != 1 || follows[0].Name != a.Name {
+		t.Errorf("ada follows %+v after unfollowing b", follows)
+	}
+	follows, _ = s.GetFeedFollowsForUser(ctx, bob.ID)
+	if len(follows) != 1 {
+		t.Errorf("unfollowing affected another user: %+v", follows)
+	}
+
+	// Not following is not an error
+	err = s.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: ada.ID, FeedID: b.ID})
+	if err != nil {
+		t.Errorf("DeleteFeedFollow twice: %v", err)
+	}
+}
+
+func testCredentials(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "ada")
+	feed := createFeed(t, s, user, "https://a.example.com/rss")
+
+	_, err := s.GetFeedCredential(ctx, feed.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("no credential: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// Credentials are upserted
+	ts := now()
+	for _, authType := range []string{"basic", "bearer"} {
+		_, err = s.SetFeedCredential(ctx, database.SetFeedCredentialParams{FeedID: feed.ID, CreatedAt: ts, UpdatedAt: ts, AuthType: authType, Secret: []byte{1, 2, 3}})
+		if err != nil {
+			t.Fatalf("SetFeedCredential: %v", err)
+		}
+	}
+	cred, err := s.GetFeedCredential(ctx, feed.ID)
+	if err != nil || cred.AuthType != "bearer" || string(cred.Secret) != "\x01\x02\x03" {
+		t.Errorf("GetFeedCredential = %+v, %v", cred, err)
+	}
+
+	err = s.DeleteFeedCredential(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("DeleteFeedCredential: %v", err)
+	}
+	_, err = s.GetFeedCredential(ctx, feed.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted credential: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testPosts(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "ada")
+	a := createFeed(t, s, user, "https://a.example.com/rss")
+	b := createFeed(t, s, user, "https://b.example.com/rss")
+	follow(t, s, user, a)
+
+	ts := now()
+	for i, feed := range []database.Feed{a, a, a, b} {
+		_, err := s.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts,
+			Title:           "post",
+			Url:             feed.Url + "/" + uuid.NewString(),
+			Description:     sql.NullString{String: "<p>Hi</p>", Valid: true},
+			PublishedAt:     sql.NullTime{Time: ts.Add(time.Duration(i) * time.Hour), Valid: true},
+			FeedID:          uuid.NullUUID{UUID: feed.ID, Valid: true},
+			Author:          sql.NullString{String: "Ada", Valid: i == 0},
+			DescriptionText: sql.NullString{String: "Hi", Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+	}
+
+	posts, err := s.GetLimitedPostsForUser(ctx, database.GetLimitedPostsForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("GetLimitedPostsForUser: %v", err)
+	}
+	// Only the followed feed's, oldest first
+	if len(posts) != 3 {
+		t.Fatalf("got %d posts, want 3", len(posts))
+	}
+	for i, post := range posts {
+		if post.FeedID.UUID != a.ID {
+			t.Errorf("post %d belongs to %s", i, post.FeedID.UUID)
+		}
+		if want := ts.Add(time.Duration(i) * time.Hour); !post.PublishedAt.Time.Equal(want) {
+			t.Errorf("post %d published at %v, want %v", i, post.PublishedAt.Time, want)
+		}
+	}
+	first := posts[0]
+	if first.Author.String != "Ada" || !first.Author.Valid || first.DescriptionText.String != "Hi" || first.Description.String != "<p>Hi</p>" {
+		t.Errorf("post = %+v", first)
+	}
+	if posts[1].Author.Valid {
+		t.Errorf("author = %+v, want NULL", posts[1].Author)
+	}
+	if first.Content.Valid {
+		t.Errorf("content = %+v, want NULL", first.Content)
+	}
+
+	// Content extracted from the article
+	err = s.SetPostContent(ctx, database.SetPostContentParams{
+		ID:        first.ID,
+		Content:   sql.NullString{String: "<p>Hello</p>", Valid: true},
+		UpdatedAt: ts,
+	})
+	if err != nil {
+		t.Fatalf("SetPostContent: %v", err)
+	}
+	if post, err := s.GetPost(ctx, first.ID); err != nil || post.Content != (sql.NullString{String: "<p>Hello</p>", Valid: true}) {
+		t.Errorf("GetPost = %+v, %v", post, err)
+	}
+
+	posts, _ = s.GetLimitedPostsForUser(ctx, database.GetLimitedPostsForUserParams{UserID: user.ID, Limit: 2})
+	if len(posts) != 2 {
+		t.Errorf("limit 2 returned %d posts", len(posts))
+	}
+
+	// Saved after a time
+	posts, err = s.GetNewPostsForUser(ctx, database.GetNewPostsForUserParams{UserID: user.ID, CreatedAt: ts.Add(-time.Second)})
+	if err != nil || len(posts) != 3 || !posts[0].PublishedAt.Time.Equal(ts) {
+		t.Errorf("GetNewPostsForUser = %+v, %v", posts, err)
+	}
+	if posts, _ := s.GetNewPostsForUser(ctx, database.GetNewPostsForUserParams{UserID: user.ID, CreatedAt: ts}); len(posts) != 0 {
+		t.Errorf("GetNewPostsForUser after the posts = %+v", posts)
+	}
+
+	// Followed or not
+	posts, err = s.GetPostsForFeed(ctx, b.ID)
+	if err != nil || len(posts) != 1 || posts[0].FeedID.UUID != b.ID {
+		t.Errorf("GetPostsForFeed = %+v, %v", posts, err)
+	}
+
+	_, err = s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Title: "dup", Url: first.Url, PublishedAt: sql.NullTime{Time: ts, Valid: true}})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("duplicate post URL: err = %v, want a unique violation", err)
+	}
+
+	// Posts go with their feeds, so the URL is free again
+	if err := s.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("DeleteAllUsers with posts: %v", err)
+	}
+	_, err = s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Title: "again", Url: first.Url, PublishedAt: sql.NullTime{Time: ts, Valid: true}})
+	if err != nil {
+		t.Errorf("the posts of deleted feeds are still there: %v", err)
+	}
+}
+
+func testSessions(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	ts := now()
+
+	session, err := s.CreateSession(ctx, database.CreateSessionParams{TokenHash: "live", UserID: ada.ID, CreatedAt: ts, ExpiresAt: ts.Add(time.Hour)})
+	if err != nil || session.UserID != ada.ID {
+		t.Fatalf("CreateSession = %+v, %v", session, err)
+	}
+	_, err = s.CreateSession(ctx, database.CreateSessionParams{TokenHash: "live", UserID: ada.ID, CreatedAt: ts, ExpiresAt: ts})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("duplicate token: err = %v, want a unique violation", err)
+	}
+	_, err = s.CreateSession(ctx, database.CreateSessionParams{TokenHash: "orphan", UserID: uuid.New(), CreatedAt: ts, ExpiresAt: ts})
+	if err == nil {
+		t.Errorf("a session for an unknown user should fail")
+	}
+
+	// Expiry is compared across time zones
+	local := time.FixedZone("UTC+2", 2*60*60)
+	_, err = s.CreateSession(ctx, database.CreateSessionParams{TokenHash: "old", UserID: ada.ID, CreatedAt: ts, ExpiresAt: ts.Add(-time.Minute).In(local)})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	err = s.DeleteExpiredSessions(ctx, ts)
+	if err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	if _, err := s.GetSession(ctx, "old"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expired session: err = %v, want sql.ErrNoRows", err)
+	}
+	got, err := s.GetSession(ctx, "live")
+	if err != nil || !got.ExpiresAt.Equal(ts.Add(time.Hour)) {
+		t.Errorf("GetSession = %+v, %v", got, err)
+	}
+
+	err = s.DeleteSession(ctx, "live")
+	if err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := s.GetSession(ctx, "live"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted session: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// Sessions go with their users
+	createSession := database.CreateSessionParams{TokenHash: "again", UserID: ada.ID, CreatedAt: ts, ExpiresAt: ts.Add(time.Hour)}
+	if _, err := s.CreateSession(ctx, createSession); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("DeleteAllUsers: %v", err)
+	}
+	if _, err := s.GetSession(ctx, "again"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("session of a deleted user: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testUserManagement(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	a := createFeed(t, s, ada, "https://a.example.com/rss")
+	b := createFeed(t, s, ada, "https://b.example.com/rss")
+	follow(t, s, ada, a)
+	follow(t, s, bob, a)
+
+	if n, err := s.CountFeedFollowers(ctx, a.ID); err != nil || n != 2 {
+		t.Errorf("CountFeedFollowers(a) = %d, %v", n, err)
+	}
+	if n, err := s.CountFeedFollowers(ctx, b.ID); err != nil || n != 0 {
+		t.Errorf("CountFeedFollowers(b) = %d, %v", n, err)
+	}
+
+	// Rename keeps names unique
+	later := now().Add(time.Minute)
+	err := s.RenameUser(ctx, database.RenameUserParams{ID: bob.ID, Name: "ada", UpdatedAt: later})
+	if !storage.IsUniqueViolation(err) {
+		t.Errorf("renaming to a taken name: err = %v, want a unique violation", err)
+	}
+	err = s.RenameUser(ctx, database.RenameUserParams{ID: bob.ID, Name: "robert", UpdatedAt: later})
+	if err != nil {
+		t.Fatalf("RenameUser: %v", err)
+	}
+	if got, err := s.GetUserById(ctx, bob.ID); err != nil || got.Name != "robert" || !got.UpdatedAt.Equal(later) {
+		t.Errorf("after RenameUser = %+v, %v", got, err)
+	}
+
+	// Deactivation
+	if bob.DeactivatedAt.Valid {
+		t.Errorf("new user deactivated at %v", bob.DeactivatedAt)
+	}
+	err = s.SetUserDeactivated(ctx, database.SetUserDeactivatedParams{ID: bob.ID, DeactivatedAt: sql.NullTime{Time: later, Valid: true}, UpdatedAt: later})
+	if err != nil {
+		t.Fatalf("SetUserDeactivated: %v", err)
+	}
+	if got, _ := s.GetUserById(ctx, bob.ID); !got.DeactivatedAt.Time.Equal(later) {
+		t.Errorf("deactivated at = %+v", got.DeactivatedAt)
+	}
+	s.SetUserDeactivated(ctx, database.SetUserDeactivatedParams{ID: bob.ID, UpdatedAt: later})
+	if got, _ := s.GetUserById(ctx, bob.ID); got.DeactivatedAt.Valid {
+		t.Errorf("reactivated user deactivated at = %+v", got.DeactivatedAt)
+	}
+
+	// Sessions of one user
+	for _, session := range []database.CreateSessionParams{
+		{TokenHash: "ada1", UserID: ada.ID, CreatedAt: now(), ExpiresAt: later},
+		{TokenHash: "ada2", UserID: ada.ID, CreatedAt: now(), ExpiresAt: later},
+		{TokenHash: "bob", UserID: bob.ID, CreatedAt: now(), ExpiresAt: later},
+	} {
+		if _, err := s.CreateSession(ctx, session); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+	if err := s.DeleteSessionsForUser(ctx, ada.ID); err != nil {
+		t.Fatalf("DeleteSessionsForUser: %v", err)
+	}
+	if _, err := s.GetSession(ctx, "ada2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ada's session is still there: %v", err)
+	}
+	if _, err := s.GetSession(ctx, "bob"); err != nil {
+		t.Errorf("bob's session went too: %v", err)
+	}
+
+	// Feed transfers, only to existing users
+	if _, err := s.TransferFeeds(ctx, database.TransferFeedsParams{ToUserID: uuid.New(), UpdatedAt: later, FromUserID: ada.ID}); err == nil {
+		t.Errorf("transferring feeds to an unknown user should fail")
+	}
+	n, err := s.TransferFeeds(ctx, database.TransferFeedsParams{ToUserID: bob.ID, UpdatedAt: later, FromUserID: ada.ID})
+	if err != nil || n != 2 {
+		t.Fatalf("TransferFeeds = %d, %v", n, err)
+	}
+	feed, _ := s.GetFeedByUrl(ctx, a.Url)
+	if feed.UserID != bob.ID || !feed.UpdatedAt.Equal(later) {
+		t.Errorf("transferred feed = %+v", feed)
+	}
+
+	// Deleting ada leaves bob's feeds, drops her follows
+	if err := s.DeleteUser(ctx, ada.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.GetUserById(ctx, ada.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted user: err = %v, want sql.ErrNoRows", err)
+	}
+	if n, _ := s.CountFeedFollowers(ctx, a.ID); n != 1 {
+		t.Errorf("followers after deleting ada = %d, want 1", n)
+	}
+
+	// Deleting bob takes his feeds with him
+	if err := s.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if feeds, _ := s.GetFeeds(ctx); len(feeds) != 0 {
+		t.Errorf("feeds of a deleted user: %+v", feeds)
+	}
+}
+
+func testOrphanedFeeds(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	a := createFeed(t, s, ada, "https://a.example.com/rss")
+	b := createFeed(t, s, ada, "https://b.example.com/rss")
+	follow(t, s, ada, a)
+
+	ts := now()
+	at := func(when time.Time) sql.NullTime { return sql.NullTime{Time: when, Valid: true} }
+	n, err := s.MarkOrphanedFeeds(ctx, at(ts))
+	if err != nil || n != 1 {
+		t.Fatalf("MarkOrphanedFeeds = %d, %v, want 1", n, err)
+	}
+	// The first time counts
+	n, _ = s.MarkOrphanedFeeds(ctx, at(ts.Add(time.Hour)))
+	if n != 0 {
+		t.Errorf("MarkOrphanedFeeds again = %d, want 0", n)
+	}
+
+	feeds, err := s.GetOrphanedFeeds(ctx, at(ts.Add(time.Minute)))
+	if err != nil || len(feeds) != 1 || feeds[0].ID != b.ID || !feeds[0].OrphanedAt.Time.Equal(ts) {
+		t.Errorf("GetOrphanedFeeds = %+v, %v", feeds, err)
+	}
+	if feeds, _ := s.GetOrphanedFeeds(ctx, at(ts)); len(feeds) != 0 {
+		t.Errorf("orphaned at the limit: %+v", feeds)
+	}
+
+	// Following it again clears it
+	follow(t, s, ada, b)
+	if err := s.ClearOrphanedFeeds(ctx); err != nil {
+		t.Fatalf("ClearOrphanedFeeds: %v", err)
+	}
+	if got, _ := s.GetFeedByUrl(ctx, b.Url); got.OrphanedAt.Valid {
+		t.Errorf("followed feed still orphaned at %v", got.OrphanedAt.Time)
+	}
+	if feeds, _ := s.GetOrphanedFeeds(ctx, at(ts.Add(time.Hour))); len(feeds) != 0 {
+		t.Errorf("orphaned feeds after following: %+v", feeds)
+	}
+}
+
+func testSavedPosts(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	feed := createFeed(t, s, ada, "https://a.example.com/rss")
+
+	ts := now()
+	var posts []database.Post
+	for i := range 3 {
+		post, err := s.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts,
+			Title:       "post",
+			Url:         feed.Url + "/" + uuid.NewString(),
+			PublishedAt: sql.NullTime{Time: ts.Add(time.Duration(i) * time.Hour), Valid: true},
+			FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	got, err := s.GetPost(ctx, posts[1].ID)
+	if err != nil || got.Url != posts[1].Url {
+		t.Errorf("GetPost = %+v, %v", got, err)
+	}
+	if _, err := s.GetPost(ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown post: err = %v, want sql.ErrNoRows", err)
+	}
+
+	save := func(user database.User, post database.Post, at time.Time) {
+		t.Helper()
+		err := s.SavePost(ctx, database.SavePostParams{UserID: user.ID, PostID: post.ID, CreatedAt: at})
+		if err != nil {
+			t.Fatalf("SavePost: %v", err)
+		}
+	}
+	save(ada, posts[0], ts)
+	save(ada, posts[2], ts.Add(time.Minute))
+	// Saving twice is not an error
+	save(ada, posts[0], ts)
+	save(bob, posts[1], ts)
+	if err := s.SavePost(ctx, database.SavePostParams{UserID: ada.ID, PostID: uuid.New(), CreatedAt: ts}); err == nil {
+		t.Errorf("saving an unknown post should fail")
+	}
+
+	saved, err := s.GetSavedPostsForUser(ctx, ada.ID)
+	if err != nil || len(saved) != 2 || saved[0].ID != posts[2].ID || saved[1].ID != posts[0].ID {
+		t.Errorf("GetSavedPostsForUser = %+v, %v, want the last saved first", saved, err)
+	}
+
+	// Saved by anyone is kept
+	prunable, err := s.GetPrunablePostsForFeed(ctx, feed.ID)
+	if err != nil || len(prunable) != 0 {
+		t.Errorf("GetPrunablePostsForFeed = %+v, %v, want none", prunable, err)
+	}
+	if err := s.UnsavePost(ctx, database.UnsavePostParams{UserID: ada.ID, PostID: posts[0].ID}); err != nil {
+		t.Fatalf("UnsavePost: %v", err)
+	}
+	if err := s.UnsavePost(ctx, database.UnsavePostParams{UserID: ada.ID, PostID: posts[0].ID}); err != nil {
+		t.Errorf("UnsavePost twice: %v", err)
+	}
+	save(ada, posts[1], ts)
+	if err := s.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	prunable, _ = s.GetPrunablePostsForFeed(ctx, feed.ID)
+	if len(prunable) != 1 || prunable[0].ID != posts[0].ID {
+		t.Errorf("prunable posts = %+v, want the unsaved one", prunable)
+	}
+
+	// Deleting a post takes its saves along
+	if err := s.DeletePost(ctx, posts[2].ID); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if _, err := s.GetPost(ctx, posts[2].ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted post: err = %v, want sql.ErrNoRows", err)
+	}
+	saved, _ = s.GetSavedPostsForUser(ctx, ada.ID)
+	if len(saved) != 1 || saved[0].ID != posts[1].ID {
+		t.Errorf("saved after deleting a post = %+v", saved)
+	}
+
+	// Newest first
+	if err := s.UnsavePost(ctx, database.UnsavePostParams{UserID: ada.ID, PostID: posts[1].ID}); err != nil {
+		t.Fatalf("UnsavePost: %v", err)
+	}
+	prunable, _ = s.GetPrunablePostsForFeed(ctx, feed.ID)
+	if len(prunable) != 2 || prunable[0].ID != posts[1].ID || prunable[1].ID != posts[0].ID {
+		t.Errorf("prunable posts = %+v, want the newest first", prunable)
+	}
+
+	// Retention of the feed
+	err = s.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		ID:        feed.ID,
+		KeepPosts: sql.NullInt32{Int32: 10, Valid: true},
+		UpdatedAt: ts,
+	})
+	if err != nil {
+		t.Fatalf("SetFeedRetention: %v", err)
+	}
+	feed, _ = s.GetFeedByUrl(ctx, feed.Url)
+	if feed.KeepPosts != (sql.NullInt32{Int32: 10, Valid: true}) || feed.KeepDays.Valid {
+		t.Errorf("retention = %+v, %+v", feed.KeepPosts, feed.KeepDays)
+	}
+
+	// Readability of the feed
+	if feed.Readability {
+		t.Errorf("readability is on by default")
+	}
+	err = s.SetFeedReadability(ctx, database.SetFeedReadabilityParams{ID: feed.ID, Readability: true, UpdatedAt: ts})
+	if err != nil {
+		t.Fatalf("SetFeedReadability: %v", err)
+	}
+	if feed, _ = s.GetFeedByUrl(ctx, feed.Url); !feed.Readability {
+		t.Errorf("readability = false after SetFeedReadability")
+	}
+}
+
+func testSettings(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	if _, err := s.GetSetting(ctx, "a"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unknown setting: err = %v, want sql.ErrNoRows", err)
+	}
+
+	ts := now()
+	for _, value := range []string{"1", "2"} {
+		if err := s.SetSetting(ctx, database.SetSettingParams{Key: "a", Value: value, UpdatedAt: ts}); err != nil {
+			t.Fatalf("SetSetting: %v", err)
+		}
+	}
+	setting, err := s.GetSetting(ctx, "a")
+	if err != nil || setting.Value != "2" || !setting.UpdatedAt.Equal(ts) {
+		t.Errorf("GetSetting = %+v, %v", setting, err)
+	}
+
+	if err := s.DeleteSetting(ctx, "a"); err != nil {
+		t.Fatalf("DeleteSetting: %v", err)
+	}
+	if _, err := s.GetSetting(ctx, "a"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted setting: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testPostReads(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	feed := createFeed(t, s, ada, "https://a.example.com/rss")
+
+	ts := now()
+	post, err := s.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), CreatedAt: ts, UpdatedAt: ts, Title: "post", Url: feed.Url + "/1", PublishedAt: sql.NullTime{Time: ts, Valid: true}, FeedID: uuid.NullUUID{UUID: feed.ID, Valid: true}})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	// Reading twice keeps the first time
+	for _, at := range []time.Time{ts, ts.Add(time.Hour)} {
+		if err := s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: ada.ID, PostID: post.ID, ReadAt: at}); err != nil {
+			t.Fatalf("MarkPostRead: %v", err)
+		}
+	}
+	if err := s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: ada.ID, PostID: uuid.New(), ReadAt: ts}); err == nil {
+		t.Errorf("reading an unknown post should fail")
+	}
+
+	reads, err := s.GetPostReadsForUser(ctx, ada.ID)
+	if err != nil || len(reads) != 1 || reads[0].PostID != post.ID || !reads[0].ReadAt.Equal(ts) {
+		t.Errorf("GetPostReadsForUser = %+v, %v", reads, err)
+	}
+	if reads, _ := s.GetPostReadsForUser(ctx, bob.ID); len(reads) != 0 {
+		t.Errorf("bob read %+v", reads)
+	}
+
+	if err := s.DeletePost(ctx, post.ID); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if reads, _ := s.GetPostReadsForUser(ctx, ada.ID); len(reads) != 0 {
+		t.Errorf("reads of a deleted post: %+v", reads)
+	}
+}
+
+func testFilterRules(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	feed := createFeed(t, s, ada, "https://a.example.com/rss")
+
+	ts := now()
+	create := func(user database.User, feedID uuid.NullUUID, at time.Time, action string) database.FilterRule {
+		t.Helper()
+		rule, err := s.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: at,
+			UserID:    user.ID,
+			FeedID:    feedID,
+			Field:     "title",
+			Match:     "contains",
+			Pattern:   "sponsored",
+			Action:    action,
+			Tag:       sql.NullString{String: "ads", Valid: action == "tag"},
+		})
+		if err != nil {
+			t.Fatalf("CreateFilterRule: %v", err)
+		}
+		return rule
+	}
+	scoped := create(ada, uuid.NullUUID{UUID: feed.ID, Valid: true}, ts.Add(time.Minute), "tag")
+	global := create(ada, uuid.NullUUID{}, ts, "hide")
+	create(bob, uuid.NullUUID{}, ts, "hide")
+
+	if scoped.Tag.String != "ads" || scoped.FeedID.UUID != feed.ID || scoped.Pattern != "sponsored" {
+		t.Errorf("CreateFilterRule = %+v", scoped)
+	}
+	rules, err := s.GetFilterRulesForUser(ctx, ada.ID)
+	if err != nil || len(rules) != 2 || rules[0].ID != global.ID || rules[1].ID != scoped.ID {
+		t.Errorf("GetFilterRulesForUser = %+v, %v, want the oldest first", rules, err)
+	}
+
+	// Only the owner deletes a rule
+	n, err := s.DeleteFilterRule(ctx, database.DeleteFilterRuleParams{ID: global.ID, UserID: bob.ID})
+	if err != nil || n != 0 {
+		t.Errorf("DeleteFilterRule as bob = %d, %v", n, err)
+	}
+	n, err = s.DeleteFilterRule(ctx, database.DeleteFilterRuleParams{ID: global.ID, UserID: ada.ID})
+	if err != nil || n != 1 {
+		t.Errorf("DeleteFilterRule = %d, %v", n, err)
+	}
+
+	// Rules of a feed go with it
+	if err := s.DeleteFeed(ctx, feed.ID); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	if rules, _ := s.GetFilterRulesForUser(ctx, ada.ID); len(rules) != 0 {
+		t.Errorf("rules after deleting their feed: %+v", rules)
+	}
+	if rules, _ := s.GetFilterRulesForUser(ctx, bob.ID); len(rules) != 1 {
+		t.Errorf("bob's rules = %+v", rules)
+	}
+}
+
+func testAlerts(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+	feedA := createFeed(t, s, ada, "https://a.example.com/rss")
+	feedB := createFeed(t, s, ada, "https://b.example.com/rss")
+
+	ts := now()
+	create := func(user database.User, feedID uuid.NullUUID, at time.Time) database.AlertRule {
+		t.Helper()
+		rule, err := s.CreateAlertRule(ctx, database.CreateAlertRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: at,
+			UserID:    user.ID,
+			FeedID:    feedID,
+			Field:     "title",
+			Match:     "regex",
+			Pattern:   "(?i)release",
+			Kind:      "webhook",
+			Target:    "https://hooks.example.com/" + user.Name,
+		})
+		if err != nil {
+			t.Fatalf("CreateAlertRule: %v", err)
+		}
+		return rule
+	}
+	scoped := create(ada, uuid.NullUUID{UUID: feedB.ID, Valid: true}, ts.Add(time.Minute))
+	global := create(ada, uuid.NullUUID{}, ts)
+	bobs := create(bob, uuid.NullUUID{}, ts)
+
+	if scoped.Target != "https://hooks.example.com/ada" || scoped.FeedID.UUID != feedB.ID || scoped.Kind != "webhook" {
+		t.Errorf("CreateAlertRule = %+v", scoped)
+	}
+	rules, err := s.GetAlertRulesForUser(ctx, ada.ID)
+	if err != nil || len(rules) != 2 || rules[0].ID != global.ID || rules[1].ID != scoped.ID {
+		t.Errorf("GetAlertRulesForUser = %+v, %v, want the oldest first", rules, err)
+	}
+
+	// Only the rules of the followers, for every feed or that one
+	follow(t, s, ada, feedA)
+	follow(t, s, ada, feedB)
+	rules, err = s.GetAlertRulesForFeed(ctx, feedA.ID)
+	if err != nil || len(rules) != 1 || rules[0].ID != global.ID {
+		t.Errorf("GetAlertRulesForFeed(A) = %+v, %v", rules, err)
+	}
+	rules, err = s.GetAlertRulesForFeed(ctx, feedB.ID)
+	if err != nil || len(rules) != 2 || rules[0].ID != global.ID || rules[1].ID != scoped.ID {
+		t.Errorf("GetAlertRulesForFeed(B) = %+v, %v", rules, err)
+	}
+
+	// Deliveries, newest first
+	for i, status := range []string{"sent", "failed", "sent"} {
+		n := strconv.Itoa(i)
+		_, err := s.CreateAlertDelivery(ctx, database.CreateAlertDeliveryParams{
+			ID:        uuid.New(),
+			CreatedAt: ts.Add(time.Duration(i) * time.Second),
+			RuleID:    global.ID,
+			PostTitle: "Release " + n,
+			PostUrl:   "https://a.example.com/" + n,
+			Status:    status,
+			Attempts:  int32(i + 1),
+			Error:     sql.NullString{String: "500 Internal Server Error", Valid: status == "failed"},
+		})
+		if err != nil {
+			t.Fatalf("CreateAlertDelivery: %v", err)
+		}
+	}
+	_, err = s.CreateAlertDelivery(ctx, database.CreateAlertDeliveryParams{
+		ID: uuid.New(), CreatedAt: ts, RuleID: bobs.ID, PostTitle: "Release", PostUrl: "https://b.example.com/0", Status: "sent", Attempts: 1,
+	})
+	if err != nil {
+		t.Fatalf("CreateAlertDelivery: %v", err)
+	}
+	deliveries, err := s.GetAlertDeliveriesForUser(ctx, database.GetAlertDeliveriesForUserParams{UserID: ada.ID, Limit: 2})
+	if err != nil || len(deliveries) != 2 || deliveries[0].PostTitle != "Release 2" || deliveries[1].Error.String != "500 Internal Server Error" || deliveries[1].Attempts != 2 {
+		t.Errorf("GetAlertDeliveriesForUser = %+v, %v", deliveries, err)
+	}
+
+	// Only the owner deletes a rule, its deliveries go with it
+	n, err := s.DeleteAlertRule(ctx, database.DeleteAlertRuleParams{ID: global.ID, UserID: bob.ID})
+	if err != nil || n != 0 {
+		t.Errorf("DeleteAlertRule as bob = %d, %v", n, err)
+	}
+	n, err = s.DeleteAlertRule(ctx, database.DeleteAlertRuleParams{ID: global.ID, UserID: ada.ID})
+	if err != nil || n != 1 {
+		t.Errorf("DeleteAlertRule = %d, %v", n, err)
+	}
+	if deliveries, _ := s.GetAlertDeliveriesForUser(ctx, database.GetAlertDeliveriesForUserParams{UserID: ada.ID, Limit: 10}); len(deliveries) != 0 {
+		t.Errorf("deliveries after deleting their rule: %+v", deliveries)
+	}
+
+	// Rules of a feed go with it
+	if err := s.DeleteFeed(ctx, feedB.ID); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	if rules, _ := s.GetAlertRulesForUser(ctx, ada.ID); len(rules) != 0 {
+		t.Errorf("rules after deleting their feed: %+v", rules)
+	}
+	if rules, _ := s.GetAlertRulesForUser(ctx, bob.ID); len(rules) != 1 {
+		t.Errorf("bob's rules = %+v", rules)
+	}
+}
+
+func testDigests(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+	bob := createUser(t, s, "bob")
+
+	if _, err := s.GetLastDigest(ctx, ada.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("no digest yet: err = %v, want sql.ErrNoRows", err)
+	}
+
+	ts := now()
+	for _, at := range []time.Time{ts, ts.Add(time.Hour)} {
+		if err := s.SetLastDigest(ctx, database.SetLastDigestParams{UserID: ada.ID, SentAt: at}); err != nil {
+			t.Fatalf("SetLastDigest: %v", err)
+		}
+	}
+	digest, err := s.GetLastDigest(ctx, ada.ID)
+	if err != nil || !digest.SentAt.Equal(ts.Add(time.Hour)) {
+		t.Errorf("GetLastDigest = %+v, %v", digest, err)
+	}
+	if _, err := s.GetLastDigest(ctx, bob.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("bob's digest: err = %v, want sql.ErrNoRows", err)
+	}
+	if err := s.SetLastDigest(ctx, database.SetLastDigestParams{UserID: uuid.New(), SentAt: ts}); err == nil {
+		t.Errorf("the digest of an unknown user should fail")
+	}
+
+	if err := s.DeleteUser(ctx, ada.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.GetLastDigest(ctx, ada.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("digest of a deleted user: err = %v", err)
+	}
+}
+
+func testTransactions(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	ada := createUser(t, s, "ada")
+
+	// A failed transaction leaves nothing behind
+	failed := errors.New("failed")
+	err := storage.InTx(ctx, s, func(tx storage.Store) error {
+		feed := createFeed(t, tx, ada, "https://a.example.com/rss")
+		follow(t, tx, ada, feed)
+		if err := tx.DeleteUser(ctx, ada.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("InTx = %v, want the error of f", err)
+	}
+	if _, err := s.GetUserById(ctx, ada.ID); err != nil {
+		t.Errorf("the deleted user is gone after a rollback: %v", err)
+	}
+	if _, err := s.GetFeedByUrl(ctx, "https://a.example.com/rss"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("the feed is there after a rollback: %v", err)
+	}
+
+	// A successful one keeps everything
+	err = storage.InTx(ctx, s, func(tx storage.Store) error {
+		feed := createFeed(t, tx, ada, "https://b.example.com/rss")
+		follow(t, tx, ada, feed)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	if follows, err := s.GetFeedFollowsForUser(ctx, ada.ID); err != nil || len(follows) != 1 {
+		t.Errorf("follows after a commit = %+v, %v", follows, err)
+	}
+}
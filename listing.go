@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/neixir/gator/internal/output"
+)
+
+// printList prints list in the format asked for with --output and --fields.
+// Without them, text prints the command's usual output.
+func printList(cmd command, list *output.List, text func()) error {
+	format, _ := cmd.flag("output").(output.Format)
+	fields, _ := cmd.flag("fields").(string)
+	if format == output.Text && fields == "" {
+		text()
+		return nil
+	}
+
+	// Picking fields only makes sense with columns
+	if format == output.Text {
+		format = output.Table
+	}
+	if fields != "" {
+		var err error
+		names := strings.Split(fields, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		list, err = list.Select(names)
+		if err != nil {
+			return err
+		}
+	}
+
+	return list.Write(os.Stdout, format)
+}
@@ -16,140 +16,404 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
 
 	"github.com/neixir/gator/internal/config"
 	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/migrate"
+	"github.com/neixir/gator/internal/output"
 	"github.com/neixir/gator/internal/rss"
-
-	_ "github.com/lib/pq"
+	"github.com/neixir/gator/internal/sanitize"
+	"github.com/neixir/gator/internal/storage"
 )
 
 type state struct {
-	db  *database.Queries
+	// Postgres or SQLite, depending on db_url
+	db  storage.Store
 	cfg *config.Config
+	// The connection behind db, nil for the in-memory store
+	conn *storage.Conn
+}
+
+// inTx runs f with a copy of s whose queries share one transaction, so
+// either all of their changes are kept or none.
+func inTx(s *state, f func(tx *state) error) error {
+	return storage.InTx(context.Background(), s.db, func(db storage.Store) error {
+		tx := *s
+		tx.db = db
+		return f(&tx)
+	})
 }
 
 // CH1 L3
 // A command contains a name and a slice of string arguments.
 // For example, in the case of the login command, the name would be "login"
 // and the handler will expect the arguments slice to contain one string, the username.
+// The arguments are already checked against the spec of the command, flags removed.
 type command struct {
-	name string
-	args []string
+	name  string
+	args  []string
+	flags *flag.FlagSet
+}
+
+// flag returns the value of one of the flags of the command, nil if it
+// doesn't have it.
+func (c command) flag(name string) any {
+	if c.flags == nil {
+		return nil
+	}
+	f := c.flags.Lookup(name)
+	if f == nil {
+		return nil
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return nil
+	}
+
+	return getter.Get()
 }
 
 // CH1 L3
 // Create a commands struct. This will hold all the commands the CLI can handle.
 type commands struct {
-	// This will be a map of command names to their handler functions.
-	callback map[string]func(*state, command) error
+	// In the order help lists them
+	list []*commandSpec
 }
 
 // CH1 L3
 // This method runs a given command with the provided state if it exists.
 func (c *commands) run(s *state, cmd command) error {
-	_, ok := c.callback[cmd.name]
-	if ok {
-		err := c.callback[cmd.name](s, cmd)
-		if err != nil {
-			return err
-		}
-
+	spec, parsed, err := c.lookup(append([]string{cmd.name}, cmd.args...))
+	if errors.Is(err, flag.ErrHelp) && spec == nil {
+		c.printHelp()
 		return nil
 	}
+	if errors.Is(err, flag.ErrHelp) {
+		spec.printHelp()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-	return fmt.Errorf("command not found")
-
-}
-
-// CH1 L3
-// This method registers a new handler function for a command name.
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.callback[name] = f
+	return spec.handler(s, parsed)
 }
 
 // CH1 L3
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("missing argument <username>")
-	}
-
 	username := cmd.args[0]
 
 	// CH2 L3
 	// Update the login command handler to error (and exit with code 1) if the given username doesn't exist in the database.
-	_, err := s.db.GetUser(context.Background(), username)
+	user, err := s.db.GetUser(context.Background(), username)
 	if err != nil {
 		return fmt.Errorf("the user does not exist. %v", err)
 	}
 
-	err = s.cfg.SetUser(username)
+	err = authenticate(user)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("User %s set.\n", username)
+	err = startSession(s, user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged in as %s.\n", username)
 
 	return nil
 }
 
 // CH2 L3
 func handlerRegister(s *state, cmd command) error {
-	// Ensure that a name was passed in the args.
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("missing argument <username>")
+	// The spec of the command ensures that a name was passed in the args.
+	username := cmd.args[0]
+
+	// Before asking for a password
+	_, err := s.db.GetUser(context.Background(), username)
+	if err == nil {
+		return fmt.Errorf("the user %s already exists", username)
 	}
 
-	username := cmd.args[0]
+	passwordHash, err := promptNewPassword()
+	if err != nil {
+		return err
+	}
+
+	// The first user of a database is its admin
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("getting user list. %v", err)
+	}
 
 	// Create a new user in the database.
 	// It should have access to the CreateUser query through the state -> db struct.
 	arg := database.CreateUserParams{
-		ID:        uuid.New(), // Use the uuid.New() function to generate a new UUID for the user.
-		CreatedAt: time.Now(), // created_at and updated_at should be the current time.
-		UpdatedAt: time.Now(),
-		Name:      username, // Use the provided name.
+		ID:           uuid.New(), // Use the uuid.New() function to generate a new UUID for the user.
+		CreatedAt:    time.Now(), // created_at and updated_at should be the current time.
+		UpdatedAt:    time.Now(),
+		Name:         username, // Use the provided name.
+		PasswordHash: passwordHash,
+		IsAdmin:      len(users) == 0,
 	}
 
 	// Pass context.Background() to the query to create an empty Context argument.
 	newUser, err := s.db.CreateUser(context.Background(), arg)
 	if err != nil {
 		// Exit with code 1 if a user with that name already exists.
-		// TODO Potser millor abans de crear fer GetUser?
 		return fmt.Errorf("creating user. %v", err)
 	}
 
-	// Set the current user in the config to the given name.
-	err = s.cfg.SetUser(username)
+	// Log in as the new user
+	err = startSession(s, newUser)
 	if err != nil {
-		return fmt.Errorf("setting user. %v", err)
+		return err
 	}
 
-	// Print a message that the user was created, and log the user's data to the console for your own debugging.
 	fmt.Printf("Created new user %s.\n", username)
-	fmt.Println(newUser)
+	if newUser.IsAdmin {
+		fmt.Println("It's the first user, so it's an admin.")
+	}
 
 	return nil
 }
 
 // CH2 L4
-func handlerReset(s *state, cmd command) error {
+// Admins only. Asks first (unless --yes) and backs the database up.
+func handlerReset(s *state, cmd command, user database.User) error {
+	yes, _ := cmd.flag("yes").(bool)
+	if !yes {
+		fmt.Println("This deletes every user with their feeds, follows and posts.")
+		answer, err := promptLine(`Type "reset" to continue`)
+		if err != nil {
+			return fmt.Errorf("%v. Pass --yes to reset without asking", err)
+		}
+		if answer != "reset" {
+			return errors.New("reset cancelled")
+		}
+	}
+
+	noBackup, _ := cmd.flag("no-backup").(bool)
+	if !noBackup {
+		path, err := backup(s, cmd)
+		if err != nil {
+			return fmt.Errorf("%v. Nothing was deleted, pass --no-backup to reset anyway", err)
+		}
+		if path != "" {
+			fmt.Printf("Backed up the database to %s.\n", path)
+		}
+	}
+
 	err := s.db.DeleteAllUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("resetting users table. %v", err)
 	}
 
+	// The sessions went with the users
+	err = s.cfg.SetSession("", "")
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("Users table has been reset.")
 	return nil
 }
 
+// backup copies the database to --backup-dir, by default gator-backups next
+// to the config file. The in-memory store has nothing to back up, the path
+// is empty then.
+func backup(s *state, cmd command) (string, error) {
+	if s.conn == nil {
+		return "", nil
+	}
+
+	dir, _ := cmd.flag("backup-dir").(string)
+	if dir == "" {
+		path, err := config.Path()
+		if err != nil {
+			return "", fmt.Errorf("getting config file path. %v", err)
+		}
+		dir = filepath.Join(filepath.Dir(path), "gator-backups")
+	}
+
+	return s.conn.Backup(context.Background(), dir)
+}
+
+// admin grant <username>
+func handlerAdminGrant(s *state, cmd command, user database.User) error {
+	return setAdmin(s, cmd.args[0], true)
+}
+
+// admin revoke <username>
+// There is always an admin left, or nobody could give it back.
+func handlerAdminRevoke(s *state, cmd command, user database.User) error {
+	target, err := s.db.GetUser(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("the user does not exist. %v", err)
+	}
+	err = checkNotLastAdmin(s, target)
+	if err != nil {
+		return err
+	}
+
+	return setAdmin(s, cmd.args[0], false)
+}
+
+func setAdmin(s *state, username string, isAdmin bool) error {
+	target, err := s.db.GetUser(context.Background(), username)
+	if err != nil {
+		return fmt.Errorf("the user does not exist. %v", err)
+	}
+
+	err = s.db.SetUserAdmin(context.Background(), database.SetUserAdminParams{
+		ID:        target.ID,
+		IsAdmin:   isAdmin,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("updating user. %v", err)
+	}
+
+	if isAdmin {
+		fmt.Printf("%s is an admin.\n", target.Name)
+	} else {
+		fmt.Printf("%s is not an admin anymore.\n", target.Name)
+	}
+
+	return nil
+}
+
+// newMigrator returns the migrator of the database in s.
+func newMigrator(s *state) (*migrate.Migrator, error) {
+	if s.conn == nil {
+		return nil, fmt.Errorf("this database has no migrations")
+	}
+
+	return migrate.New(s.conn.DB, s.conn.Driver)
+}
+
+// migrate up
+func handlerMigrateUp(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	results, err := m.Up(context.Background())
+	return printMigrationResults(m, results, err)
+}
+
+// migrate down
+func handlerMigrateDown(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	var results []*goose.MigrationResult
+	result, err := m.Down(context.Background())
+	if result != nil {
+		results = append(results, result)
+	}
+	return printMigrationResults(m, results, err)
+}
+
+// migrate to <version>
+func handlerMigrateTo(s *state, cmd command) error {
+	version, err := strconv.ParseInt(cmd.args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version %q", cmd.args[0])
+	}
+
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	results, err := m.To(context.Background(), version)
+	return printMigrationResults(m, results, err)
+}
+
+// migrate status
+func handlerMigrateStatus(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	return printMigrationStatus(context.Background(), m, cmd)
+}
+
+// printMigrationResults prints the migrations that ran, then the error that
+// stopped them or the schema version.
+func printMigrationResults(m *migrate.Migrator, results []*goose.MigrationResult, err error) error {
+	for _, result := range results {
+		fmt.Printf("* %s %s (%s)\n", result.Direction, result.Source.Path, result.Duration.Round(time.Millisecond))
+	}
+	if err != nil {
+		return fmt.Errorf("migrating. %v", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("Nothing to do.")
+	}
+
+	version, err := m.Version(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema at version %d of %d.\n", version, m.Latest())
+
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator, cmd command) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("getting migration status. %v", err)
+	}
+
+	list := output.NewList("version", "path", "applied", "applied_at")
+	for _, status := range statuses {
+		appliedAt := sql.NullTime{Time: status.AppliedAt, Valid: status.State == goose.StateApplied}
+		list.Add(status.Source.Version, status.Source.Path, appliedAt.Valid, appliedAt)
+	}
+
+	return printList(cmd, list, func() {
+		for _, status := range statuses {
+			applied := "pending"
+			if status.State == goose.StateApplied {
+				applied = "applied " + status.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Printf("* %s -- %s\n", status.Source.Path, applied)
+		}
+	})
+}
+
+// checkSchema refuses to run commands on a database with pending migrations.
+func checkSchema(s *state) error {
+	if s.conn == nil {
+		return nil
+	}
+
+	m, err := migrate.New(s.conn.DB, s.conn.Driver)
+	if err != nil {
+		return err
+	}
+
+	return m.Check(context.Background())
+}
+
 // CH2 L5
 func handlerUsers(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
@@ -157,55 +421,101 @@ func handlerUsers(s *state, cmd command) error {
 		return fmt.Errorf("getting user list. %v", err)
 	}
 
+	list := output.NewList("id", "name", "current", "admin", "created_at", "deactivated_at")
 	for _, user := range users {
-		username := user.Name
-		if s.cfg.CurrentUserName == username {
-			username = fmt.Sprintf("%s (current)", username)
-		}
-		fmt.Printf("* %s\n", username)
+		list.Add(user.ID, user.Name, user.Name == s.cfg.CurrentUserName, user.IsAdmin, user.CreatedAt, user.DeactivatedAt)
 	}
 
-	return nil
+	return printList(cmd, list, func() {
+		for _, user := range users {
+			username := user.Name
+			if s.cfg.CurrentUserName == username {
+				username = fmt.Sprintf("%s (current)", username)
+			}
+			if user.DeactivatedAt.Valid {
+				username = fmt.Sprintf("%s (deactivated)", username)
+			}
+			fmt.Printf("* %s\n", username)
+		}
+	})
 }
 
 // CH3 L1 + CH5 L1
+// agg <time_between_reqs> [--dry-run] [--prune-every 1h] [--archive-dir <dir>]
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("missing arguments <time_between_reqs>")
-	}
+	dryRun, _ := cmd.flag("dry-run").(bool)
+	pruneEvery, _ := cmd.flag("prune-every").(time.Duration)
+	archiveDir, _ := cmd.flag("archive-dir").(string)
 
 	// time_between_reqs is a duration string, like 1s, 1m, 1h, etc.
 	// https://pkg.go.dev/time#ParseDuration
 	time_between_reqs := cmd.args[0]
-	timeBetweenRequests, err := time.ParseDuration(cmd.args[0])
+	timeBetweenRequests, err := time.ParseDuration(time_between_reqs)
 	if err != nil {
 		// time: unknown unit "x" in duration "10x"
 		return err
 	}
 
+	// A dry run scrapes a copy of the feeds kept in memory, so nothing is written
+	if dryRun {
+		db, err := newDryRunStore(s.db)
+		if err != nil {
+			return err
+		}
+		s = &state{db: db, cfg: s.cfg}
+		fmt.Println("Dry run: feeds are fetched and parsed but nothing is saved.")
+	}
+
 	fmt.Printf("Collecting feeds every %s\n", time_between_reqs)
 
 	// Use a time.Ticker to run your scrapeFeeds function once every time_between_reqs.
 	// I used a for loop to ensure that it runs immediately and then every time the ticker ticks:
 	ticker := time.NewTicker(timeBetweenRequests)
+	var lastPrune time.Time
 	for ; ; <-ticker.C {
 		fmt.Println(" ... clock strikes ...")
-		scrapeFeeds(s)
-	}
+		err = scrapeFeeds(s)
+		if err != nil {
+			fmt.Printf("Error scraping feeds: %v\n", err)
+		}
 
-	return nil
+		// Old posts go while we are at it, see retention
+		if pruneEvery > 0 && time.Since(lastPrune) >= pruneEvery {
+			lastPrune = time.Now()
+			n, err := prunePosts(s, archiveDir, false)
+			if err != nil {
+				fmt.Printf("Error pruning posts: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("Pruned %d post(s).\n", n)
+			}
+		}
+	}
 }
 
 // CH3 L2
+// addfeed <name> <url> [--auth basic|bearer|cookie]
 func handlerAddfeed(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 2 {
-		return fmt.Errorf("missing arguments <name> <url>")
-	}
+	authType, _ := cmd.flag("auth").(string)
+	readability, _ := cmd.flag("readability").(bool)
 
 	// Obtenim nom i url del feed dels arguments
 	name := cmd.args[0]
 	url := cmd.args[1]
 
+	// Ask for the credentials before creating anything, so a typo doesn't leave a half configured feed.
+	var auth *rss.Auth
+	if authType != "" {
+		if _, err := credentialsKey(s.cfg); err != nil {
+			return err
+		}
+
+		var err error
+		auth, err = promptFeedAuth(authType)
+		if err != nil {
+			return err
+		}
+	}
+
 	arg := database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
@@ -215,31 +525,64 @@ func handlerAddfeed(s *state, cmd command, user database.User) error {
 		UserID:    user.ID,
 	}
 
-	// Pass context.Background() to the query to create an empty Context argument.
-	feed, err := s.db.CreateFeed(context.Background(), arg)
+	// All or nothing, a feed missing its credentials would keep the URL taken
+	var feed database.Feed
+	err := inTx(s, func(tx *state) error {
+		// Pass context.Background() to the query to create an empty Context argument.
+		var err error
+		feed, err = tx.db.CreateFeed(context.Background(), arg)
+		if err != nil {
+			return fmt.Errorf("creating feed. %v", err)
+		}
+
+		if auth != nil {
+			err = saveFeedAuth(tx, feed.ID, auth)
+			if err != nil {
+				return err
+			}
+		}
+		if readability {
+			err = tx.db.SetFeedReadability(context.Background(), database.SetFeedReadabilityParams{
+				ID:          feed.ID,
+				Readability: true,
+				UpdatedAt:   time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("turning readability on. %v", err)
+			}
+		}
+
+		// CH4 L1
+		// It should now automatically create a feed follow record for the current user when they add a feed.
+		// Es copy paste de "handleFollow", potser fer-ne metode (TODO)
+		argsFollow := database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		}
+
+		_, err = tx.db.CreateFeedFollow(context.Background(), argsFollow)
+		if err != nil {
+			return fmt.Errorf("creating feed_follows. %v", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("creating feed. %v", err)
+		return err
 	}
 
 	fmt.Println("Created new feed.")
 	fmt.Printf("* [%s] %s -- %s\n", user.Name, feed.Name, feed.Url)
-	// fmt.Println(feed)
-
-	// CH4 L1
-	// It should now automatically create a feed follow record for the current user when they add a feed.
-	// Es copy paste de "handleFollow", potser fer-ne metode (TODO)
-	argsFollow := database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		FeedID:    feed.ID,
+	if auth != nil {
+		fmt.Printf("  Credentials stored (%s).\n", auth.Type)
 	}
-
-	_, err = s.db.CreateFeedFollow(context.Background(), argsFollow)
-	if err != nil {
-		return fmt.Errorf("creating feed_follows. %v", err)
+	if readability {
+		fmt.Println("  Readability on, agg saves the content of each article.")
 	}
+	// fmt.Println(feed)
 
 	return nil
 }
@@ -250,6 +593,7 @@ func handlerFeeds(s *state, cmd command) error {
 		return fmt.Errorf("getting feed list. %v", err)
 	}
 
+	list := output.NewList("id", "name", "url", "user", "auth", "last_fetched_at", "created_at", "orphaned_at", "keep_posts", "keep_days", "readability")
 	for _, feed := range feeds {
 		// Obtenim User segons id
 		// TODO Pper anar be podriem crear un map fora d'aquest for
@@ -260,11 +604,31 @@ func handlerFeeds(s *state, cmd command) error {
 			username = user.Name
 		}
 
-		fmt.Printf("* %s, %s, %v\n", feed.Name, feed.Url, username)
-	}
+		// Only the auth type is shown, the credentials themselves never leave the database.
+		authType := ""
+		cred, err := s.db.GetFeedCredential(context.Background(), feed.ID)
+		if err == nil {
+			authType = cred.AuthType
+		}
 
-	return nil
+		list.Add(feed.ID, feed.Name, feed.Url, username, authType, feed.LastFetchedAt, feed.CreatedAt, feed.OrphanedAt, feed.KeepPosts, feed.KeepDays, feed.Readability)
+	}
 
+	return printList(cmd, list, func() {
+		for i, row := range list.Rows {
+			authInfo := ""
+			if row[4] != "" {
+				authInfo = fmt.Sprintf(" [auth: %s]", row[4])
+			}
+			if feeds[i].OrphanedAt.Valid {
+				authInfo += " [no followers]"
+			}
+			if feeds[i].Readability {
+				authInfo += " [readability]"
+			}
+			fmt.Printf("* %s, %s, %v%s\n", row[1], row[2], row[3], authInfo)
+		}
+	})
 }
 
 // CH4 L1
@@ -272,10 +636,6 @@ func handlerFeeds(s *state, cmd command) error {
 // It should print the name of the feed and the current user once the record is created
 // (which the query we just made should support). You'll need a query to look up feeds by URL.
 func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("missing arguments <url>")
-	}
-
 	// Obtenim nom i url del feed dels arguments
 	url := cmd.args[0]
 
@@ -298,6 +658,12 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("creating feed_follows. %v", err)
 	}
 
+	// gc leaves it alone again
+	err = updateOrphanedFeeds(s)
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("Created new follow:")
 	fmt.Printf("* [%s] %s\n", user.Name, feed.Name)
 
@@ -310,19 +676,20 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("getting following feeds for [%s] -- %v", user.Name, err)
 	}
 
-	fmt.Printf("User %s follows:\n", user.Name)
-	for _, feed := range followingFeeds {
-		fmt.Printf("* %s\n", feed.Name)
+	list := output.NewList("feed_id", "feed", "followed_at")
+	for _, follow := range followingFeeds {
+		list.Add(follow.FeedID, follow.Name, follow.CreatedAt)
 	}
 
-	return nil
+	return printList(cmd, list, func() {
+		fmt.Printf("User %s follows:\n", user.Name)
+		for _, feed := range followingFeeds {
+			fmt.Printf("* %s\n", feed.Name)
+		}
+	})
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("missing arguments <url>")
-	}
-
 	// Obtenim nom i url del feed dels arguments
 	url := cmd.args[0]
 
@@ -343,11 +710,43 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("deleting feed_follows. %v", err)
 	}
 
+	// If it was the last follower gc starts counting
+	err = updateOrphanedFeeds(s)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("User %s is not following \"%s\" anymore.\n", user.Name, feed.Name)
 
 	return nil
 }
 
+// browsePosts returns the first limit posts browse shows user, oldest
+// first, after the filter rules.
+func browsePosts(s *state, user database.User, limit int) ([]postView, error) {
+	// Hidden posts don't count, ask for more until there are enough
+	var views []postView
+	for n := limit; ; n += limit - len(views) {
+		arg := database.GetLimitedPostsForUserParams{
+			UserID: user.ID,
+			Limit:  int32(n),
+		}
+		newPosts, err := s.db.GetLimitedPostsForUser(context.Background(), arg)
+		if err != nil {
+			return nil, fmt.Errorf("getting posts for [%s] -- %v", user.Name, err)
+		}
+		views, err = filterPosts(s, user, newPosts)
+		if err != nil {
+			return nil, err
+		}
+		if len(views) >= limit || len(newPosts) < n {
+			break
+		}
+	}
+
+	return views[:min(limit, len(views))], nil
+}
+
 func handlerBrowse(s *state, cmd command, user database.User) error {
 	var limit int
 	var err error
@@ -359,24 +758,20 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		if err != nil {
 			return err
 		}
+		if limit < 1 {
+			return errors.New("the limit has to be at least 1")
+		}
 	}
 
-	arg := database.GetLimitedPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	}
-
-	newPosts, err := s.db.GetLimitedPostsForUser(context.Background(), arg)
+	views, err := browsePosts(s, user, limit)
 	if err != nil {
-		return fmt.Errorf("getting posts for [%s] -- %v", user.Name, err)
-	}
-
-	fmt.Printf("%d new posts.\n", len(newPosts))
-	for _, post := range newPosts {
-		fmt.Printf("* %s\n", post.Title)
+		return err
 	}
 
-	return nil
+	return printList(cmd, postList(views), func() {
+		fmt.Printf("%d new posts.\n", len(views))
+		printPostTitles(views)
+	})
 }
 
 // This will be the function signature of all command handlers.
@@ -389,19 +784,53 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 // Obtenim l'usuari segons el que haguem obtingut del fitxer de configuracio
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+		// The session in the config, not just a name
+		user, err := sessionUser(s)
 		if err != nil {
-			return fmt.Errorf("the user does not exist. %v", err)
+			return err
 		}
 
 		return handler(s, cmd, user)
 	}
 }
 
+// middlewareAdmin is middlewareLoggedIn for the commands that change
+// everyone's data, only admins get to run them.
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		if !user.IsAdmin {
+			return fmt.Errorf("only admins can run %s", cmd.name)
+		}
+
+		return handler(s, cmd, user)
+	})
+}
+
+// scraperDB is the part of the database used by the scraper.
+// Every storage.Store implements it, the tests use a fake.
+type scraperDB interface {
+	GetNextFeedToFetch(ctx context.Context) (database.Feed, error)
+	MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error
+	GetFeedCredential(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error)
+	CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error)
+	SetPostContent(ctx context.Context, arg database.SetPostContentParams) error
+	GetAlertRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.AlertRule, error)
+	GetUserById(ctx context.Context, id uuid.UUID) (database.User, error)
+	CreateAlertDelivery(ctx context.Context, arg database.CreateAlertDeliveryParams) (database.AlertDelivery, error)
+}
+
 // CH5 L1-L2
 func scrapeFeeds(s *state) error {
+	return scrapeNextFeed(s.db, s.cfg)
+}
+
+func scrapeNextFeed(db scraperDB, cfg *config.Config) error {
 	// Get the next feed to fetch from the DB
-	nextFeed, err := s.db.GetNextFeedToFetch(context.Background())
+	// Only feeds someone follows
+	nextFeed, err := db.GetNextFeedToFetch(context.Background())
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("getting next feed to fetch. Nobody follows any feed")
+	}
 	if err != nil {
 		return fmt.Errorf("getting next feed to fetch. %v", err)
 	}
@@ -413,14 +842,26 @@ func scrapeFeeds(s *state) error {
 		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
 	}
 
-	err = s.db.MarkFeedFetched(context.Background(), argsMark)
+	err = db.MarkFeedFetched(context.Background(), argsMark)
 	if err != nil {
 		return fmt.Errorf("marking feed as fetched. %v", err)
 	}
 
+	// Protected feeds carry their own credentials
+	auth, err := loadFeedAuth(db, cfg, nextFeed.ID)
+	if err != nil {
+		return fmt.Errorf("loading credentials for %s. %v", nextFeed.Name, err)
+	}
+
 	// Fetch the feed using the URL (we already wrote this function)
 	fmt.Printf("# Fetching %s", nextFeed.Name)
-	feed, err := rss.FetchFeed(nextFeed.Url)
+	feed, err := rss.FetchFeedWithAuth(nextFeed.Url, auth)
+	if err != nil {
+		return err
+	}
+
+	// The alerts of its followers, for the posts that are new
+	alertRules, err := loadAlertRules(db, nextFeed)
 	if err != nil {
 		return err
 	}
@@ -430,14 +871,17 @@ func scrapeFeeds(s *state) error {
 	// Update your scraper to save posts. Instead of printing out the titles of the posts, save them to the database!
 	fmt.Printf(": %d items.\n", len(feed.Channel.Item))
 	for _, item := range feed.Channel.Item {
-		fmt.Printf("* ADDING --> %s (%v)\n", item.Title, item.PubDate)
+		// The URL is what identifies a post
+		if item.Link == "" {
+			fmt.Printf("* SKIPPING (no link) --> %s\n", item.Title)
+			continue
+		}
 
 		// converteix item.PubDate (string) a time.Time
-		// fmt.Printf("item.Pubdate: %s\n", item.PubDate)
-		//pubDate, err := time.Parse("Mon, 23 Jun 2025 07:01:00 +0000", item.PubDate)
-		pubDate, err := time.Parse(time.RFC1123Z, item.PubDate)
-		if err != nil {
-			return fmt.Errorf("failed to parse date: %w", err)
+		// A missing or unreadable date shouldn't drop the post, we use the time we saw it instead.
+		pubDate, dateErr := item.PublishedAt()
+		if dateErr != nil {
+			pubDate = time.Now()
 		}
 
 		argsCreatePost := database.CreatePostParams{
@@ -449,74 +893,531 @@ func scrapeFeeds(s *state) error {
 			Description: sql.NullString{String: item.Description, Valid: true},
 			PublishedAt: sql.NullTime{Time: pubDate, Valid: true},
 			FeedID:      uuid.NullUUID{UUID: nextFeed.ID, Valid: true},
+			Author:      sql.NullString{String: item.Author, Valid: item.Author != ""},
+			// item.Description is already sanitized by the rss package
+			DescriptionText: sql.NullString{String: sanitize.Text(item.Description), Valid: true},
 		}
 
-		_, err = s.db.CreatePost(context.Background(), argsCreatePost)
+		post, err := db.CreatePost(context.Background(), argsCreatePost)
 		if err != nil {
 			// If you encounter an error where the post with that URL already exists, just ignore it. That will happen a lot.
 			// If it's a different error, you should probably log it.
 			// pq: duplicate key value violates unique constraint "posts_url_key"
-			if !strings.Contains(err.Error(), "unique constraint \"posts_url_key\"") {
+			// sqlite: constraint failed: UNIQUE constraint failed: posts.url
+			if !storage.IsUniqueViolation(err) {
 				//return fmt.Errorf("creating post -- %v", err)
-				fmt.Println("Error creating post -- %v", err)
+				fmt.Printf("Error creating post -- %v\n", err)
+			}
+			continue
+		}
+
+		// Only the posts that are new, in a dry run too
+		fmt.Printf("* ADDING --> %s (%v)\n", item.Title, item.PubDate)
+		if dateErr != nil {
+			fmt.Printf("  (failed to parse date: %v, using current time)\n", dateErr)
+		}
+
+		// Only new posts, the articles of the others were fetched already
+		if nextFeed.Readability {
+			post, err = saveContent(db, post)
+			if err != nil {
+				fmt.Printf("  (no content: %v)\n", err)
 			}
 		}
 
+		sendAlerts(db, cfg, nextFeed, alertRules, post)
 	}
 	fmt.Println("")
 
 	return nil
 }
 
+// newCommands registers every command the CLI knows.
+func newCommands() *commands {
+	// CH1 L3 Create a new instance of the commands struct.
+	listOfCommands := &commands{}
+
+	//
+	listOfCommands.register(&commandSpec{
+		name:        "init",
+		description: "Create the config file, the database and your user",
+		handler:     handlerInit,
+		needs:       needsNothing,
+	})
+	listOfCommands.register(&commandSpec{ // CH1 L3
+		name:        "login",
+		description: "Log in as an existing user, asks for the password",
+		args:        "<username>",
+		handler:     handlerLogin,
+		complete:    completeUsernames,
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "register",
+		description: "Create a user with a password and log in as them",
+		args:        "<username>",
+		handler:     handlerRegister,
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "logout",
+		description: "End the session of the current user",
+		handler:     handlerLogout,
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "reset",
+		description: "Delete every user, with their feeds, follows and posts (admins only)",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("yes", false, "don't ask for confirmation")
+			fs.Bool("no-backup", false, "don't back the database up first")
+			fs.String("backup-dir", "", "where the backup goes (default gator-backups next to the config file)")
+		},
+		handler: middlewareAdmin(handlerReset),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "user",
+		description: "Manage your account, or anyone's for admins",
+		subcommands: []*commandSpec{
+			{
+				name:        "delete",
+				description: "Delete a user, with their follows and the feeds nobody else follows",
+				args:        "<username>",
+				flags: func(fs *flag.FlagSet) {
+					fs.String("transfer-to", "", "give the user's feeds to this user")
+					fs.Bool("delete-feeds", false, "delete the user's feeds even if others follow them")
+					fs.Bool("yes", false, "don't ask for confirmation")
+				},
+				handler:  middlewareLoggedIn(handlerUserDelete),
+				complete: completeUsernames,
+			},
+			{
+				name:        "rename",
+				description: "Change a username",
+				args:        "<username> <new-name>",
+				handler:     middlewareLoggedIn(handlerUserRename),
+				complete:    firstArg(completeUsernames),
+			},
+			{
+				name:        "set-password",
+				description: "Change a user's password",
+				args:        "<username>",
+				handler:     middlewareLoggedIn(handlerUserSetPassword),
+				complete:    completeUsernames,
+			},
+			{
+				name:        "deactivate",
+				description: "Stop a user from logging in, keeping their feeds",
+				args:        "<username>",
+				handler:     middlewareLoggedIn(handlerUserDeactivate),
+				complete:    completeUsernames,
+			},
+			{
+				name:        "activate",
+				description: "Let a deactivated user log in again (admins only)",
+				args:        "<username>",
+				handler:     middlewareAdmin(handlerUserActivate),
+				complete:    completeUsernames,
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "admin",
+		description: "Give or take away admin rights (admins only)",
+		subcommands: []*commandSpec{
+			{
+				name:        "grant",
+				description: "Make a user an admin",
+				args:        "<username>",
+				handler:     middlewareAdmin(handlerAdminGrant),
+				complete:    completeUsernames,
+			},
+			{
+				name:        "revoke",
+				description: "Make an admin a regular user",
+				args:        "<username>",
+				handler:     middlewareAdmin(handlerAdminRevoke),
+				complete:    completeUsernames,
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "migrate",
+		description: "Create or update the database schema",
+		needs:       needsConnection,
+		subcommands: []*commandSpec{
+			{name: "up", description: "Apply every pending migration", handler: handlerMigrateUp},
+			{name: "down", description: "Roll back the last migration", handler: handlerMigrateDown},
+			{name: "status", description: "List the migrations and whether they are applied", handler: handlerMigrateStatus},
+			{name: "to", description: "Migrate up or down to a version", args: "<version>", handler: handlerMigrateTo},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "profile",
+		description: "List, add or switch config profiles",
+		handler:     handlerProfileList,
+		needs:       needsConfig,
+		subcommands: []*commandSpec{
+			{name: "list", description: "List the profiles, the default one included", handler: handlerProfileList},
+			{name: "use", description: "Switch to a profile, default is the top level settings", args: "<name>", handler: handlerProfileUse, complete: completeProfiles},
+			{name: "add", description: "Add a profile for another database", args: "<name> <db_url>", handler: handlerProfileAdd},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "users",
+		description: "List the users",
+		handler:     handlerUsers,
+	})
+	listOfCommands.register(&commandSpec{ // CH3 L1 + CH5 L1
+		name:        "agg",
+		description: "Fetch the feeds, one every time_between_reqs (30s, 1m, 1h...)",
+		args:        "<time_between_reqs>",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("dry-run", false, "fetch and parse the feeds but save nothing")
+			fs.Duration("prune-every", defaultPruneEvery, "delete the posts past their retention this often, 0 never")
+			fs.String("archive-dir", "", "save the pruned posts as gzipped JSON in this directory first")
+		},
+		handler: handlerAgg,
+	})
+	listOfCommands.register(&commandSpec{ // CH3 L2 + CH4 L2
+		name:        "addfeed",
+		description: "Add a feed and follow it",
+		args:        "<name> <url>",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("auth", "", "ask for the credentials of a protected feed, `type` basic, bearer or cookie")
+			fs.Bool("readability", false, "fetch the article of each post and save its main content")
+		},
+		handler: middlewareLoggedIn(handlerAddfeed),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "feed",
+		description: "Change a feed you added, or any feed for admins",
+		subcommands: []*commandSpec{
+			{
+				name:        "rename",
+				description: "Change the name of a feed",
+				args:        "<url> <new-name>",
+				handler:     middlewareLoggedIn(handlerFeedRename),
+				complete:    firstArg(completeFeedURLs),
+			},
+			{
+				name:        "set-url",
+				description: "Change the URL of a feed, keeping its follows and posts",
+				args:        "<url> <new-url>",
+				handler:     middlewareLoggedIn(handlerFeedSetUrl),
+				complete:    firstArg(completeFeedURLs),
+			},
+			{
+				name:        "remove",
+				description: "Delete a feed with its follows and posts",
+				args:        "<url>",
+				flags: func(fs *flag.FlagSet) {
+					fs.Bool("yes", false, "don't ask for confirmation")
+				},
+				handler:  middlewareLoggedIn(handlerFeedRemove),
+				complete: completeFeedURLs,
+			},
+			{
+				name:        "readability",
+				description: "Fetch the article of each new post and save its main content, or stop",
+				args:        "<url> <on|off>",
+				handler:     middlewareLoggedIn(handlerFeedReadability),
+				complete:    firstArg(completeFeedURLs),
+			},
+			{
+				name:        "transfer",
+				description: "Give a feed to another user",
+				args:        "<url> <username>",
+				handler:     middlewareLoggedIn(handlerFeedTransfer),
+				complete:    completeFeedTransfer,
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "gc",
+		description: "Delete the feeds nobody has followed for a while, with their posts (admins only)",
+		flags: func(fs *flag.FlagSet) {
+			fs.Duration("grace", defaultGCGrace, "how long a feed has to be without followers")
+			fs.String("archive-dir", "", "save the feeds and their posts as gzipped JSON in this directory first")
+			fs.Bool("dry-run", false, "list the feeds that would be deleted")
+			fs.Bool("yes", false, "delete them even if users saved some of their posts")
+		},
+		handler: middlewareAdmin(handlerGC),
+	})
+	listOfCommands.register(&commandSpec{ // CH3 L3
+		name:        "feeds",
+		description: "List every feed",
+		handler:     handlerFeeds,
+	})
+	listOfCommands.register(&commandSpec{ // CH4 L1 + CH4 L2
+		name:        "follow",
+		description: "Follow a feed added by any user",
+		args:        "<url>",
+		handler:     middlewareLoggedIn(handlerFollow),
+		complete:    completeFeedURLs,
+	})
+	listOfCommands.register(&commandSpec{ // CH4 L1 + CH4 L2
+		name:        "following",
+		description: "List the feeds you follow",
+		handler:     middlewareLoggedIn(handlerFollowing),
+	})
+	listOfCommands.register(&commandSpec{ // CH4 L3
+		name:        "unfollow",
+		description: "Stop following a feed",
+		args:        "<url>",
+		handler:     middlewareLoggedIn(handlerUnfollow),
+		complete:    completeFollowedURLs,
+	})
+	listOfCommands.register(&commandSpec{ // CH5 L2
+		name:        "browse",
+		description: "Show the latest posts of the feeds you follow, 2 unless you give a limit",
+		args:        "[limit]",
+		handler:     middlewareLoggedIn(handlerBrowse),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "show",
+		description: "Show a post, with its links as footnotes",
+		args:        "<post-id|index>",
+		flags: func(fs *flag.FlagSet) {
+			fs.Int("width", 0, "wrap the text at `N` columns (default the terminal width, up to 100)")
+			fs.Bool("pager", false, "show it through $PAGER, less if it isn't set")
+		},
+		handler: middlewareLoggedIn(handlerShow),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "read",
+		description: "Show a post with the content of its article and mark it as read",
+		args:        "<post-id|index>",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("fetch", false, "fetch the article now if its content wasn't saved")
+		},
+		handler: middlewareLoggedIn(handlerRead),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "save",
+		description: "Save a post, prune keeps it",
+		args:        "<post-id>",
+		handler:     middlewareLoggedIn(handlerSave),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "unsave",
+		description: "Stop saving a post",
+		args:        "<post-id>",
+		handler:     middlewareLoggedIn(handlerUnsave),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "saved",
+		description: "List the posts you saved",
+		handler:     middlewareLoggedIn(handlerSaved),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "filter",
+		description: "Hide, mark as read, highlight or tag posts by their title, description or author",
+		handler:     middlewareLoggedIn(handlerFilterList),
+		subcommands: []*commandSpec{
+			{
+				name:        "add",
+				description: "Add a rule with one condition, for every feed or the one of --feed",
+				flags: func(fs *flag.FlagSet) {
+					postConditionFlags(fs)
+					fs.String("action", "", "what to do with the posts that match: hide, mark-read, highlight or tag")
+					fs.String("tag", "", "the `name` of the tag, for --action tag")
+				},
+				handler: middlewareLoggedIn(handlerFilterAdd),
+			},
+			{name: "list", description: "List your filter rules", handler: middlewareLoggedIn(handlerFilterList)},
+			{
+				name:        "remove",
+				description: "Delete one of your filter rules",
+				args:        "<id>",
+				handler:     middlewareLoggedIn(handlerFilterRemove),
+			},
+			{
+				name:        "test",
+				description: "List the posts of the feeds you follow that a rule, or a condition, matches",
+				args:        "[id]",
+				flags:       postConditionFlags,
+				handler:     middlewareLoggedIn(handlerFilterTest),
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "alert",
+		description: "Get a webhook call, a command run or an email for the new posts that match",
+		handler:     middlewareLoggedIn(handlerAlertList),
+		subcommands: []*commandSpec{
+			{
+				name:        "add",
+				description: "Add a rule with one condition, for every feed you follow or the one of --feed",
+				flags: func(fs *flag.FlagSet) {
+					postConditionFlags(fs)
+					fs.String("webhook", "", "POST the post as JSON to `url`, retrying if it fails")
+					fs.String("command", "", "run `cmd` with sh, the post as JSON on its input and in GATOR_ALERT_* variables (admins only)")
+					fs.String("email", "", "send an email to `address` through the smtp server of the config file")
+				},
+				handler: middlewareLoggedIn(handlerAlertAdd),
+			},
+			{name: "list", description: "List your alert rules", handler: middlewareLoggedIn(handlerAlertList)},
+			{
+				name:        "remove",
+				description: "Delete one of your alert rules with its history",
+				args:        "<id>",
+				handler:     middlewareLoggedIn(handlerAlertRemove),
+			},
+			{
+				name:        "history",
+				description: "List the latest alerts sent, or that failed",
+				flags: func(fs *flag.FlagSet) {
+					fs.Int("limit", defaultAlertHistory, "show the latest `N`")
+				},
+				handler: middlewareLoggedIn(handlerAlertHistory),
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "digest",
+		description: "Summarize your unread posts since the last digest, by feed",
+		flags: func(fs *flag.FlagSet) {
+			fs.Duration("since", 0, "cover the posts saved in this `duration`, instead of the ones since the last digest")
+			fs.String("file", "", "write it to `path`, as HTML if it ends in .html")
+			fs.String("email", "", "send it to `address` through the smtp server of the config file")
+			fs.Bool("dry-run", false, "print it, without sending it or starting the next digest here")
+		},
+		handler: middlewareLoggedIn(handlerDigest),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "retention",
+		description: "Show or change how many posts are kept",
+		handler:     handlerRetention,
+		subcommands: []*commandSpec{
+			{name: "show", description: "Show the global retention and the feeds with their own", handler: handlerRetention},
+			{
+				name:        "set",
+				description: "Set the retention of a feed, or the global one without a URL (admins only)",
+				args:        "[url]",
+				flags: func(fs *flag.FlagSet) {
+					fs.Int("posts", -1, "keep the newest `N` posts, 0 for no limit, -1 for the global value (or to leave the global one as it is)")
+					fs.Int("days", -1, "keep the posts of the last `N` days, 0 for no limit, -1 for the global value (or to leave the global one as it is)")
+				},
+				handler:  middlewareLoggedIn(handlerRetentionSet),
+				complete: completeFeedURLs,
+			},
+		},
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "prune",
+		description: "Delete the posts past their retention, except the saved ones (admins only)",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("archive-dir", "", "save the posts as gzipped JSON in this directory first")
+			fs.Bool("dry-run", false, "count the posts that would be deleted")
+		},
+		handler: middlewareAdmin(handlerPrune),
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "help",
+		description: "List the commands, or show the details of one",
+		args:        "[command] [subcommand]",
+		handler:     listOfCommands.handlerHelp,
+		complete:    listOfCommands.completeCommands,
+		needs:       needsNothing,
+	})
+	listOfCommands.register(&commandSpec{
+		name:        "completion",
+		description: "Print the completion script for bash, zsh or fish",
+		args:        "<shell>",
+		handler:     handlerCompletion,
+		complete:    completeShells,
+		needs:       needsNothing,
+	})
+	// Called by the completion scripts: gator __complete -- <words typed so far>
+	listOfCommands.register(&commandSpec{
+		name:        "__complete",
+		description: "Print the completions of the last word",
+		args:        "[word]...",
+		handler:     listOfCommands.handlerComplete,
+		needs:       needsNothing,
+		hidden:      true,
+	})
+
+	return listOfCommands
+}
+
 func main() {
 	status := state{}
+	listOfCommands := newCommands()
+
+	// gator [global flags] <command> [args...]
+	// The command, its flags and arguments are checked before anything is opened
+	spec, cmd, err := listOfCommands.lookup(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) && spec == nil {
+		listOfCommands.printHelp()
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		spec.printHelp()
+		return
+	}
+	// CH1 L3 Use os.Args to get the command-line arguments passed in by the user.
+	if errors.Is(err, errNoCommand) {
+		fmt.Println("Please provide a command.")
+		fmt.Println()
+		listOfCommands.printHelp()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// init creates the config, it can't need one
+	if spec.needs == needsNothing {
+		status.cfg = &config.Config{}
+		err := spec.handler(&status, cmd)
+		if err != nil {
+			fmt.Printf("Error running command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// CH1 L2-3
 	// Read the config file.
-	cfg, err := config.Read()
+	cfg, err := loadConfig(spec.needs)
 	if err != nil {
-		fmt.Println("Error reading config file: %v", err)
+		fmt.Printf("Error reading config file: %v\n", err)
+		os.Exit(1)
 	}
 
-	status.cfg = &cfg
+	status.cfg = cfg
 
-	// CH2 L3
-	dbURL := status.cfg.DbUrl
-	db, err := sql.Open("postgres", dbURL)
-	dbQueries := database.New(db)
-	status.db = dbQueries
-
-	// CH1 L3 Create a new instance of the commands struct with an initialized map of handler functions.
-	listOfCommands := commands{
-		callback: make(map[string]func(*state, command) error),
+	if spec.needs == needsConfig {
+		err = spec.handler(&status, cmd)
+		if err != nil {
+			fmt.Printf("Error running command: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	//
-	listOfCommands.register("login", handlerLogin) // CH1 L3
-	listOfCommands.register("register", handlerRegister)
-	listOfCommands.register("reset", handlerReset)
-	listOfCommands.register("users", handlerUsers)
-	listOfCommands.register("agg", handlerAgg)                                 // CH3 L1 + CH5 L1
-	listOfCommands.register("addfeed", middlewareLoggedIn(handlerAddfeed))     // CH3 L2 + CH4 L2
-	listOfCommands.register("feeds", handlerFeeds)                             // CH3 L3
-	listOfCommands.register("follow", middlewareLoggedIn(handlerFollow))       // CH4 L1 + CH4 L2
-	listOfCommands.register("following", middlewareLoggedIn(handlerFollowing)) // CH4 L1 + CH4 L2
-	listOfCommands.register("unfollow", middlewareLoggedIn(handlerUnfollow))   // CH4 L3
-	listOfCommands.register("browse", middlewareLoggedIn(handlerBrowse))       // CH5 L2
-
-	// CH1 L3 Use os.Args to get the command-line arguments passed in by the user.
-	if len(os.Args) < 2 {
-		fmt.Println("Please provide a command.")
+	// CH2 L3
+	// The scheme of db_url picks the backend: postgres:// or sqlite://
+	conn, err := connect(status.cfg.DbUrl)
+	if err != nil {
+		fmt.Printf("Error connecting to %s: %v\n", storage.Redacted(status.cfg.DbUrl), err)
 		os.Exit(1)
 	}
+	defer conn.Close()
+	status.db = conn
+	status.conn = conn
 
-	cmd := command{
-		name: os.Args[1],
-		args: os.Args[2:],
+	// Every command but migrate needs an up to date schema
+	if spec.needs == needsDatabase {
+		err = checkSchema(&status)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Run the command
-	err = listOfCommands.run(&status, cmd)
+	err = spec.handler(&status, cmd)
 	if err != nil {
 		fmt.Printf("Error running command: %v\n", err)
 		os.Exit(1)
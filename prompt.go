@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Shared reader so consecutive prompts don't lose buffered input.
+var stdin = bufio.NewReader(os.Stdin)
+
+// promptLine asks for a value and returns the trimmed answer.
+func promptLine(label string) (string, error) {
+	fmt.Printf("%s: ", label)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading %s. %v", strings.ToLower(label), err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// promptSecret is like promptLine but doesn't echo the answer when stdin is a terminal.
+func promptSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return promptLine(label)
+	}
+
+	fmt.Printf("%s: ", label)
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("reading %s. %v", strings.ToLower(label), err)
+	}
+
+	return string(secret), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/readability"
+)
+
+// saveContent fetches the article of post and saves its main content.
+func saveContent(db scraperDB, post database.Post) (database.Post, error) {
+	content, err := readability.Fetch(context.Background(), post.Url)
+	if err != nil {
+		return post, err
+	}
+
+	err = db.SetPostContent(context.Background(), database.SetPostContentParams{
+		ID:        post.ID,
+		Content:   sql.NullString{String: content, Valid: true},
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return post, fmt.Errorf("saving content. %v", err)
+	}
+
+	post.Content = sql.NullString{String: content, Valid: true}
+	return post, nil
+}
+
+// read <post-id|index> [--fetch]
+// show, then the post is marked as read.
+func handlerRead(s *state, cmd command, user database.User) error {
+	post, err := postByArg(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	fetch, _ := cmd.flag("fetch").(bool)
+	if fetch && !post.Content.Valid {
+		post, err = saveContent(s.db, post)
+		if err != nil {
+			return fmt.Errorf("getting the article of %q. %v", post.Title, err)
+		}
+	}
+
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(renderPost(post, feeds[post.FeedID.UUID], terminalWidth()))
+	if !post.Content.Valid {
+		fmt.Printf("\n(Only the description. `gator read --fetch %s` gets the whole article.)\n", post.ID)
+	}
+
+	err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("marking post as read. %v", err)
+	}
+
+	return nil
+}
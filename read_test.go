@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/neixir/gator/internal/feedtest"
+)
+
+// articleServer serves /feed.xml with two posts: /articles/good, a page with
+// an article in it, and /articles/missing, which doesn't exist.
+func articleServer(t *testing.T) *feedtest.Server {
+	t.Helper()
+
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/articles/good": {Body: []byte(`<html><head><title>Good</title></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article>
+<h1>A good article</h1>
+<p>Readability keeps the paragraphs of the article, like this one, and drops the menus, the
+sidebars and the comments around them, which are not what anyone came for.</p>
+<p>This second paragraph is there so the article is long enough, with a <a href="/more">relative
+link</a> that has to point to the site and not to gator.</p>
+</article>
+<div class="comments"><p>First! This comment is long enough to be a paragraph, but it is a comment.</p></div>
+</body></html>`)},
+		"/articles/missing": {Status: 404},
+	})
+	server.Handle("/feed.xml", feedtest.Response{
+		Body: []byte(fmt.Sprintf(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Articles</title>
+<item><title>A good article</title><link>%s</link><description>Short.</description><pubDate>Mon, 02 Jun 2025 10:00:00 +0000</pubDate></item>
+<item><title>A missing article</title><link>%s</link><description>Gone.</description><pubDate>Tue, 03 Jun 2025 10:00:00 +0000</pubDate></item>
+</channel></rss>`, server.FeedURL("/articles/good"), server.FeedURL("/articles/missing"))),
+		Header: map[string]string{"Content-Type": "application/rss+xml"},
+	})
+
+	return server
+}
+
+func TestRead(t *testing.T) {
+	forEachBackend(t, testRead)
+}
+
+func testRead(t *testing.T, s *state) {
+	server := articleServer(t)
+	url := server.FeedURL("/feed.xml")
+
+	register(t, s, "alice")
+	if out := mustRun(t, s, "addfeed", "Articles", url, "--readability"); !strings.Contains(out, "Readability on") {
+		t.Errorf("addfeed --readability = %q", out)
+	}
+	if out := mustRun(t, s, "feeds"); !strings.Contains(out, "[readability]") {
+		t.Errorf("feeds = %q", out)
+	}
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+
+	ids := strings.Fields(mustRun(t, s, "browse", "10", "--output", "tsv", "--fields", "id"))
+	if len(ids) != 3 || ids[0] != "id" {
+		t.Fatalf("browse ids = %q", ids)
+	}
+	good, missing := ids[1], ids[2]
+
+	out := mustRun(t, s, "read", good)
+	for _, want := range []string{
+		"A good article\nFeed:      Articles\nPublished: Mon 2 Jun 2025 10:00\nURL:       " + server.FeedURL("/articles/good"),
+		"Readability keeps the paragraphs",
+		"with a relative\nlink[1] that",
+		"[1] " + server.FeedURL("/more"),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("read is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "First!") || strings.Contains(out, "Only the description") {
+		t.Errorf("read:\n%s", out)
+	}
+	if out := mustRun(t, s, "browse", "10"); !strings.Contains(out, "A good article (read)") {
+		t.Errorf("browse after read = %q", out)
+	}
+
+	// The article wasn't there when agg fetched it
+	if out := mustRun(t, s, "read", missing); !strings.Contains(out, "Gone.") || !strings.Contains(out, "gator read --fetch "+missing) {
+		t.Errorf("read without content = %q", out)
+	}
+	if _, err := runCommand(t, s, "read", missing, "--fetch"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("read --fetch of a missing article: err = %v", err)
+	}
+	server.Handle("/articles/missing", feedtest.Response{Body: []byte(strings.Repeat("<p>It is back now, with a paragraph long enough to count.</p>", 4))})
+	if out := mustRun(t, s, "read", missing, "--fetch"); !strings.Contains(out, "It is back now") {
+		t.Errorf("read --fetch = %q", out)
+	}
+	if _, err := runCommand(t, s, "read", "nope"); err == nil {
+		t.Errorf("read of a bad id should fail")
+	}
+
+	// Only whoever added the feed can change it
+	register(t, s, "bob")
+	if _, err := runCommand(t, s, "feed", "readability", url, "off"); err == nil {
+		t.Errorf("bob turned readability off on alice's feed")
+	}
+	login(t, s, "alice")
+	if _, err := runCommand(t, s, "feed", "readability", url, "maybe"); err == nil {
+		t.Errorf("feed readability maybe should fail")
+	}
+	if out := mustRun(t, s, "feed", "readability", url, "off"); !strings.Contains(out, `Readability is off for "Articles".`) {
+		t.Errorf("feed readability off = %q", out)
+	}
+	if out := mustRun(t, s, "feeds"); strings.Contains(out, "[readability]") {
+		t.Errorf("feeds after off = %q", out)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/neixir/gator/internal/archive"
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/output"
+)
+
+// Keys of the global retention in the settings table
+const (
+	settingKeepPosts = "retention.keep_posts"
+	settingKeepDays  = "retention.keep_days"
+)
+
+// How often agg prunes unless --prune-every says otherwise
+const defaultPruneEvery = time.Hour
+
+// retention says how many posts of a feed prune keeps. Zero is no limit.
+// A post goes once it is past every limit that is set: with both, the
+// newest KeepPosts posts stay even if they are older than KeepDays.
+type retention struct {
+	KeepPosts int
+	KeepDays  int
+}
+
+func (r retention) String() string {
+	switch {
+	case r.KeepPosts > 0 && r.KeepDays > 0:
+		return fmt.Sprintf("%d posts or %d days", r.KeepPosts, r.KeepDays)
+	case r.KeepPosts > 0:
+		return fmt.Sprintf("%d posts", r.KeepPosts)
+	case r.KeepDays > 0:
+		return fmt.Sprintf("%d days", r.KeepDays)
+	}
+
+	return "everything"
+}
+
+// globalRetention reads the retention every feed without its own uses.
+func globalRetention(s *state) (retention, error) {
+	var r retention
+	for key, value := range map[string]*int{settingKeepPosts: &r.KeepPosts, settingKeepDays: &r.KeepDays} {
+		setting, err := s.db.GetSetting(context.Background(), key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return r, fmt.Errorf("getting %s. %v", key, err)
+		}
+		*value, err = strconv.Atoi(setting.Value)
+		if err != nil {
+			return r, fmt.Errorf("%s is not a number: %q", key, setting.Value)
+		}
+	}
+
+	return r, nil
+}
+
+// feedRetention is global with what feed sets on top of it.
+func feedRetention(global retention, feed database.Feed) retention {
+	r := global
+	if feed.KeepPosts.Valid {
+		r.KeepPosts = int(feed.KeepPosts.Int32)
+	}
+	if feed.KeepDays.Valid {
+		r.KeepDays = int(feed.KeepDays.Int32)
+	}
+
+	return r
+}
+
+// postsToPrune picks what r doesn't keep from posts, newest first.
+func postsToPrune(posts []database.Post, r retention, now time.Time) []database.Post {
+	if r.KeepPosts <= 0 && r.KeepDays <= 0 {
+		return nil
+	}
+
+	cutoff := now.AddDate(0, 0, -r.KeepDays)
+	var pruned []database.Post
+	for i, post := range posts {
+		if r.KeepPosts > 0 && i < r.KeepPosts {
+			continue
+		}
+		if r.KeepDays > 0 && !post.PublishedAt.Time.Before(cutoff) {
+			continue
+		}
+		pruned = append(pruned, post)
+	}
+
+	return pruned
+}
+
+// prunePosts deletes the posts of every feed its retention doesn't keep,
+// except the saved ones. With archiveDir they are saved there first.
+// It returns how many went.
+func prunePosts(s *state, archiveDir string, dryRun bool) (int, error) {
+	ctx := context.Background()
+	global, err := globalRetention(s)
+	if err != nil {
+		return 0, err
+	}
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getting feed list. %v", err)
+	}
+
+	var pruned []database.Post
+	for _, feed := range feeds {
+		r := feedRetention(global, feed)
+		posts, err := s.db.GetPrunablePostsForFeed(ctx, feed.ID)
+		if err != nil {
+			return 0, fmt.Errorf("getting posts of %s. %v", feed.Name, err)
+		}
+		feedPruned := postsToPrune(posts, r, time.Now())
+		if len(feedPruned) > 0 {
+			fmt.Printf("* %s: %d of %d posts, it keeps %s.\n", feed.Name, len(feedPruned), len(posts), r)
+		}
+		pruned = append(pruned, feedPruned...)
+	}
+	if dryRun || len(pruned) == 0 {
+		return len(pruned), nil
+	}
+
+	// The copy first, nothing is deleted if it fails
+	if archiveDir != "" {
+		archived := []archive.Post{}
+		for _, post := range pruned {
+			archived = append(archived, archive.NewPost(post))
+		}
+		path, err := archive.Write(archiveDir, "posts", archived)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Printf("Archived %d post(s) to %s.\n", len(archived), path)
+	}
+
+	for _, post := range pruned {
+		err = s.db.DeletePost(ctx, post.ID)
+		if err != nil {
+			return 0, fmt.Errorf("deleting post %s. %v", post.ID, err)
+		}
+	}
+
+	return len(pruned), nil
+}
+
+// prune [--archive-dir <dir>] [--dry-run]
+func handlerPrune(s *state, cmd command, user database.User) error {
+	archiveDir, _ := cmd.flag("archive-dir").(string)
+	dryRun, _ := cmd.flag("dry-run").(bool)
+
+	n, err := prunePosts(s, archiveDir, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("Would delete %d post(s).\n", n)
+		return nil
+	}
+
+	fmt.Printf("Deleted %d post(s).\n", n)
+	return nil
+}
+
+// retention
+// The global retention and the feeds that have their own.
+func handlerRetention(s *state, cmd command) error {
+	global, err := globalRetention(s)
+	if err != nil {
+		return err
+	}
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("getting feed list. %v", err)
+	}
+
+	list := output.NewList("feed", "url", "keep_posts", "keep_days")
+	list.Add("(global)", "", global.KeepPosts, global.KeepDays)
+	var own []database.Feed
+	for _, feed := range feeds {
+		if feed.KeepPosts.Valid || feed.KeepDays.Valid {
+			own = append(own, feed)
+			list.Add(feed.Name, feed.Url, feed.KeepPosts, feed.KeepDays)
+		}
+	}
+
+	return printList(cmd, list, func() {
+		fmt.Printf("Every feed keeps %s.\n", global)
+		for _, feed := range own {
+			fmt.Printf("* %s keeps %s\n", feed.Name, feedRetention(global, feed))
+		}
+	})
+}
+
+// retention set [url] [--posts N] [--days N]
+// Without a URL it sets the global retention, admins only, and a limit left
+// at -1 stays as it was. A feed's own can be changed by whoever can change
+// the feed; -1 goes back to the global value.
+func handlerRetentionSet(s *state, cmd command, user database.User) error {
+	keepPosts, _ := cmd.flag("posts").(int)
+	keepDays, _ := cmd.flag("days").(int)
+	if keepPosts < -1 || keepDays < -1 {
+		return errors.New("--posts and --days can't be less than -1")
+	}
+
+	if len(cmd.args) == 0 {
+		if !user.IsAdmin {
+			return errors.New("only admins can change the global retention")
+		}
+		r, err := globalRetention(s)
+		if err != nil {
+			return err
+		}
+		if keepPosts >= 0 {
+			r.KeepPosts = keepPosts
+		}
+		if keepDays >= 0 {
+			r.KeepDays = keepDays
+		}
+		err = setGlobalRetention(s, r)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Every feed keeps %s.\n", r)
+		return nil
+	}
+
+	feed, err := ownedFeed(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	nullInt := func(n int) sql.NullInt32 {
+		return sql.NullInt32{Int32: int32(n), Valid: n >= 0}
+	}
+	feed.KeepPosts, feed.KeepDays = nullInt(keepPosts), nullInt(keepDays)
+	err = s.db.SetFeedRetention(context.Background(), database.SetFeedRetentionParams{
+		ID:        feed.ID,
+		KeepPosts: feed.KeepPosts,
+		KeepDays:  feed.KeepDays,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("setting retention. %v", err)
+	}
+
+	global, err := globalRetention(s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s keeps %s.\n", feed.Name, feedRetention(global, feed))
+	return nil
+}
+
+// setGlobalRetention saves r, no limit is no setting.
+func setGlobalRetention(s *state, r retention) error {
+	ctx := context.Background()
+	for key, value := range map[string]int{settingKeepPosts: r.KeepPosts, settingKeepDays: r.KeepDays} {
+		var err error
+		if value == 0 {
+			err = s.db.DeleteSetting(ctx, key)
+		} else {
+			err = s.db.SetSetting(ctx, database.SetSettingParams{
+				Key:       key,
+				Value:     strconv.Itoa(value),
+				UpdatedAt: time.Now(),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("setting %s. %v", key, err)
+		}
+	}
+
+	return nil
+}
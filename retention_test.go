@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/archive"
+	"github.com/neixir/gator/internal/database"
+)
+
+func TestPostsToPrune(t *testing.T) {
+	now := time.Now()
+	// Newest first, one a day
+	var posts []database.Post
+	for i := range 5 {
+		posts = append(posts, database.Post{
+			Title:       fmt.Sprint(i),
+			PublishedAt: sql.NullTime{Time: now.Add(-time.Duration(i)*24*time.Hour - time.Hour), Valid: true},
+		})
+	}
+
+	tests := []struct {
+		r    retention
+		want string
+	}{
+		{retention{}, ""},
+		{retention{KeepPosts: 2}, "234"},
+		{retention{KeepDays: 3}, "34"},
+		// The newest posts stay even if they are old
+		{retention{KeepPosts: 4, KeepDays: 1}, "4"},
+		{retention{KeepPosts: 1, KeepDays: 2}, "234"},
+		{retention{KeepPosts: 10}, ""},
+	}
+	for _, test := range tests {
+		got := ""
+		for _, post := range postsToPrune(posts, test.r, now) {
+			got += post.Title
+		}
+		if got != test.want {
+			t.Errorf("postsToPrune(%s) = %q, want %q", test.r, got, test.want)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	forEachBackend(t, testRetention)
+}
+
+func testRetention(t *testing.T, s *state) {
+	ctx := context.Background()
+	url := "https://a.example/feed.xml"
+	register(t, s, "alice")
+	mustRun(t, s, "addfeed", "A", url)
+	feed, _ := s.db.GetFeedByUrl(ctx, url)
+
+	// One a day, newest first
+	var posts []database.Post
+	for i := range 5 {
+		published := time.Now().Add(-time.Duration(i)*24*time.Hour - time.Hour)
+		post, err := s.db.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(),
+			Title:       fmt.Sprintf("post %d", i),
+			Url:         fmt.Sprintf("%s/%d", url, i),
+			PublishedAt: sql.NullTime{Time: published, Valid: true},
+			FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreatePost: %v", err)
+		}
+		posts = append(posts, post)
+	}
+	exists := func(post database.Post) bool {
+		_, err := s.db.GetPost(ctx, post.ID)
+		return !errors.Is(err, sql.ErrNoRows)
+	}
+
+	// bob saves the oldest, and can't change the retention
+	register(t, s, "bob")
+	mustRun(t, s, "save", posts[4].ID.String())
+	if out := mustRun(t, s, "saved"); !strings.Contains(out, "* post 4") {
+		t.Errorf("saved = %q", out)
+	}
+	if _, err := runCommand(t, s, "save", "nope"); err == nil || !strings.Contains(err.Error(), "not a post id") {
+		t.Errorf("save nope: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "retention", "set", "--posts", "2"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("global retention as bob: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "retention", "set", url, "--posts", "2"); err == nil || !strings.Contains(err.Error(), "only the user who added") {
+		t.Errorf("retention of alice's feed as bob: err = %v", err)
+	}
+	if _, err := runCommand(t, s, "prune"); err == nil || !strings.Contains(err.Error(), "only admins") {
+		t.Errorf("prune as bob: err = %v", err)
+	}
+
+	login(t, s, "alice")
+	if out := mustRun(t, s, "prune"); !strings.Contains(out, "Deleted 0 post(s).") {
+		t.Errorf("prune without retention = %q", out)
+	}
+
+	// The saved post doesn't count
+	mustRun(t, s, "retention", "set", "--posts", "2")
+	out := mustRun(t, s, "prune", "--dry-run")
+	if !strings.Contains(out, "* A: 2 of 4 posts, it keeps 2 posts.") || !strings.Contains(out, "Would delete 2 post(s).") {
+		t.Errorf("prune --dry-run = %q", out)
+	}
+
+	// The feed's own goes first
+	mustRun(t, s, "retention", "set", url, "--posts", "0", "--days", "3")
+	if out := mustRun(t, s, "retention"); !strings.Contains(out, "Every feed keeps 2 posts.") || !strings.Contains(out, "* A keeps 3 days") {
+		t.Errorf("retention = %q", out)
+	}
+	dir := filepath.Join(t.TempDir(), "archive")
+	out = mustRun(t, s, "prune", "--archive-dir", dir)
+	if !strings.Contains(out, "Deleted 1 post(s).") {
+		t.Errorf("prune = %q", out)
+	}
+	if exists(posts[3]) || !exists(posts[2]) || !exists(posts[4]) {
+		t.Errorf("prune kept the wrong posts")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "posts-*.json.gz"))
+	if len(files) != 1 {
+		t.Fatalf("archives = %v", files)
+	}
+	var archived []archive.Post
+	if err := archive.Read(files[0], &archived); err != nil || len(archived) != 1 || archived[0].Url != posts[3].Url {
+		t.Errorf("archived = %+v, %v", archived, err)
+	}
+
+	// Back to the global one
+	mustRun(t, s, "retention", "set", url)
+	mustRun(t, s, "prune")
+	if exists(posts[2]) || !exists(posts[1]) {
+		t.Errorf("prune with the global retention kept the wrong posts")
+	}
+
+	login(t, s, "bob")
+	mustRun(t, s, "unsave", posts[4].ID.String())
+	login(t, s, "alice")
+	mustRun(t, s, "prune")
+	if exists(posts[4]) {
+		t.Errorf("an unsaved post survived prune")
+	}
+
+	// The global limits are set one at a time, only 0 clears one
+	mustRun(t, s, "retention", "set", "--posts", "0", "--days", "30")
+	if out := mustRun(t, s, "retention", "set", "--posts", "100"); !strings.Contains(out, "Every feed keeps 100 posts or 30 days.") {
+		t.Errorf("retention set --posts after --days = %q", out)
+	}
+	if out := mustRun(t, s, "retention", "set", "--days", "0"); !strings.Contains(out, "Every feed keeps 100 posts.") {
+		t.Errorf("retention set --days 0 = %q", out)
+	}
+}
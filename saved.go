@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// postByID returns the post with the id given as an argument.
+func postByID(s *state, arg string) (database.Post, error) {
+	id, err := uuid.Parse(arg)
+	if err != nil {
+		return database.Post{}, fmt.Errorf("%q is not a post id. `gator browse --fields id,title` lists them", arg)
+	}
+
+	post, err := s.db.GetPost(context.Background(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Post{}, fmt.Errorf("the post %s does not exist", arg)
+	}
+	if err != nil {
+		return database.Post{}, fmt.Errorf("getting post. %v", err)
+	}
+
+	return post, nil
+}
+
+// save <post-id>
+// prune never deletes a saved post.
+func handlerSave(s *state, cmd command, user database.User) error {
+	post, err := postByID(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SavePost(context.Background(), database.SavePostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("saving post. %v", err)
+	}
+
+	fmt.Printf("Saved %q.\n", post.Title)
+	return nil
+}
+
+// unsave <post-id>
+func handlerUnsave(s *state, cmd command, user database.User) error {
+	post, err := postByID(s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.UnsavePost(context.Background(), database.UnsavePostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("unsaving post. %v", err)
+	}
+
+	fmt.Printf("%q is not saved anymore.\n", post.Title)
+	return nil
+}
+
+func handlerSaved(s *state, cmd command, user database.User) error {
+	posts, err := s.db.GetSavedPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("getting saved posts for [%s] -- %v", user.Name, err)
+	}
+
+	views, err := filterPosts(s, user, posts)
+	if err != nil {
+		return err
+	}
+
+	return printList(cmd, postList(views), func() {
+		fmt.Printf("%d saved posts.\n", len(views))
+		printPostTitles(views)
+	})
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/neixir/gator/internal/config"
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/feedtest"
+	"github.com/neixir/gator/internal/notify"
+	"github.com/neixir/gator/internal/rss"
+)
+
+// fakeScraperDB keeps feeds and posts in memory and fails like Postgres on
+// duplicate post URLs.
+type fakeScraperDB struct {
+	feeds       []database.Feed
+	credentials map[uuid.UUID]database.FeedCredential
+	posts       map[string]database.CreatePostParams
+	contents    map[uuid.UUID]sql.NullString
+	// Returned by CreatePost when set
+	createErr  error
+	users      map[uuid.UUID]database.User
+	alerts     []database.AlertRule
+	deliveries []database.CreateAlertDeliveryParams
+	// Returned by CreateAlertDelivery when set
+	deliveryErr error
+}
+
+func newFakeScraperDB(urls ...string) *fakeScraperDB {
+	db := &fakeScraperDB{
+		credentials: map[uuid.UUID]database.FeedCredential{},
+		posts:       map[string]database.CreatePostParams{},
+		contents:    map[uuid.UUID]sql.NullString{},
+		users:       map[uuid.UUID]database.User{},
+	}
+	for i, url := range urls {
+		db.feeds = append(db.feeds, database.Feed{
+			ID:   uuid.New(),
+			Name: fmt.Sprintf("feed %d", i),
+			Url:  url,
+		})
+	}
+
+	return db
+}
+
+func (db *fakeScraperDB) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	if len(db.feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	// ORDER BY last_fetched_at ASC NULLS FIRST
+	feeds := append([]database.Feed{}, db.feeds...)
+	sort.SliceStable(feeds, func(i, j int) bool {
+		a, b := feeds[i].LastFetchedAt, feeds[j].LastFetchedAt
+		if !a.Valid || !b.Valid {
+			return !a.Valid && b.Valid
+		}
+		return a.Time.Before(b.Time)
+	})
+
+	return feeds[0], nil
+}
+
+func (db *fakeScraperDB) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	for i := range db.feeds {
+		if db.feeds[i].ID == arg.ID {
+			db.feeds[i].LastFetchedAt = arg.LastFetchedAt
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (db *fakeScraperDB) GetFeedCredential(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error) {
+	cred, ok := db.credentials[feedID]
+	if !ok {
+		return database.FeedCredential{}, sql.ErrNoRows
+	}
+
+	return cred, nil
+}
+
+func (db *fakeScraperDB) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	if db.createErr != nil {
+		return database.Post{}, db.createErr
+	}
+	if _, ok := db.posts[arg.Url]; ok {
+		return database.Post{}, &pq.Error{
+			Code:       "23505",
+			Constraint: "posts_url_key",
+			Message:    `duplicate key value violates unique constraint "posts_url_key"`,
+		}
+	}
+
+	db.posts[arg.Url] = arg
+	return database.Post{
+		ID:              arg.ID,
+		Title:           arg.Title,
+		Url:             arg.Url,
+		Description:     arg.Description,
+		PublishedAt:     arg.PublishedAt,
+		FeedID:          arg.FeedID,
+		Author:          arg.Author,
+		DescriptionText: arg.DescriptionText,
+	}, nil
+}
+
+func (db *fakeScraperDB) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	db.contents[arg.ID] = arg.Content
+	return nil
+}
+
+// GetAlertRulesForFeed doesn't know about follows, every rule applies.
+func (db *fakeScraperDB) GetAlertRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.AlertRule, error) {
+	var rules []database.AlertRule
+	for _, rule := range db.alerts {
+		if !rule.FeedID.Valid || rule.FeedID.UUID == feedID {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+func (db *fakeScraperDB) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, ok := db.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	return user, nil
+}
+
+func (db *fakeScraperDB) CreateAlertDelivery(ctx context.Context, arg database.CreateAlertDeliveryParams) (database.AlertDelivery, error) {
+	if db.deliveryErr != nil {
+		return database.AlertDelivery{}, db.deliveryErr
+	}
+	db.deliveries = append(db.deliveries, arg)
+	return database.AlertDelivery{ID: arg.ID, RuleID: arg.RuleID, Status: arg.Status}, nil
+}
+
+func TestScrapeFeedsSavesPosts(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml":   {Fixture: "rss2.xml"},
+		"/dates.xml":  {Fixture: "dates.xml"},
+		"/atom.xml":   {Fixture: "atom.xml"},
+		"/ns.xml":     {Fixture: "namespaces.xml"},
+		"/feed.json":  {Fixture: "jsonfeed.json"},
+		"/latin1.xml": {Fixture: "latin1.xml"},
+	})
+	db := newFakeScraperDB(
+		server.FeedURL("/rss2.xml"),
+		server.FeedURL("/dates.xml"),
+		server.FeedURL("/atom.xml"),
+		server.FeedURL("/ns.xml"),
+		server.FeedURL("/feed.json"),
+		server.FeedURL("/latin1.xml"),
+	)
+
+	for range db.feeds {
+		err := scrapeNextFeed(db, &config.Config{})
+		if err != nil {
+			t.Fatalf("scrapeNextFeed: %v", err)
+		}
+	}
+
+	for _, feed := range db.feeds {
+		if !feed.LastFetchedAt.Valid {
+			t.Errorf("feed %s was not fetched", feed.Url)
+		}
+	}
+
+	// 3 + 7 + 2 + 2 + 2 + 1
+	if len(db.posts) != 17 {
+		t.Errorf("got %d posts, want 17", len(db.posts))
+	}
+
+	post, ok := db.posts["https://blog.boot.dev/golang/go-first-language/"]
+	if !ok {
+		t.Fatalf("rss2 post missing")
+	}
+	if post.Title != "Is Go a good first language? — an honest answer" {
+		t.Errorf("title = %q", post.Title)
+	}
+	if post.Description.String != "<p>Short answer: yes.</p>" {
+		t.Errorf("description = %q", post.Description.String)
+	}
+	if post.DescriptionText.String != "Short answer: yes." {
+		t.Errorf("description text = %q", post.DescriptionText.String)
+	}
+	if want := time.Date(2025, 6, 23, 7, 1, 0, 0, time.UTC); !post.PublishedAt.Time.Equal(want) {
+		t.Errorf("published at = %v, want %v", post.PublishedAt.Time, want)
+	}
+	if post.FeedID.UUID != db.feeds[0].ID {
+		t.Errorf("post linked to the wrong feed")
+	}
+
+	post = db.posts["https://planet.example.org/2025/releasing-2-0"]
+	if post.Author.String != "Ada Lovelace" || !post.Author.Valid {
+		t.Errorf("author = %+v", post.Author)
+	}
+
+	post = db.posts["https://es.example.com/nino"]
+	if post.Title != "El niño y el pingüino" {
+		t.Errorf("latin1 title = %q", post.Title)
+	}
+}
+
+func TestScrapeFeedsDates(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/dates.xml": {Fixture: "dates.xml"},
+	})
+	db := newFakeScraperDB(server.FeedURL("/dates.xml"))
+
+	before := time.Now()
+	err := scrapeNextFeed(db, &config.Config{})
+	if err != nil {
+		t.Fatalf("scrapeNextFeed: %v", err)
+	}
+
+	want := time.Date(2025, 6, 23, 7, 1, 0, 0, time.UTC)
+	for _, n := range []string{"1", "2", "4", "5"} {
+		got := db.posts["https://dates.example.com/"+n].PublishedAt.Time
+		if !got.Equal(want) {
+			t.Errorf("post %s published at %v, want %v", n, got, want)
+		}
+	}
+
+	// Unparseable and missing dates fall back to the time of the scrape
+	for _, n := range []string{"6", "7"} {
+		got := db.posts["https://dates.example.com/"+n].PublishedAt.Time
+		if got.Before(before) {
+			t.Errorf("post %s published at %v, want the scrape time", n, got)
+		}
+	}
+}
+
+func TestScrapeFeedsDedupe(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml": {Fixture: "rss2.xml"},
+	})
+	db := newFakeScraperDB(server.FeedURL("/rss2.xml"))
+
+	for i := 0; i < 3; i++ {
+		err := scrapeNextFeed(db, &config.Config{})
+		if err != nil {
+			t.Fatalf("scrape %d: %v", i, err)
+		}
+	}
+
+	if len(db.posts) != 3 {
+		t.Errorf("got %d posts after 3 scrapes, want 3", len(db.posts))
+	}
+	if got := len(server.Requests("/rss2.xml")); got != 3 {
+		t.Errorf("feed fetched %d times, want 3", got)
+	}
+}
+
+func TestScrapeFeedsSendsAlerts(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml": {Fixture: "rss2.xml"},
+	})
+	var got []notify.Message
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg notify.Message
+		json.NewDecoder(r.Body).Decode(&msg)
+		got = append(got, msg)
+	}))
+	defer hook.Close()
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	db := newFakeScraperDB(server.FeedURL("/rss2.xml"))
+	owner := database.User{ID: uuid.New(), Name: "ada"}
+	gone := database.User{ID: uuid.New(), Name: "bob", DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	db.users[owner.ID] = owner
+	db.users[gone.ID] = gone
+	rule := func(user database.User, pattern, target string) database.AlertRule {
+		return database.AlertRule{ID: uuid.New(), UserID: user.ID, Field: "title", Match: "regex", Pattern: pattern, Kind: alertWebhook, Target: target}
+	}
+	db.alerts = []database.AlertRule{
+		rule(owner, `(?i)\bgo\b`, hook.URL),
+		rule(owner, "Tests", broken.URL),
+		// Another feed
+		{ID: uuid.New(), UserID: owner.ID, FeedID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, Field: "title", Match: "contains", Pattern: "", Kind: alertWebhook, Target: hook.URL},
+		// A deactivated user's
+		rule(gone, "", hook.URL),
+		// A command, but ada isn't an admin
+		{ID: uuid.New(), UserID: owner.ID, Field: "title", Match: "contains", Pattern: "", Kind: alertCommand, Target: "true"},
+	}
+
+	// Only new posts
+	for range 2 {
+		if err := scrapeNextFeed(db, &config.Config{}); err != nil {
+			t.Fatalf("scrapeNextFeed: %v", err)
+		}
+	}
+
+	if len(got) != 1 || got[0].Title != "Is Go a good first language? — an honest answer" || got[0].Feed != "feed 0" || got[0].Rule != db.alerts[0].ID.String() {
+		t.Errorf("webhook got %+v", got)
+	}
+	if len(db.deliveries) != 2 {
+		t.Fatalf("deliveries = %+v", db.deliveries)
+	}
+	for _, d := range db.deliveries {
+		switch d.RuleID {
+		case db.alerts[0].ID:
+			if d.Status != deliverySent || d.Attempts != 1 || d.Error.Valid {
+				t.Errorf("sent delivery = %+v", d)
+			}
+		case db.alerts[1].ID:
+			if d.Status != deliveryFailed || d.PostTitle != "Why I Write Tests" || !strings.Contains(d.Error.String, "404") {
+				t.Errorf("failed delivery = %+v", d)
+			}
+		}
+	}
+}
+
+// Losing the record of an alert doesn't lose the posts.
+func TestScrapeFeedsDeliveryErrors(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/rss2.xml": {Fixture: "rss2.xml"},
+	})
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hook.Close()
+
+	db := newFakeScraperDB(server.FeedURL("/rss2.xml"))
+	owner := database.User{ID: uuid.New(), Name: "ada"}
+	db.users[owner.ID] = owner
+	db.alerts = []database.AlertRule{
+		{ID: uuid.New(), UserID: owner.ID, Field: "title", Match: "contains", Pattern: "", Kind: alertWebhook, Target: hook.URL},
+	}
+	db.deliveryErr = errors.New("disk full")
+
+	if err := scrapeNextFeed(db, &config.Config{}); err != nil {
+		t.Fatalf("scrapeNextFeed: %v", err)
+	}
+	if len(db.posts) != 3 {
+		t.Errorf("saved %d posts, want 3", len(db.posts))
+	}
+}
+
+func TestScrapeFeedsSavesContent(t *testing.T) {
+	server := articleServer(t)
+	db := newFakeScraperDB(server.FeedURL("/feed.xml"))
+	db.feeds[0].Readability = true
+
+	// Only new posts
+	for range 2 {
+		if err := scrapeNextFeed(db, &config.Config{}); err != nil {
+			t.Fatalf("scrapeNextFeed: %v", err)
+		}
+	}
+
+	if len(db.posts) != 2 {
+		t.Fatalf("got %d posts, want 2 even if an article is missing", len(db.posts))
+	}
+	good := db.posts[server.FeedURL("/articles/good")]
+	if content := db.contents[good.ID]; !content.Valid || !strings.Contains(content.String, "Readability keeps the paragraphs") || strings.Contains(content.String, "Home") {
+		t.Errorf("content = %+v", content)
+	}
+	if missing := db.posts[server.FeedURL("/articles/missing")]; db.contents[missing.ID].Valid {
+		t.Errorf("content of a missing article = %+v", db.contents[missing.ID])
+	}
+	if got := len(server.Requests("/articles/good")); got != 1 {
+		t.Errorf("article fetched %d times, want 1", got)
+	}
+}
+
+func TestScrapeFeedsErrors(t *testing.T) {
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/down.xml": {Fixture: "rss2.xml", Status: http.StatusServiceUnavailable},
+		"/html":     {Fixture: "not_a_feed.html"},
+		"/rss2.xml": {Fixture: "rss2.xml"},
+	})
+
+	t.Run("no feeds", func(t *testing.T) {
+		err := scrapeNextFeed(newFakeScraperDB(), &config.Config{})
+		if err == nil || !strings.Contains(err.Error(), "getting next feed") {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	for _, path := range []string{"/down.xml", "/html", "/missing.xml"} {
+		t.Run(path, func(t *testing.T) {
+			db := newFakeScraperDB(server.FeedURL(path))
+			err := scrapeNextFeed(db, &config.Config{})
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			// Marked anyway so a broken feed doesn't block the others
+			if !db.feeds[0].LastFetchedAt.Valid {
+				t.Errorf("broken feed was not marked as fetched")
+			}
+			if len(db.posts) != 0 {
+				t.Errorf("got %d posts", len(db.posts))
+			}
+		})
+	}
+
+	t.Run("create post fails", func(t *testing.T) {
+		db := newFakeScraperDB(server.FeedURL("/rss2.xml"))
+		db.createErr = errors.New("connection reset")
+		// Logged, not returned: one bad post shouldn't stop the rest
+		err := scrapeNextFeed(db, &config.Config{})
+		if err != nil {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	t.Run("credentials without key", func(t *testing.T) {
+		t.Setenv("GATOR_CREDENTIALS_KEY", "")
+		db := newFakeScraperDB(server.FeedURL("/rss2.xml"))
+		db.credentials[db.feeds[0].ID] = database.FeedCredential{FeedID: db.feeds[0].ID, AuthType: rss.AuthBearer, Secret: []byte("x")}
+		err := scrapeNextFeed(db, &config.Config{})
+		if err == nil || !strings.Contains(err.Error(), "credentials") {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestScrapeFeedsWithCredentials(t *testing.T) {
+	t.Setenv("GATOR_CREDENTIALS_KEY", "")
+
+	server := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/private.xml": {Fixture: "rss2.xml"},
+	})
+
+	cfg := &config.Config{CredentialsKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}
+	db := newFakeScraperDB(server.FeedURL("/private.xml"))
+	sealed, err := sealFeedAuth(cfg, &rss.Auth{Type: rss.AuthBearer, Token: "let-me-in"})
+	if err != nil {
+		t.Fatalf("sealFeedAuth: %v", err)
+	}
+	db.credentials[db.feeds[0].ID] = database.FeedCredential{FeedID: db.feeds[0].ID, AuthType: rss.AuthBearer, Secret: sealed}
+
+	err = scrapeNextFeed(db, cfg)
+	if err != nil {
+		t.Fatalf("scrapeNextFeed: %v", err)
+	}
+
+	req := server.Requests("/private.xml")[0]
+	if got := req.Header.Get("Authorization"); got != "Bearer let-me-in" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
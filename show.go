@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/neixir/gator/internal/database"
+	"github.com/neixir/gator/internal/sanitize"
+)
+
+// Width of show when stdout is not a terminal, and the widest it gets on one
+const (
+	defaultShowWidth = 80
+	maxShowWidth     = 100
+)
+
+// postByArg returns the post given as an argument: its id, or its position
+// in browse, from 1.
+func postByArg(s *state, user database.User, arg string) (database.Post, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		return postByID(s, arg)
+	}
+	if index < 1 {
+		return database.Post{}, errors.New("the first post is 1")
+	}
+
+	views, err := browsePosts(s, user, index)
+	if err != nil {
+		return database.Post{}, err
+	}
+	if len(views) < index {
+		return database.Post{}, fmt.Errorf("there are only %d posts to browse", len(views))
+	}
+
+	return views[index-1].Post, nil
+}
+
+// renderPost formats post as text width columns wide: a header, the
+// content of its article or else its description, and the links of the text
+// as numbered footnotes. Control characters are left out, the feed decides
+// what every field has.
+func renderPost(post database.Post, feedName string, width int) string {
+	var b strings.Builder
+	b.WriteString(sanitize.Wrap(sanitize.Line(post.Title), width) + "\n")
+	header := [][2]string{{"Feed", feedName}, {"Author", post.Author.String}}
+	if post.PublishedAt.Valid {
+		header = append(header, [2]string{"Published", post.PublishedAt.Time.Format("Mon 2 Jan 2006 15:04")})
+	}
+	header = append(header, [2]string{"URL", post.Url})
+	for _, field := range header {
+		if value := sanitize.Line(field[1]); value != "" {
+			fmt.Fprintf(&b, "%-10s %s\n", field[0]+":", value)
+		}
+	}
+
+	body := post.Description.String
+	if post.Content.Valid {
+		body = post.Content.String
+	}
+	text, links := sanitize.TextWithLinks(body)
+	text = sanitize.Printable(text)
+	if text != "" {
+		b.WriteString("\n" + sanitize.Wrap(text, width) + "\n")
+	}
+	if len(links) > 0 {
+		b.WriteString("\n")
+		for i, link := range links {
+			fmt.Fprintf(&b, "[%d] %s\n", i+1, sanitize.Line(link))
+		}
+	}
+
+	return b.String()
+}
+
+// terminalWidth is the width of the terminal on stdout, up to maxShowWidth.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultShowWidth
+	}
+
+	return min(width, maxShowWidth)
+}
+
+// page shows text through $PAGER, or less if it isn't set.
+func page(text string) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", pager)
+	} else {
+		cmd = exec.Command("sh", "-c", pager)
+	}
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("running the pager %q. %v", pager, err)
+	}
+
+	return nil
+}
+
+// show <post-id|index> [--width N] [--pager]
+func handlerShow(s *state, cmd command, user database.User) error {
+	post, err := postByArg(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	feeds, err := feedNames(s)
+	if err != nil {
+		return err
+	}
+
+	width, _ := cmd.flag("width").(int)
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	text := renderPost(post, feeds[post.FeedID.UUID], width)
+
+	if pager, _ := cmd.flag("pager").(bool); pager {
+		return page(text)
+	}
+	fmt.Print(text)
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/neixir/gator/internal/feedtest"
+)
+
+func TestShow(t *testing.T) {
+	forEachBackend(t, testShow)
+}
+
+func testShow(t *testing.T, s *state) {
+	feeds := feedtest.NewServer(t, map[string]feedtest.Response{
+		"/feed.xml": {Body: []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Links</title>
+<item><title>Weekly links</title><link>https://example.com/weekly</link><author>Ada</author>
+<pubDate>Mon, 02 Jun 2025 10:00:00 +0000</pubDate>
+<description>&lt;p&gt;Three things this week: &lt;a href="https://go.dev/blog"&gt;the Go blog&lt;/a&gt;, &lt;a href="https://example.com/a"&gt;this post&lt;/a&gt; and, once more, &lt;a href="https://go.dev/blog"&gt;the Go blog&lt;/a&gt;.&lt;/p&gt;&lt;ul&gt;&lt;li&gt;A list item that is long enough to be wrapped at forty columns&lt;/li&gt;&lt;/ul&gt;</description></item>
+<item><title>Second post</title><link>https://example.com/second</link>
+<pubDate>Tue, 03 Jun 2025 10:00:00 +0000</pubDate>
+<description>&lt;p&gt;Nothing to see.&lt;/p&gt;</description></item>
+</channel></rss>`)},
+		"/escapes.xml": {Body: []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Escapes</title>
+<item><title>Clear&amp;#27;[2J the screen</title><link>https://example.com/escapes</link><author>Eve&#13;</author>
+<pubDate>Wed, 04 Jun 2025 10:00:00 +0000</pubDate>
+<description>&lt;p&gt;Bell&amp;#7; and &amp;#27;]0;pwned&amp;#7; tab&amp;#9;ok&lt;/p&gt;</description></item>
+</channel></rss>`)},
+	})
+
+	register(t, s, "alice")
+	mustRun(t, s, "addfeed", "Links", feeds.FeedURL("/feed.xml"))
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+
+	out := mustRun(t, s, "show", "1", "--width", "40")
+	want := `Weekly links
+Feed:      Links
+Author:    Ada
+Published: Mon 2 Jun 2025 10:00
+URL:       https://example.com/weekly
+
+Three things this week: the Go blog[1],
+this post[2] and, once more, the Go
+blog[1].
+
+- A list item that is long enough to be
+  wrapped at forty columns
+
+[1] https://go.dev/blog
+[2] https://example.com/a
+`
+	if out != want {
+		t.Errorf("show 1 =\n%s\nwant\n%s", out, want)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if utf8.RuneCountInString(line) > 40 {
+			t.Errorf("line longer than 40: %q", line)
+		}
+	}
+
+	// By id, the same post
+	ids := strings.Fields(mustRun(t, s, "browse", "--output", "tsv", "--fields", "id"))
+	if byID := mustRun(t, s, "show", ids[1], "--width", "40"); byID != out {
+		t.Errorf("show <id> =\n%s", byID)
+	}
+
+	// show doesn't mark posts as read
+	if out := mustRun(t, s, "browse"); strings.Contains(out, "(read)") {
+		t.Errorf("browse after show = %q", out)
+	}
+
+	// The index follows browse, filters included
+	mustRun(t, s, "filter", "add", "--title-contains", "weekly", "--action", "hide")
+	if out := mustRun(t, s, "show", "1"); !strings.HasPrefix(out, "Second post\n") || !strings.Contains(out, "\n\nNothing to see.\n") || strings.Contains(out, "[1]") {
+		t.Errorf("show 1 with a filter = %q", out)
+	}
+	for _, arg := range []string{"0", "2", "abc"} {
+		if _, err := runCommand(t, s, "show", arg); err == nil {
+			t.Errorf("show %s should fail", arg)
+		}
+	}
+
+	// Through the pager
+	t.Setenv("PAGER", "tr a-z A-Z")
+	if out := mustRun(t, s, "show", "1", "--pager"); !strings.HasPrefix(out, "SECOND POST\n") {
+		t.Errorf("show --pager = %q", out)
+	}
+	t.Setenv("PAGER", "exit 3")
+	if _, err := runCommand(t, s, "show", "1", "--pager"); err == nil || !strings.Contains(err.Error(), "pager") {
+		t.Errorf("show with a failing pager: err = %v", err)
+	}
+
+	// Escape sequences in a feed don't reach the terminal
+	t.Setenv("PAGER", "")
+	mustRun(t, s, "addfeed", "Escapes", feeds.FeedURL("/escapes.xml"))
+	captureStdout(t, func() {
+		if err := scrapeFeeds(s); err != nil {
+			t.Errorf("scrapeFeeds: %v", err)
+		}
+	})
+	out = mustRun(t, s, "show", "2")
+	if strings.ContainsAny(out, "\x1b\x07\r") || !strings.HasPrefix(out, "Clear[2J the screen\n") || !strings.Contains(out, "Author:    Eve\n") || !strings.Contains(out, "Bell and ]0;pwned tab ok") {
+		t.Errorf("show of a post with escape sequences = %q", out)
+	}
+	if out := mustRun(t, s, "browse"); strings.Contains(out, "\x1b") || !strings.Contains(out, "* Clear[2J the screen") {
+		t.Errorf("browse of a post with escape sequences = %q", out)
+	}
+}
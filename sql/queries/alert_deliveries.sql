@@ -0,0 +1,23 @@
+-- name: CreateAlertDelivery :one
+INSERT INTO alert_deliveries (id, created_at, rule_id, post_title, post_url, status, attempts, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- Newest first.
+-- name: GetAlertDeliveriesForUser :many
+SELECT alert_deliveries.*
+FROM alert_deliveries
+INNER JOIN alert_rules
+ON alert_rules.id = alert_deliveries.rule_id
+WHERE alert_rules.user_id = $1
+ORDER BY alert_deliveries.created_at DESC
+LIMIT $2;
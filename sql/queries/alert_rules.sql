@@ -0,0 +1,35 @@
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (id, created_at, user_id, feed_id, field, match, pattern, kind, target)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- In the order they were added.
+-- name: GetAlertRulesForUser :many
+SELECT * FROM alert_rules
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- The rules that apply to the new posts of a feed: the ones of its
+-- followers, for every feed or that one.
+-- name: GetAlertRulesForFeed :many
+SELECT alert_rules.*
+FROM alert_rules
+INNER JOIN feed_follows
+ON feed_follows.user_id = alert_rules.user_id AND feed_follows.feed_id = $1
+WHERE alert_rules.feed_id IS NULL OR alert_rules.feed_id = $1
+ORDER BY alert_rules.created_at ASC;
+
+-- Only the user's own.
+-- name: DeleteAlertRule :execrows
+DELETE FROM alert_rules
+WHERE id = $1 AND user_id = $2;
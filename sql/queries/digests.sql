@@ -0,0 +1,12 @@
+-- name: GetLastDigest :one
+SELECT * FROM digests
+WHERE user_id = $1;
+
+-- name: SetLastDigest :exec
+INSERT INTO digests (user_id, sent_at)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT (user_id) DO UPDATE
+SET sent_at = EXCLUDED.sent_at;
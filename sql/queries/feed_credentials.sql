@@ -0,0 +1,20 @@
+-- name: SetFeedCredential :one
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, secret)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at, auth_type = EXCLUDED.auth_type, secret = EXCLUDED.secret
+RETURNING *;
+
+-- name: GetFeedCredential :one
+SELECT * FROM feed_credentials
+WHERE feed_id = $1;
+
+-- name: DeleteFeedCredential :exec
+DELETE FROM feed_credentials
+WHERE feed_id = $1;
@@ -37,4 +37,8 @@ WHERE feed_follows.user_id = $1;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
-WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows
+WHERE feed_id = $1;
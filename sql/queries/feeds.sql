@@ -30,7 +30,66 @@ WHERE id = $1;
 -- A simple approach is to keep track of when a feed was last fetched,
 -- and always fetch the oldest one first (or any that haven't ever been fetched).
 -- SQL has a NULLS FIRST clause that can help with this.
+-- Feeds nobody follows are skipped, gc deletes them.
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- Gives every feed of one user to another.
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg(to_user_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1;
+
+-- A new URL is fetched first.
+-- name: SetFeedUrl :exec
+UPDATE feeds
+SET url = $2, last_fetched_at = NULL, updated_at = $3
+WHERE id = $1;
+
+-- name: TransferFeed :exec
+UPDATE feeds
+SET user_id = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- Feeds that just lost their last follower.
+-- name: MarkOrphanedFeeds :execrows
+UPDATE feeds
+SET orphaned_at = $1
+WHERE orphaned_at IS NULL
+AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id);
+
+-- Somebody followed them again.
+-- name: ClearOrphanedFeeds :exec
+UPDATE feeds
+SET orphaned_at = NULL
+WHERE orphaned_at IS NOT NULL
+AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id);
+
+-- Feeds without followers since before the given time.
+-- name: GetOrphanedFeeds :many
+SELECT * FROM feeds
+WHERE orphaned_at < $1
+ORDER BY orphaned_at ASC;
+
+-- NULL uses the global setting.
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET keep_posts = $2, keep_days = $3, updated_at = $4
+WHERE id = $1;
+
+-- name: SetFeedReadability :exec
+UPDATE feeds
+SET readability = $2, updated_at = $3
+WHERE id = $1;
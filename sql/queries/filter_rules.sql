@@ -0,0 +1,25 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_id, field, match, pattern, action, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- In the order they were added.
+-- name: GetFilterRulesForUser :many
+SELECT * FROM filter_rules
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- Only the user's own.
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2;
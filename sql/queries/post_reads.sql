@@ -0,0 +1,12 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetPostReadsForUser :many
+SELECT * FROM post_reads
+WHERE user_id = $1;
@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text)
 VALUES (
     $1,
     $2,
@@ -8,7 +8,9 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
 RETURNING *;
 
@@ -18,4 +20,40 @@ FROM posts
 INNER JOIN feed_follows
 ON feed_follows.feed_id = posts.feed_id and feed_follows.user_id = $1
 ORDER BY posts.published_at ASC
-LIMIT $2;
\ No newline at end of file
+LIMIT $2;
+
+-- The posts of followed feeds gator saved after a time, for digest.
+-- name: GetNewPostsForUser :many
+SELECT posts.*
+FROM posts
+INNER JOIN feed_follows
+ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.created_at > $2
+ORDER BY posts.published_at ASC;
+
+-- For archiving a feed before gc deletes it.
+-- name: GetPostsForFeed :many
+SELECT * FROM posts
+WHERE feed_id = $1
+ORDER BY published_at ASC;
+
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = $1;
+
+-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = $1;
+
+-- The posts prune may delete, newest first: nobody saved them.
+-- name: GetPrunablePostsForFeed :many
+SELECT * FROM posts
+WHERE feed_id = $1
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+ORDER BY published_at DESC;
+
+-- The main content of the article, extracted by readability.
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = $3
+WHERE id = $1;
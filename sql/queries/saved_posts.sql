@@ -0,0 +1,21 @@
+-- name: SavePost :exec
+INSERT INTO saved_posts (user_id, post_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnsavePost :exec
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- Most recently saved first.
+-- name: GetSavedPostsForUser :many
+SELECT posts.*
+FROM posts
+INNER JOIN saved_posts
+ON saved_posts.post_id = posts.id
+WHERE saved_posts.user_id = $1
+ORDER BY saved_posts.created_at DESC;
@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -20,4 +22,28 @@ WHERE id=$1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserAdmin :exec
+UPDATE users
+SET is_admin = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: RenameUser :exec
+UPDATE users
+SET name = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: SetUserDeactivated :exec
+UPDATE users
+SET deactivated_at = $2, updated_at = $3
+WHERE id = $1;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE feed_credentials (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    -- basic, bearer or cookie. The secret itself is encrypted with the credentials key.
+    auth_type TEXT NOT NULL,
+    secret BYTEA NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
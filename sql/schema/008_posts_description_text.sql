@@ -0,0 +1,10 @@
+-- +goose Up
+-- Plain text rendering of the (sanitized) HTML description.
+-- It needs Go to render, so posts saved before this migration keep NULL and
+-- gator renders their description when it needs the text (descriptionText).
+ALTER TABLE posts
+ADD COLUMN description_text TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN description_text;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE sessions (
+    -- SHA-256 of the token kept in the config file. The token itself is never stored.
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE sessions;
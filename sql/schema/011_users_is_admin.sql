@@ -0,0 +1,10 @@
+-- +goose Up
+-- Admins can run the commands that change everyone's data, like reset.
+-- Existing users stay regular users: they may have no password yet, so
+-- `gator init` makes the first admin of a database from before roles.
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN is_admin;
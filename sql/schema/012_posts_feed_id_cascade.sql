@@ -0,0 +1,10 @@
+-- +goose Up
+-- Deleting a feed (or the user that added it) deletes its posts too.
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id);
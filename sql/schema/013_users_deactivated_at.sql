@@ -0,0 +1,8 @@
+-- +goose Up
+-- Deactivated users can't log in, their feeds and follows stay. NULL while active.
+ALTER TABLE users
+ADD COLUMN deactivated_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN deactivated_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- When the last follower of a feed left, NULL while someone follows it.
+-- gc deletes feeds orphaned for longer than a grace period. Feeds already
+-- without followers get a time the first time gc or unfollow looks at them.
+ALTER TABLE feeds
+ADD COLUMN orphaned_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN orphaned_at;
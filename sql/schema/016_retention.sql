@@ -0,0 +1,19 @@
+-- +goose Up
+-- How many posts of a feed prune keeps, NULL to use the global setting.
+ALTER TABLE feeds
+ADD COLUMN keep_posts INTEGER,
+ADD COLUMN keep_days INTEGER;
+
+-- Settings shared by every user of the database, like the global retention.
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE settings;
+
+ALTER TABLE feeds
+DROP COLUMN keep_posts,
+DROP COLUMN keep_days;
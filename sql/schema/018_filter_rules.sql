@@ -0,0 +1,20 @@
+-- +goose Up
+-- What a user does with the posts that match: hide, mark-read, highlight or
+-- tag them. A rule without a feed applies to every feed.
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description or author
+    field TEXT NOT NULL,
+    -- contains or regex
+    match TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL,
+    -- Only for the tag action
+    tag TEXT
+);
+
+-- +goose Down
+DROP TABLE filter_rules;
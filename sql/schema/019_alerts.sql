@@ -0,0 +1,37 @@
+-- +goose Up
+-- Notify a user of the new posts that match: POST them to a webhook, run a
+-- command or send an email. A rule without a feed applies to every feed
+-- the user follows.
+CREATE TABLE alert_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    -- title, description or author
+    field TEXT NOT NULL,
+    -- contains or regex
+    match TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    -- webhook, command or email
+    kind TEXT NOT NULL,
+    -- The URL, the command or the address
+    target TEXT NOT NULL
+);
+
+-- Every notification sent, or given up on. The post is copied, prune may
+-- delete it.
+CREATE TABLE alert_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    post_title TEXT NOT NULL,
+    post_url TEXT NOT NULL,
+    -- sent or failed
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT
+);
+
+-- +goose Down
+DROP TABLE alert_deliveries;
+DROP TABLE alert_rules;
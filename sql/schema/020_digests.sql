@@ -0,0 +1,9 @@
+-- +goose Up
+-- When each user got their last digest, the next one starts there.
+CREATE TABLE digests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    sent_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE digests;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Feeds with readability on get the article of each new post fetched, and
+-- its main content extracted into posts.content.
+ALTER TABLE feeds
+ADD COLUMN readability BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;
+
+ALTER TABLE feeds
+DROP COLUMN readability;
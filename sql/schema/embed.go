@@ -0,0 +1,8 @@
+// Package schema embeds the Postgres migrations so gator can apply them
+// itself (see internal/migrate). goose and sqlc only read the .sql files.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
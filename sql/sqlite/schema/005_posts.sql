@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE posts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID REFERENCES feeds(id)
+);
+
+-- +goose Down
+DROP TABLE posts;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feed_credentials (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    auth_type TEXT NOT NULL,
+    secret BLOB NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- bcrypt hash. NULL for the users registered before passwords: an admin sets one with
+-- `gator user set-password`, or they choose one in `gator init`.
+ALTER TABLE users
+ADD COLUMN password_hash TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN password_hash;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- Deleting a feed (or the user that added it) deletes its posts too.
+-- SQLite can't change a foreign key, the table is copied.
+CREATE TABLE posts_new (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    author TEXT,
+    description_text TEXT
+);
+
+INSERT INTO posts_new (id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text FROM posts;
+
+DROP TABLE posts;
+
+ALTER TABLE posts_new RENAME TO posts;
+
+-- +goose Down
+CREATE TABLE posts_old (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID REFERENCES feeds(id),
+    author TEXT,
+    description_text TEXT
+);
+
+INSERT INTO posts_old (id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, description_text FROM posts;
+
+DROP TABLE posts;
+
+ALTER TABLE posts_old RENAME TO posts;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN keep_posts INTEGER;
+ALTER TABLE feeds ADD COLUMN keep_days INTEGER;
+
+CREATE TABLE settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE settings;
+
+ALTER TABLE feeds DROP COLUMN keep_days;
+ALTER TABLE feeds DROP COLUMN keep_posts;
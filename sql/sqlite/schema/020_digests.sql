@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE digests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    sent_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE digests;
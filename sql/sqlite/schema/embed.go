@@ -0,0 +1,8 @@
+// Package schema embeds the SQLite migrations so gator can apply them
+// itself (see internal/migrate). goose only reads the .sql files.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/neixir/gator/internal/database"
+)
+
+// managedUser returns the user called username if user can manage the
+// account: users manage their own, admins everyone's.
+func managedUser(s *state, user database.User, username string) (database.User, error) {
+	target, err := s.db.GetUser(context.Background(), username)
+	if err != nil {
+		return database.User{}, fmt.Errorf("the user does not exist. %v", err)
+	}
+	if target.ID != user.ID && !user.IsAdmin {
+		return database.User{}, fmt.Errorf("only admins can change other users")
+	}
+
+	return target, nil
+}
+
+// checkNotLastAdmin fails if target is the only active admin, somebody has
+// to be able to run the admin commands.
+func checkNotLastAdmin(s *state, target database.User) error {
+	if !target.IsAdmin || target.DeactivatedAt.Valid {
+		return nil
+	}
+
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return fmt.Errorf("getting user list. %v", err)
+	}
+	for _, u := range users {
+		if u.IsAdmin && !u.DeactivatedAt.Valid && u.ID != target.ID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is the only admin, make someone else an admin first", target.Name)
+}
+
+// user delete <username>
+// The feeds the user added go with them, and with the feeds every follow and
+// post. If other users follow them they have to be given to someone else
+// (--transfer-to) or deleted on purpose (--delete-feeds).
+func handlerUserDelete(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	target, err := managedUser(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	err = checkNotLastAdmin(s, target)
+	if err != nil {
+		return err
+	}
+
+	var heir database.User
+	transferTo, _ := cmd.flag("transfer-to").(string)
+	deleteFeeds, _ := cmd.flag("delete-feeds").(bool)
+	if transferTo != "" {
+		heir, err = s.db.GetUser(ctx, transferTo)
+		if err != nil {
+			return fmt.Errorf("the user %s does not exist. %v", transferTo, err)
+		}
+		if heir.ID == target.ID {
+			return fmt.Errorf("can't give %s's feeds to %s", target.Name, transferTo)
+		}
+		if heir.DeactivatedAt.Valid {
+			return fmt.Errorf("%s is deactivated", heir.Name)
+		}
+	} else if !deleteFeeds {
+		followed, err := feedsFollowedByOthers(s, target)
+		if err != nil {
+			return err
+		}
+		if len(followed) > 0 {
+			return fmt.Errorf("other users follow feeds %s added: %s\nPass --transfer-to <username> to give them to someone else, or --delete-feeds",
+				target.Name, strings.Join(followed, ", "))
+		}
+	}
+
+	yes, _ := cmd.flag("yes").(bool)
+	if !yes {
+		answer, err := promptLine(fmt.Sprintf("Type %q to delete the user", target.Name))
+		if err != nil {
+			return fmt.Errorf("%v. Pass --yes to delete without asking", err)
+		}
+		if answer != target.Name {
+			return errors.New("delete cancelled")
+		}
+	}
+
+	// The feeds can't go with the user if giving them away failed
+	var transferred int64
+	err = inTx(s, func(tx *state) error {
+		if transferTo != "" {
+			var err error
+			transferred, err = tx.db.TransferFeeds(ctx, database.TransferFeedsParams{
+				ToUserID:   heir.ID,
+				UpdatedAt:  time.Now(),
+				FromUserID: target.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("transferring feeds. %v", err)
+			}
+		}
+
+		err := tx.db.DeleteUser(ctx, target.ID)
+		if err != nil {
+			return fmt.Errorf("deleting user. %v", err)
+		}
+
+		return updateOrphanedFeeds(tx)
+	})
+	if err != nil {
+		return err
+	}
+	if transferTo != "" {
+		fmt.Printf("Gave %d feed(s) to %s.\n", transferred, heir.Name)
+	}
+
+	// Our own session is gone
+	if target.ID == user.ID {
+		err = s.cfg.SetSession("", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Deleted user %s.\n", target.Name)
+	return nil
+}
+
+// feedsFollowedByOthers returns "name (n followers)" for every feed user
+// added that other users follow.
+func feedsFollowedByOthers(s *state, user database.User) ([]string, error) {
+	ctx := context.Background()
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting feed list. %v", err)
+	}
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("getting follows. %v", err)
+	}
+	own := map[uuid.UUID]bool{}
+	for _, follow := range follows {
+		own[follow.FeedID] = true
+	}
+
+	followed := []string{}
+	for _, feed := range feeds {
+		if feed.UserID != user.ID {
+			continue
+		}
+		n, err := s.db.CountFeedFollowers(ctx, feed.ID)
+		if err != nil {
+			return nil, fmt.Errorf("counting followers of %s. %v", feed.Name, err)
+		}
+		if own[feed.ID] {
+			n--
+		}
+		if n > 0 {
+			followed = append(followed, fmt.Sprintf("%s (%d followers)", feed.Name, n))
+		}
+	}
+
+	return followed, nil
+}
+
+// user rename <username> <new-name>
+func handlerUserRename(s *state, cmd command, user database.User) error {
+	target, err := managedUser(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	newName := cmd.args[1]
+	if newName == "" {
+		return errors.New("the new name is empty")
+	}
+	if _, err := s.db.GetUser(context.Background(), newName); err == nil {
+		return fmt.Errorf("the user %s already exists", newName)
+	}
+
+	err = s.db.RenameUser(context.Background(), database.RenameUserParams{
+		ID:        target.ID,
+		Name:      newName,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("renaming user. %v", err)
+	}
+
+	// The session doesn't change, only the name shown
+	if target.ID == user.ID {
+		err = s.cfg.SetSession(newName, s.cfg.SessionToken)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Renamed %s to %s.\n", target.Name, newName)
+	return nil
+}
+
+// user set-password <username>
+// Your own needs the current password, if there is one. Admins set anyone's,
+// which is how users from before passwords get their first one.
+func handlerUserSetPassword(s *state, cmd command, user database.User) error {
+	target, err := managedUser(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if target.ID == user.ID && target.PasswordHash.Valid {
+		err = checkPassword(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setPassword(s.db, target)
+	if err != nil {
+		return err
+	}
+
+	// Whoever knew the old password is logged out
+	if target.ID != user.ID {
+		err = s.db.DeleteSessionsForUser(context.Background(), target.ID)
+		if err != nil {
+			return fmt.Errorf("deleting sessions. %v", err)
+		}
+	}
+
+	fmt.Printf("Set the password of %s.\n", target.Name)
+	return nil
+}
+
+// user deactivate <username>
+// The user can't log in anymore, but their feeds and follows stay.
+func handlerUserDeactivate(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	target, err := managedUser(s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	err = checkNotLastAdmin(s, target)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetUserDeactivated(ctx, database.SetUserDeactivatedParams{
+		ID:            target.ID,
+		DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt:     time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("deactivating user. %v", err)
+	}
+
+	// Logged out everywhere
+	err = s.db.DeleteSessionsForUser(ctx, target.ID)
+	if err != nil {
+		return fmt.Errorf("deleting sessions. %v", err)
+	}
+	if target.ID == user.ID {
+		err = s.cfg.SetSession("", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Deactivated %s.\n", target.Name)
+	return nil
+}
+
+// user activate <username>
+// Admins only, a deactivated user can't log in to do it.
+func handlerUserActivate(s *state, cmd command, user database.User) error {
+	target, err := s.db.GetUser(context.Background(), cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("the user does not exist. %v", err)
+	}
+
+	err = s.db.SetUserDeactivated(context.Background(), database.SetUserDeactivatedParams{
+		ID:        target.ID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("activating user. %v", err)
+	}
+
+	fmt.Printf("Activated %s.\n", target.Name)
+	return nil
+}